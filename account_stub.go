@@ -131,6 +131,45 @@ func (ab *AccountStub) CreateJointAccount(holders *stringset.Set) (*JointAccount
 	return nil, nil, errors.New("failed to create a random address")
 }
 
+// CreatePoolAccount creates the reserve account of the pool. (joint account without holders)
+// Nobody can transfer the balance of the account, only the pool routes can.
+func (ab *AccountStub) CreatePoolAccount(poolID string) (*JointAccount, *Balance, error) {
+	ts, err := txtime.GetTime(ab.stub)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	addr := NewAddress(ab.token, AccountTypeJoint, "pool/"+poolID)
+	if _, err = ab.GetAccount(addr); err != nil {
+		if _, ok := err.(NotExistedAccountError); !ok {
+			return nil, nil, errors.Wrap(err, "failed to get an existed account")
+		}
+
+		account := &JointAccount{
+			Account: Account{
+				DOCTYPEID:   addr.String(),
+				Token:       ab.token,
+				Type:        AccountTypeJoint,
+				CreatedTime: ts,
+				UpdatedTime: ts,
+			},
+			Holders: stringset.New(),
+		}
+		if err = ab.PutAccount(account); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to create an account")
+		}
+
+		balance, err := NewBalanceStub(ab.stub).CreateBalance(account.GetID())
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to create an account balance")
+		}
+
+		return account, balance, nil
+	}
+
+	return nil, nil, ExistedAccountError{addr: addr.String()}
+}
+
 // GetAccount retrieves the account by an address
 func (ab *AccountStub) GetAccount(addr *Address) (AccountInterface, error) {
 	data, err := ab.GetAccountState(addr.String())
@@ -233,7 +272,7 @@ func (ab *AccountStub) SuspendAccount(kid string) (*Account, error) {
 	}
 	pac := account.(*Account)
 	if pac.SuspendedTime != nil {
-		return nil, errors.New("already suspended")
+		return nil, InvalidStateError{reason: "already suspended"}
 	}
 
 	pac.SuspendedTime = ts
@@ -257,7 +296,7 @@ func (ab *AccountStub) UnsuspendAccount(kid string) (*Account, error) {
 	}
 	pac := account.(*Account)
 	if nil == pac.SuspendedTime {
-		return nil, errors.New("not suspended")
+		return nil, InvalidStateError{reason: "not suspended"}
 	}
 
 	pac.SuspendedTime = nil
@@ -288,7 +327,7 @@ func (ab *AccountStub) PutHolder(holder *Holder) error {
 // AddHolder _
 func (ab *AccountStub) AddHolder(account *JointAccount, kid string) (*JointAccount, error) {
 	if account.HasHolder(kid) {
-		return nil, errors.New("already existed holder")
+		return nil, InvalidStateError{reason: "already existed holder"}
 	}
 
 	ts, err := txtime.GetTime(ab.stub)
@@ -315,7 +354,7 @@ func (ab *AccountStub) AddHolder(account *JointAccount, kid string) (*JointAccou
 // RemoveHolder _
 func (ab *AccountStub) RemoveHolder(account *JointAccount, kid string) (*JointAccount, error) {
 	if !account.HasHolder(kid) {
-		return nil, errors.New("not existed holder")
+		return nil, InvalidStateError{reason: "not existed holder"}
 	}
 
 	ts, err := txtime.GetTime(ab.stub)
@@ -18,12 +18,12 @@ import (
 // params[1:] : co-holders' personal account addresses (exclude invoker, max 127)
 func accountCreate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// validate available token
@@ -34,15 +34,14 @@ func accountCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respo
 		}
 		// check knt chaincode
 		if _, err = invokeKNT(stub, code, []string{"token"}); err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to get the token meta")
+			return responseError(err, "failed to get the token meta")
 		}
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	ab := NewAccountStub(stub, code)
@@ -66,7 +65,7 @@ func accountCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respo
 
 	addrs := stringset.New(params[1:]...) // remove duplication
 	if addrs.Size() > 128 {
-		return shim.Error("too many holders")
+		return responseErrorCode(ErrorCodeHolderLimit, "too many holders")
 	}
 	// validate & get kid of co-holders
 	for addr := range addrs.Map() {
@@ -78,7 +77,7 @@ func accountCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respo
 	}
 
 	if holders.Size() < 2 { // addrs had invoker's addr
-		return shim.Error("joint account needs co-holders")
+		return responseErrorCode(ErrorCodeInvalidParameter, "joint account needs co-holders")
 	}
 
 	// contract
@@ -90,13 +89,13 @@ func accountCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respo
 // params[0] : token code | account address
 func accountGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, false)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	var addr *Address
@@ -130,20 +129,20 @@ func accountGet(stub shim.ChaincodeStubInterface, params []string) peer.Response
 func accountHolderAdd(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	jac, taddr, err := getValidatedAccountHolderParameters(stub, params)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 	if jac.Holders.Size() > 127 {
-		return shim.Error("already has max holders (128)")
+		return responseErrorCode(ErrorCodeHolderLimit, "already has max holders (128)")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	if !jac.HasHolder(kid) {
-		return shim.Error("no authority")
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
 	}
 
 	ab := NewAccountStub(stub, "")
@@ -155,7 +154,7 @@ func accountHolderAdd(stub shim.ChaincodeStubInterface, params []string) peer.Re
 	holder := pac.Holder()
 
 	if jac.HasHolder(holder) {
-		return shim.Error("existed holder")
+		return responseErrorCode(ErrorCodeExistedHolder, "existed holder")
 	}
 
 	signers := stringset.New(holder)
@@ -171,20 +170,20 @@ func accountHolderAdd(stub shim.ChaincodeStubInterface, params []string) peer.Re
 func accountHolderRemove(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	jac, taddr, err := getValidatedAccountHolderParameters(stub, params)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 	if jac.Holders.Size() < 3 {
-		return shim.Error("the account has minimum holders (2)")
+		return responseErrorCode(ErrorCodeHolderLimit, "the account has minimum holders (2)")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	if !jac.HasHolder(kid) {
-		return shim.Error("no authority")
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
 	}
 
 	holder := taddr.ID()
@@ -204,7 +203,7 @@ func accountHolderRemove(stub shim.ChaincodeStubInterface, params []string) peer
 	}
 
 	if !jac.HasHolder(holder) {
-		return shim.Error("not existed holder")
+		return responseErrorCode(ErrorCodeNotExistedHolder, "not existed holder")
 	}
 
 	signers := stringset.New()
@@ -225,7 +224,7 @@ func accountList(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 	// authentication
 	kid, err := kid.GetID(stub, false)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	code := ""
@@ -236,7 +235,7 @@ func accountList(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 		if len(params[0]) > 0 {
 			code, err = ValidateTokenCode(params[0])
 			if err != nil {
-				return shim.Error(err.Error())
+				return responseError(err, "")
 			}
 		}
 		// bookamrk
@@ -246,7 +245,7 @@ func accountList(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 			if len(params) > 2 {
 				fetchSize, err = strconv.Atoi(params[2])
 				if err != nil {
-					return shim.Error("invalid fetch size")
+					return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
 				}
 			}
 		}
@@ -270,18 +269,18 @@ func accountList(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 // params[0] : token code
 func accountSuspend(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	ab := NewAccountStub(stub, code)
@@ -301,18 +300,18 @@ func accountSuspend(stub shim.ChaincodeStubInterface, params []string) peer.Resp
 // params[0] : token code
 func accountUnsuspend(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	ab := NewAccountStub(stub, code)
@@ -332,7 +331,7 @@ func accountUnsuspend(stub shim.ChaincodeStubInterface, params []string) peer.Re
 
 func getValidatedAccountHolderParameters(stub shim.ChaincodeStubInterface, params []string) (*JointAccount, *Address, error) {
 	if len(params) != 2 {
-		return nil, nil, errors.New("incorrect number of parameters. expecting 2")
+		return nil, nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 2"}
 	}
 
 	addr, err := ParseAddress(params[0])
@@ -340,7 +339,7 @@ func getValidatedAccountHolderParameters(stub shim.ChaincodeStubInterface, param
 		return nil, nil, errors.Wrap(err, "failed to parse the account address")
 	}
 	if addr.Type != AccountTypeJoint {
-		return nil, nil, errors.New("the account must be joint account")
+		return nil, nil, InvalidParameterError{reason: "the account must be joint account"}
 	}
 
 	taddr, err := ParseAddress(params[1])
@@ -348,11 +347,11 @@ func getValidatedAccountHolderParameters(stub shim.ChaincodeStubInterface, param
 		return nil, nil, errors.Wrap(err, "failed to parse the co-holder's account address")
 	}
 	if taddr.Type != AccountTypePersonal {
-		return nil, nil, errors.New("the co-holder's account must be personal account")
+		return nil, nil, InvalidParameterError{reason: "the co-holder's account must be personal account"}
 	}
 
 	if addr.Code != taddr.Code {
-		return nil, nil, errors.New("mismatched token accounts")
+		return nil, nil, InvalidParameterError{reason: "mismatched token accounts"}
 	}
 
 	ab := NewAccountStub(stub, addr.Code)
@@ -379,7 +378,7 @@ func responseAccountWithBalance(account AccountInterface, balance *Balance) peer
 			Balance *Balance `json:"balance"`
 		}{a, balance})
 	} else { // never here
-		return shim.Error("unknown account type")
+		return responseErrorCode(ErrorCodeInternal, "unknown account type")
 	}
 	if err != nil {
 		return responseError(err, "failed to marshal the payload")
@@ -408,7 +407,7 @@ func responseAccountWithBalanceState(account AccountInterface, balance []byte) p
 // doc: ["account/create", code, [co-holders...]]
 func executeAccountCreate(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 3 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	code := doc[1].(string)
@@ -429,7 +428,7 @@ func executeAccountCreate(stub shim.ChaincodeStubInterface, cid string, doc []in
 // doc: ["account/holder/add", address, holder-kid]
 func executeAccountHolderAdd(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 3 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	addr, err := ParseAddress(doc[1].(string))
@@ -447,10 +446,10 @@ func executeAccountHolderAdd(stub shim.ChaincodeStubInterface, cid string, doc [
 
 	// validate
 	if jac.Holders.Size() > 127 {
-		return shim.Error("already has max holders (128)")
+		return responseErrorCode(ErrorCodeHolderLimit, "already has max holders (128)")
 	}
 	if jac.HasHolder(holder) {
-		return shim.Error("existed holder")
+		return responseErrorCode(ErrorCodeExistedHolder, "existed holder")
 	}
 
 	if _, err = ab.AddHolder(jac, holder); err != nil {
@@ -463,7 +462,7 @@ func executeAccountHolderAdd(stub shim.ChaincodeStubInterface, cid string, doc [
 // doc: ["account/holder/remove", address, holder-kid]
 func executeAccountHolderRemove(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 3 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	addr, err := ParseAddress(doc[1].(string))
@@ -481,10 +480,10 @@ func executeAccountHolderRemove(stub shim.ChaincodeStubInterface, cid string, do
 
 	// validate
 	if jac.Holders.Size() < 3 {
-		return shim.Error("the account has minimum holders (2)")
+		return responseErrorCode(ErrorCodeHolderLimit, "the account has minimum holders (2)")
 	}
 	if !jac.HasHolder(holder) {
-		return shim.Error("not existed holder")
+		return responseErrorCode(ErrorCodeNotExistedHolder, "not existed holder")
 	}
 
 	if _, err = ab.RemoveHolder(jac, holder); err != nil {
@@ -5,6 +5,8 @@ package main
 import (
 	"bytes"
 	"math/big"
+	"regexp"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -25,6 +27,29 @@ func NewAmount(val string) (*Amount, error) {
 	return a, nil
 }
 
+var _matchDecimalAmount = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`).MatchString
+
+// IsDecimalAmount returns true if the amount string has a decimal point. (decimal-formatted amount, e.g. "12.5")
+// The amount string without a decimal point is the raw big integer.
+func IsDecimalAmount(val string) bool {
+	return strings.Contains(val, ".")
+}
+
+// NewAmountWithDecimal converts the decimal-formatted amount string with 10^decimal.
+// It is strictly rejected if the fraction digits exceed the decimal. (no rounding)
+func NewAmountWithDecimal(val string, decimal int) (*Amount, error) {
+	if !_matchDecimalAmount(val) {
+		return nil, errors.New("invalid amount value: must be decimal number")
+	}
+	i := strings.IndexByte(val, '.')
+	integer, fraction := val[:i], strings.TrimRight(val[i+1:], "0")
+	if len(fraction) > decimal {
+		return nil, errors.Errorf("invalid amount value: precision exceeded. max %d fraction digits", decimal)
+	}
+	fraction += strings.Repeat("0", decimal-len(fraction))
+	return NewAmount(integer + fraction)
+}
+
 // NewAmountWithBigInt _
 func NewAmountWithBigInt(bigInt *big.Int) *Amount {
 	return &Amount{Int: *bigInt}
@@ -58,6 +83,26 @@ func (a *Amount) Neg() *Amount {
 	return a
 }
 
+// DecimalString returns the amount formatted with 10^decimal. (e.g. 12500000 with decimal 6 => "12.5")
+func (a *Amount) DecimalString(decimal int) string {
+	digits := new(big.Int).Abs(&a.Int).String()
+	if decimal > 0 {
+		if len(digits) <= decimal {
+			digits = strings.Repeat("0", decimal-len(digits)+1) + digits
+		}
+		i := len(digits) - decimal
+		if fraction := strings.TrimRight(digits[i:], "0"); len(fraction) > 0 {
+			digits = digits[:i] + "." + fraction
+		} else {
+			digits = digits[:i]
+		}
+	}
+	if a.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
 // MulRat _
 func (a *Amount) MulRat(r *big.Rat) *Amount {
 	// floor
@@ -0,0 +1,139 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// AmountFormatTransientKey is the transient key of the amount format of the response.
+// If the value is "decimal", every amount of the response is rendered as both the raw integer
+// and the decimal formatted with the token's decimal. (e.g. "amount": "12500000", "amount_decimal": "12.5")
+const AmountFormatTransientKey = "amount_format"
+
+// amountKeys are the JSON keys of the amounts
+var amountKeys = []string{"amount", "balance", "diff", "fee", "max_supply", "sum", "supply", "total", "total_refund"}
+
+// codeKeys are the JSON keys that identify the token of the object. (token code or account address)
+var codeKeys = []string{"@token", "@fee", "@account", "@balance", "@balance_log", "@pay", "address", "account", "sender"}
+
+// isDecimalAmountFormat returns true if the decimal amount format is requested.
+func isDecimalAmountFormat(stub shim.ChaincodeStubInterface) bool {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return false
+	}
+	return string(transient[AmountFormatTransientKey]) == "decimal"
+}
+
+// amountFormatter renders the decimal amounts of the response payload.
+type amountFormatter struct {
+	tb       *TokenStub
+	decimals map[string]int // cache of the token decimals
+}
+
+// renderDecimalAmounts adds the "<key>_decimal" field next to every amount field of the payload.
+// The token of the amount is decided by the code keys of the object or its parents.
+// If the payload is not a JSON object or array (e.g. ver), it returns the payload unchanged.
+func renderDecimalAmounts(stub shim.ChaincodeStubInterface, payload []byte) ([]byte, error) {
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return payload, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	f := &amountFormatter{tb: NewTokenStub(stub), decimals: map[string]int{}}
+	if err := f.render(v, ""); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func (f *amountFormatter) render(v interface{}, code string) error {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if err := f.render(e, code); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		if c := objectCode(t); len(c) > 0 {
+			code = c
+			if decimal, ok := t["decimal"].(json.Number); ok && t["@token"] != nil {
+				d, err := decimal.Int64()
+				if err != nil {
+					return err
+				}
+				f.decimals[code] = int(d)
+			}
+		}
+		for k, e := range t {
+			if err := f.render(e, code); err != nil {
+				return err
+			}
+			if len(code) == 0 {
+				continue
+			}
+			if s, ok := e.(string); ok && isAmountKey(k) {
+				n, ok := new(big.Int).SetString(s, 10)
+				if !ok {
+					continue
+				}
+				decimal, err := f.decimal(code)
+				if err != nil {
+					return err
+				}
+				t[k+"_decimal"] = NewAmountWithBigInt(n).DecimalString(decimal)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *amountFormatter) decimal(code string) (int, error) {
+	if decimal, ok := f.decimals[code]; ok {
+		return decimal, nil
+	}
+	token, err := f.tb.GetToken(code)
+	if err != nil {
+		return 0, err
+	}
+	f.decimals[code] = token.Decimal
+	return token.Decimal, nil
+}
+
+// helpers
+
+func isAmountKey(key string) bool {
+	for _, k := range amountKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// objectCode returns the token code of the object, or empty string if it can't be identified.
+func objectCode(obj map[string]interface{}) string {
+	for _, k := range codeKeys {
+		s, ok := obj[k].(string)
+		if !ok || len(s) == 0 {
+			continue
+		}
+		if code, err := ValidateTokenCode(s); err == nil {
+			return code
+		}
+		if addr, err := ParseAddress(s); err == nil {
+			return addr.Code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,580 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Every route accepts its parameters in two forms.
+// - positional : the params of the route as they are. (see params[n] comments of the route)
+// - named : a single JSON object argument. e.g. {"receiver": "...", "amount": "100", "expiry": 3600}
+// The named form is validated against the ArgSchema of the route and converted to the positional form.
+
+// ArgSpec is the spec of a named argument.
+type ArgSpec struct {
+	Name     string
+	Required bool
+	Default  string // used when the argument is omitted but a later argument is given
+	Variadic bool   // the rest of the params (the last spec only). JSON array of strings.
+	JSON     bool   // the JSON value (e.g. array of objects) is passed as the param as it is.
+	Needs    string // the name of the argument which must be given with it (checked for the variadic spec only)
+}
+
+// ArgSchema is the ordered specs of the named arguments of a route.
+// The index of the spec is the index of the positional param.
+type ArgSchema []ArgSpec
+
+// argSchemas is the map of the named argument schemas of the routes
+var argSchemas = map[string]ArgSchema{
+	"account/create": {
+		{Name: "code", Required: true},
+		{Name: "holders", Variadic: true},
+	},
+	"account/get": {
+		{Name: "address", Required: true}, // token code | account address
+	},
+	"account/holder/add": {
+		{Name: "address", Required: true},
+		{Name: "holder", Required: true},
+	},
+	"account/holder/remove": {
+		{Name: "address", Required: true},
+		{Name: "holder", Required: true},
+	},
+	"account/list": {
+		{Name: "code"},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"account/suspend": {
+		{Name: "code", Required: true},
+	},
+	"account/unsuspend": {
+		{Name: "code", Required: true},
+	},
+	"balance/at": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "time", Required: true},
+	},
+	"balance/history": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "fetch_size", Default: "0"},
+		{Name: "start_time"},
+		{Name: "end_time"},
+	},
+	"balance/logs": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "type"},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+		{Name: "start_time"},
+		{Name: "end_time"},
+	},
+	"balance/logs/verify": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "start_time"},
+		{Name: "end_time"},
+		{Name: "fetch_size", Default: "0"},
+		{Name: "cursor"},
+	},
+	"balance/pending/get": {
+		{Name: "id", Required: true},
+	},
+	"balance/pending/list": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "sort", Default: "pending_time"},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"balance/pending/withdraw": {
+		{Name: "id", Required: true},
+	},
+	"balance/statement": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "start_time", Required: true},
+		{Name: "end_time", Required: true},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+		{Name: "format"},
+	},
+	"bridge/compact": {
+		{Name: "token", Required: true},
+		{Name: "ext_code", Required: true},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"bridge/status": {
+		{Name: "token", Required: true},
+		{Name: "ext_code"},
+	},
+	"contract/execute": {
+		{Name: "contract_id", Required: true},
+		{Name: "document", Required: true},
+	},
+	"contract/cancel": {
+		{Name: "contract_id", Required: true},
+		{Name: "document", Required: true},
+	},
+	"conversion/get": {
+		{Name: "conversion_id", Required: true},
+	},
+	"conversion/logs": {
+		{Name: "conversion_id", Required: true},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"conversion/register": {
+		{Name: "source", Required: true},
+		{Name: "target", Required: true},
+		{Name: "rate", Required: true},
+		{Name: "reserve_account", Required: true},
+		{Name: "mode"},
+		{Name: "min_amount"},
+		{Name: "max_amount"},
+	},
+	"conversion/update": {
+		{Name: "conversion_id", Required: true},
+		{Name: "rate", Required: true},
+		{Name: "min_amount"},
+		{Name: "max_amount"},
+		{Name: "paused"},
+	},
+	"convert": {
+		{Name: "conversion_id", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "memo"},
+	},
+	"dex/book": {
+		{Name: "market", Required: true},
+		{Name: "depth"},
+	},
+	"dex/order/cancel": {
+		{Name: "order_id", Required: true},
+	},
+	"dex/order/get": {
+		{Name: "order_id", Required: true},
+	},
+	"dex/order/place": {
+		{Name: "market", Required: true},
+		{Name: "side", Required: true},
+		{Name: "price", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "base_account"},
+		{Name: "quote_account"},
+	},
+	"dex/trades": {
+		{Name: "market", Required: true},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"distribution/claim": {
+		{Name: "distribution_id", Required: true},
+		{Name: "account"},
+	},
+	"distribution/close": {
+		{Name: "distribution_id", Required: true},
+	},
+	"distribution/create": {
+		{Name: "code", Required: true},
+		{Name: "payout_account", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "expiry", Required: true},
+		{Name: "snapshot_time"},
+		{Name: "memo"},
+		{Name: "excluded", Variadic: true},
+	},
+	"distribution/get": {
+		{Name: "distribution_id", Required: true},
+		{Name: "account"},
+	},
+	"fee/list": {
+		{Name: "code", Required: true},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+		{Name: "start_time"},
+		{Name: "end_time"},
+	},
+	"fee/prune": {
+		{Name: "code", Required: true},
+		{Name: "safely", Required: true},
+		{Name: "end_time"},
+	},
+	"fee/prune/preview": {
+		{Name: "code", Required: true},
+		{Name: "safely", Required: true},
+		{Name: "end_time"},
+	},
+	"fee/sponsor": {
+		{Name: "sponsor", Required: true},
+		{Name: "account", Required: true},
+	},
+	"fee/sponsor/get": {
+		{Name: "sponsor", Required: true},
+		{Name: "account", Required: true},
+	},
+	"fee/sponsor/revoke": {
+		{Name: "sponsor", Required: true},
+		{Name: "account", Required: true},
+	},
+	"htlc/claim": {
+		{Name: "htlc_id", Required: true},
+		{Name: "preimage", Required: true},
+	},
+	"htlc/get": {
+		{Name: "htlc_id", Required: true},
+	},
+	"htlc/lock": {
+		{Name: "sender"},
+		{Name: "receiver", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "hash_algo", Required: true},
+		{Name: "hashlock", Required: true},
+		{Name: "timelock", Required: true},
+		{Name: "memo"},
+		{Name: "order_id"},
+	},
+	"htlc/refund": {
+		{Name: "htlc_id", Required: true},
+	},
+	"pay": {
+		{Name: "sender"},
+		{Name: "receiver", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "order_id"},
+		{Name: "memo"},
+		{Name: "expiry"},
+		{Name: "fee_bearer"},
+		{Name: "sponsor"},
+	},
+	"pay/get": {
+		{Name: "pay_id", Required: true},
+		{Name: "order_id"},
+	},
+	"pay/prune": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "safely", Required: true},
+		{Name: "end_time"},
+	},
+	"pay/prune/preview": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "safely", Required: true},
+		{Name: "end_time"},
+	},
+	"pay/list": {
+		{Name: "address", Required: true}, // token code | account address
+		{Name: "sort_order", Default: "desc"},
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+		{Name: "start_time"},
+		{Name: "end_time"},
+	},
+	"pay/refund": {
+		{Name: "pay_id", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "memo"},
+		{Name: "order_id"},
+	},
+	"pool/add": {
+		{Name: "pool_id", Required: true},
+		{Name: "amount1", Required: true}, // the first token of the pool id
+		{Name: "amount2", Required: true}, // the second token of the pool id
+		{Name: "min_shares"},
+	},
+	"pool/create": {
+		{Name: "token1", Required: true},
+		{Name: "token2", Required: true},
+		{Name: "amount1", Required: true},
+		{Name: "amount2", Required: true},
+		{Name: "fee_rate"},
+	},
+	"pool/get": {
+		{Name: "pool_id", Required: true},
+	},
+	"pool/remove": {
+		{Name: "pool_id", Required: true},
+		{Name: "shares", Required: true},
+		{Name: "min_amount1"},
+		{Name: "min_amount2"},
+	},
+	"pool/share/get": {
+		{Name: "pool_id", Required: true},
+	},
+	"pool/swap": {
+		{Name: "pool_id", Required: true},
+		{Name: "token_in", Required: true},
+		{Name: "amount_in", Required: true},
+		{Name: "min_amount_out"},
+	},
+	"quote": {
+		{Name: "fn", Required: true},
+		{Name: "args", Variadic: true}, // JSON array or JSON object (named arguments of the fn)
+	},
+	"token/audit": {
+		{Name: "code", Required: true},
+		{Name: "cursor"},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"token/burn": {
+		{Name: "code", Required: true},
+		{Name: "amount", Required: true},
+	},
+	"token/create": {
+		{Name: "code", Required: true},
+		{Name: "holders", Variadic: true},
+	},
+	"token/get": {
+		{Name: "code", Required: true},
+	},
+	"token/mint": {
+		{Name: "code", Required: true},
+		{Name: "amount", Required: true},
+	},
+	"token/update": {
+		{Name: "code", Required: true},
+	},
+	"transfer": {
+		{Name: "sender"},
+		{Name: "receiver", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "memo"},
+		{Name: "order_id"},
+		{Name: "pending_time", Default: "0"},
+		{Name: "expiry"},
+		{Name: "fee_bearer"},
+		{Name: "sponsor"},
+		{Name: "signers", Variadic: true, Needs: "expiry"}, // multi-sig only
+	},
+	"transfer/multi": {
+		{Name: "legs", Required: true, JSON: true}, // JSON array of the legs
+		{Name: "order_id"},
+		{Name: "memo"},
+	},
+	"transfer/get": {
+		{Name: "order_id", Required: true},
+	},
+	"wrap": {
+		{Name: "sender", Required: true}, // sender address | token code
+		{Name: "ext_code", Required: true},
+		{Name: "ext_id", Required: true},
+		{Name: "amount", Required: true},
+		{Name: "memo"},
+		{Name: "order_id"},
+		{Name: "expiry"},
+		{Name: "signers", Variadic: true, Needs: "expiry"}, // multi-sig only
+	},
+	"wrap/complete": {
+		{Name: "wrap_id", Required: true},
+		{Name: "fee", Default: "0"},
+		{Name: "ext_tx_id"}, // if it is omitted, it is 'impossible wrap'
+	},
+	"wrap/complete/batch": {
+		{Name: "items", Required: true, JSON: true}, // JSON array of the items
+	},
+	"wrap/get": {
+		{Name: "wrap_id", Required: true},
+	},
+	"wrap/list": {
+		{Name: "token", Required: true},
+		{Name: "ext_code"},
+		{Name: "sender"},
+		{Name: "status"}, // requested | completed | impossible | refunded
+		{Name: "bookmark"},
+		{Name: "fetch_size", Default: "0"},
+		{Name: "start_time"},
+		{Name: "end_time"},
+	},
+	"wrap/migrate": {
+		{Name: "token", Required: true},
+		{Name: "start"},
+		{Name: "fetch_size", Default: "0"},
+	},
+	"wrap/refund": {
+		{Name: "wrap_id", Required: true},
+	},
+	"unwrap": {
+		{Name: "receiver", Required: true}, // receiver address | token code
+		{Name: "ext_code", Required: true},
+		{Name: "ext_id", Required: true},
+		{Name: "ext_tx_id", Required: true},
+		{Name: "amount", Required: true},
+	},
+	"unwrap/attest": {
+		{Name: "receiver", Required: true}, // receiver address | token code
+		{Name: "ext_code", Required: true},
+		{Name: "ext_id", Required: true},
+		{Name: "ext_tx_id", Required: true},
+		{Name: "amount", Required: true},
+	},
+	"unwrap/attestation/get": {
+		{Name: "ext_tx_id", Required: true},
+		{Name: "token"},
+		{Name: "ext_code"},
+	},
+	"unwrap/batch": {
+		{Name: "token", Required: true},
+		{Name: "ext_code", Required: true},
+		{Name: "items", Required: true, JSON: true}, // JSON array of the items
+	},
+	"unwrap/get": {
+		{Name: "ext_tx_id", Required: true},
+		{Name: "token"},
+		{Name: "ext_code"},
+	},
+	"ver": {},
+}
+
+// IsNamedArgs returns true if the params are the named form. (a single JSON object argument)
+func IsNamedArgs(params []string) bool {
+	return len(params) == 1 && strings.HasPrefix(strings.TrimSpace(params[0]), "{")
+}
+
+// ParseArgs returns the positional params of the route.
+// If the params are the positional form, they are returned as they are.
+func ParseArgs(fn string, params []string) ([]string, error) {
+	if !IsNamedArgs(params) {
+		return params, nil
+	}
+	schema, ok := argSchemas[fn]
+	if !ok {
+		return nil, InvalidParameterError{reason: "named arguments are not supported: [" + fn + "]"}
+	}
+	return schema.Positional(params[0])
+}
+
+// Positional converts the named arguments(JSON object) to the positional params.
+// Omitted arguments before the last given argument are filled with their defaults,
+// and omitted trailing arguments are left out like the positional form.
+func (s ArgSchema) Positional(arg string) ([]string, error) {
+	fields := map[string]json.RawMessage{}
+	dec := json.NewDecoder(strings.NewReader(arg))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return nil, InvalidParameterError{reason: "invalid named arguments: " + err.Error()}
+	}
+
+	// unknown fields
+	unknowns := []string{}
+	for name := range fields {
+		if s.index(name) < 0 {
+			unknowns = append(unknowns, name)
+		}
+	}
+	if len(unknowns) > 0 {
+		sort.Strings(unknowns) // deterministic message
+		return nil, InvalidParameterError{reason: "unknown fields: " + strings.Join(unknowns, ", ") + " (expecting " + s.names() + ")"}
+	}
+
+	params := []string{}
+	last := 0 // length of the params to be returned
+	for i, spec := range s {
+		raw, ok := fields[spec.Name]
+		if ok && isNullJSON(raw) {
+			ok = false
+		}
+		if spec.Variadic {
+			if ok {
+				values, err := variadicArg(spec.Name, raw)
+				if err != nil {
+					return nil, err
+				}
+				if len(values) > 0 && len(spec.Needs) > 0 && len(params[s.index(spec.Needs)]) == 0 {
+					return nil, InvalidParameterError{reason: spec.Name + " needs " + spec.Needs}
+				}
+				params = append(params, values...)
+				if len(values) > 0 {
+					last = len(params)
+				}
+			} else if spec.Required {
+				return nil, InvalidParameterError{reason: "missing field: " + spec.Name}
+			}
+			break
+		}
+		if !ok {
+			if spec.Required {
+				return nil, InvalidParameterError{reason: "missing field: " + spec.Name}
+			}
+			params = append(params, spec.Default)
+			continue
+		}
+		value := string(bytes.TrimSpace(raw))
+		if !spec.JSON {
+			var err error
+			if value, err = scalarArg(spec.Name, raw); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, value)
+		last = i + 1
+	}
+	return params[:last], nil
+}
+
+func (s ArgSchema) index(name string) int {
+	for i, spec := range s {
+		if spec.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s ArgSchema) names() string {
+	names := make([]string, len(s))
+	for i, spec := range s {
+		names[i] = spec.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// helpers
+
+func isNullJSON(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// scalarArg returns the string of JSON string, number or bool.
+func scalarArg(name string, raw json.RawMessage) (string, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", InvalidParameterError{reason: "invalid field: " + name}
+	}
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		if t {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	return "", InvalidParameterError{reason: "invalid field: " + name + ". expecting string, number or bool"}
+}
+
+// variadicArg returns the strings of JSON array.
+// JSON object is passed as a single named argument. (e.g. args of quote)
+func variadicArg(name string, raw json.RawMessage) ([]string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return []string{string(trimmed)}, nil
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(trimmed, &values); err != nil {
+		return nil, InvalidParameterError{reason: "invalid field: " + name + ". expecting array"}
+	}
+	params := make([]string, 0, len(values))
+	for _, v := range values {
+		value, err := scalarArg(name, v)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, value)
+	}
+	return params, nil
+}
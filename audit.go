@@ -0,0 +1,146 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// TokenAuditFetchSize is the default number of the states of a token/audit page.
+const TokenAuditFetchSize = 200
+
+// TokenAuditMaxFetchSize _
+const TokenAuditMaxFetchSize = 1000
+
+// TokenAuditBucket is the kind of the states which hold the token.
+type TokenAuditBucket int8
+
+const (
+	// TokenAuditBucketBalance balances of the accounts
+	TokenAuditBucketBalance TokenAuditBucket = iota
+	// TokenAuditBucketPending locked pending balances (contract, htlc, dex, distribution, ...)
+	TokenAuditBucketPending
+	// TokenAuditBucketPay unpruned pays (receivables of the merchants, refunds are negative)
+	TokenAuditBucketPay
+	// TokenAuditBucketFee unpruned fee utxos
+	TokenAuditBucketFee
+	// TokenAuditBucketWrap requested wraps (debited from the senders, not yet credited to the wrap accounts)
+	TokenAuditBucketWrap
+	// TokenAuditBucketDone all buckets are audited
+	TokenAuditBucketDone
+)
+
+var tokenAuditBucketNames = []string{"balances", "pending_balances", "pays", "fees", "requested_wraps"}
+
+// String _
+func (b TokenAuditBucket) String() string {
+	if b < TokenAuditBucketBalance || b >= TokenAuditBucketDone {
+		return "done"
+	}
+	return tokenAuditBucketNames[b]
+}
+
+// TokenAuditTotal is the accumulated total of the bucket.
+type TokenAuditTotal struct {
+	Sum   Amount `json:"sum"`
+	Count int    `json:"count"`
+}
+
+// TokenAuditCursor is the resumable position of the audit with the accumulated totals.
+type TokenAuditCursor struct {
+	Bucket   TokenAuditBucket            `json:"bucket"`
+	Bookmark string                      `json:"bookmark"`
+	Totals   map[string]*TokenAuditTotal `json:"totals"`
+}
+
+// NewTokenAuditCursor returns the cursor of the first page.
+// The totals of all buckets are initialized, so the cursor of the older version (without a new bucket) is still valid.
+func NewTokenAuditCursor() *TokenAuditCursor {
+	totals := map[string]*TokenAuditTotal{}
+	for _, name := range tokenAuditBucketNames {
+		totals[name] = &TokenAuditTotal{Sum: *ZeroAmount()}
+	}
+	return &TokenAuditCursor{Bucket: TokenAuditBucketBalance, Totals: totals}
+}
+
+// ParseTokenAuditCursor decodes the cursor string. (base64 url encoded JSON)
+func ParseTokenAuditCursor(s string) (*TokenAuditCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, InvalidParameterError{reason: "invalid cursor"}
+	}
+	cursor := NewTokenAuditCursor()
+	if err = json.Unmarshal(data, cursor); err != nil {
+		return nil, InvalidParameterError{reason: "invalid cursor"}
+	}
+	if cursor.Bucket < TokenAuditBucketBalance || cursor.Bucket >= TokenAuditBucketDone {
+		return nil, InvalidParameterError{reason: "invalid cursor bucket"}
+	}
+	for _, name := range tokenAuditBucketNames {
+		if cursor.Totals[name] == nil {
+			return nil, InvalidParameterError{reason: "invalid cursor totals"}
+		}
+	}
+	return cursor, nil
+}
+
+// String encodes the cursor.
+func (c *TokenAuditCursor) String() string {
+	data, _ := json.Marshal(c) // never fails
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Sum returns the sum of all buckets.
+func (c *TokenAuditCursor) Sum() *Amount {
+	sum := ZeroAmount()
+	for _, total := range c.Totals {
+		sum.Add(&total.Sum)
+	}
+	return sum
+}
+
+// TokenAuditDiscrepancy _
+type TokenAuditDiscrepancy struct {
+	Bucket   string  `json:"bucket"`        // bucket name or "supply"
+	Key      string  `json:"key,omitempty"` // state key
+	Expected *Amount `json:"expected,omitempty"`
+	Actual   *Amount `json:"actual"`
+	Reason   string  `json:"reason"`
+}
+
+// TokenAudit is the response payload of token/audit.
+// Each page has the discrepancies of the audited states in the page,
+// and the last page (done) has the result of the supply invariant.
+// supply = balances + pending balances + unpruned pays + unpruned fees + requested wraps
+type TokenAudit struct {
+	Token         string                      `json:"token"`
+	Supply        Amount                      `json:"supply"`
+	Bucket        string                      `json:"bucket"` // audited bucket of the page
+	Fetched       int                         `json:"fetched"`
+	Totals        map[string]*TokenAuditTotal `json:"totals"` // accumulated totals
+	Done          bool                        `json:"done"`
+	Sum           *Amount                     `json:"sum,omitempty"`    // done only
+	Holds         bool                        `json:"holds"`            // done only, the invariant holds
+	Cursor        string                      `json:"cursor,omitempty"` // cursor of the next page, empty if done
+	Discrepancies []*TokenAuditDiscrepancy    `json:"discrepancies"`
+}
+
+// parseUTXOIDTime returns the time of the pay/fee ID. (unix nano + tx id)
+func parseUTXOIDTime(id string) (*txtime.Time, error) {
+	if len(id) < 19 {
+		return nil, InvalidParameterError{reason: "invalid utxo id"}
+	}
+	s, err := strconv.ParseInt(id[0:10], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseInt(id[10:19], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return txtime.Unix(s, n), nil
+}
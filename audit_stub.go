@@ -0,0 +1,215 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// AuditStub _
+type AuditStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewAuditStub _
+func NewAuditStub(stub shim.ChaincodeStubInterface) *AuditStub {
+	return &AuditStub{stub}
+}
+
+// Audit walks a page of the bucket of the cursor and accumulates the totals.
+// When the bucket is walked through, the cursor moves to the next bucket.
+// The states of the other tokens in the key range are skipped.
+func (ab *AuditStub) Audit(token *Token, cursor *TokenAuditCursor, fetchSize int) (*TokenAudit, error) {
+	if fetchSize < 1 {
+		fetchSize = TokenAuditFetchSize
+	}
+	if fetchSize > TokenAuditMaxFetchSize {
+		fetchSize = TokenAuditMaxFetchSize
+	}
+
+	code := token.DOCTYPEID
+	bucket := cursor.Bucket
+	res := &TokenAudit{
+		Token:         code,
+		Supply:        token.Supply,
+		Bucket:        bucket.String(),
+		Totals:        cursor.Totals,
+		Discrepancies: []*TokenAuditDiscrepancy{},
+	}
+	total := cursor.Totals[bucket.String()]
+
+	var prefix string
+	var prunedTime *txtime.Time                 // fee
+	payPrunedTimes := map[string]*txtime.Time{} // pay, by merchant
+	switch bucket {
+	case TokenAuditBucketBalance:
+		prefix = NewBalanceStub(ab.stub).CreateKey(code)
+	case TokenAuditBucketPending:
+		prefix = NewBalanceStub(ab.stub).CreatePendingKey("")
+	case TokenAuditBucketPay:
+		prefix = "PAY_" // see PayStub.CreateKey
+	case TokenAuditBucketFee:
+		prefix = NewFeeStub(ab.stub).CreateKey("")
+		var err error
+		if prunedTime, err = ab.getFeePrunedTime(token); err != nil {
+			return nil, err
+		}
+	case TokenAuditBucketWrap:
+		prefix = NewWrapStub(ab.stub).CreateWrapKey("")
+	}
+
+	iter, meta, err := ab.stub.GetStateByRangeWithPagination(prefix, prefix+string(utf8.MaxRune), int32(fetchSize), cursor.Bookmark)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the states")
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		res.Fetched++
+
+		var amount *Amount
+		switch bucket {
+		case TokenAuditBucketBalance:
+			bal := &Balance{}
+			if err = json.Unmarshal(kv.Value, bal); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal the balance")
+			}
+			if c, _ := ParseCode(bal.DOCTYPEID); c != code {
+				continue
+			}
+			amount = &bal.Amount
+		case TokenAuditBucketPending:
+			pb := &PendingBalance{}
+			if err = json.Unmarshal(kv.Value, pb); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal the pending balance")
+			}
+			if c, _ := ParseCode(pb.Account); c != code {
+				continue
+			}
+			amount = &pb.Amount
+		case TokenAuditBucketPay:
+			pay := &Pay{}
+			if err = json.Unmarshal(kv.Value, pay); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal the pay")
+			}
+			if c, _ := ParseCode(pay.DOCTYPEID); c != code {
+				continue
+			}
+			pt, ok := payPrunedTimes[pay.DOCTYPEID]
+			if !ok {
+				if pt, err = ab.getPayPrunedTime(pay.DOCTYPEID); err != nil {
+					return nil, err
+				}
+				payPrunedTimes[pay.DOCTYPEID] = pt
+			}
+			if pt != nil && pay.CreatedTime.Cmp(pt) <= 0 { // pruned
+				continue
+			}
+			amount = &pay.Amount
+		case TokenAuditBucketFee:
+			fee := &Fee{}
+			if err = json.Unmarshal(kv.Value, fee); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal the fee")
+			}
+			if fee.DOCTYPEID != code {
+				continue
+			}
+			if prunedTime != nil && fee.CreatedTime.Cmp(prunedTime) <= 0 { // pruned
+				continue
+			}
+			amount = &fee.Amount
+		case TokenAuditBucketWrap:
+			wrap := &Wrap{}
+			if err = json.Unmarshal(kv.Value, wrap); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal the wrap")
+			}
+			wrap.normalize()
+			if wrap.Token != code || wrap.Status != WrapStatusRequested {
+				continue
+			}
+			amount = &wrap.Amount
+		}
+
+		if amount.Sign() < 0 && bucket != TokenAuditBucketPay { // refunds are negative pays
+			res.Discrepancies = append(res.Discrepancies, &TokenAuditDiscrepancy{
+				Bucket: bucket.String(),
+				Key:    kv.Key,
+				Actual: amount.Copy(),
+				Reason: "negative amount",
+			})
+		}
+		total.Count++
+		total.Sum.Add(amount)
+	}
+
+	// next page
+	if meta == nil || len(meta.Bookmark) == 0 || int(meta.FetchedRecordsCount) < fetchSize {
+		cursor.Bucket++
+		cursor.Bookmark = ""
+	} else {
+		cursor.Bookmark = meta.Bookmark
+	}
+	if cursor.Bucket < TokenAuditBucketDone {
+		res.Cursor = cursor.String()
+		return res, nil
+	}
+
+	// supply invariant
+	res.Done = true
+	res.Sum = cursor.Sum()
+	res.Holds = res.Sum.Cmp(&token.Supply) == 0
+	if !res.Holds {
+		res.Discrepancies = append(res.Discrepancies, &TokenAuditDiscrepancy{
+			Bucket:   "supply",
+			Expected: token.Supply.Copy(),
+			Actual:   res.Sum,
+			Reason:   "supply is not equal to the sum of the buckets",
+		})
+	}
+	if token.MaxSupply.Sign() > 0 && token.Supply.Cmp(&token.MaxSupply) > 0 {
+		res.Holds = false
+		res.Discrepancies = append(res.Discrepancies, &TokenAuditDiscrepancy{
+			Bucket:   "supply",
+			Expected: token.MaxSupply.Copy(),
+			Actual:   token.Supply.Copy(),
+			Reason:   "supply exceeds the max supply",
+		})
+	}
+	return res, nil
+}
+
+// getPayPrunedTime returns the created time of the last pruned pay of the merchant. If nothing is pruned, it returns nil.
+func (ab *AuditStub) getPayPrunedTime(addr string) (*txtime.Time, error) {
+	bal, err := NewBalanceStub(ab.stub).GetBalance(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(bal.LastPrunedPayID) == 0 {
+		return nil, nil
+	}
+	return parseUTXOIDTime(bal.LastPrunedPayID)
+}
+
+// getFeePrunedTime returns the created time of the last pruned fee of the token. If nothing is pruned, it returns nil.
+func (ab *AuditStub) getFeePrunedTime(token *Token) (*txtime.Time, error) {
+	feeID := token.LastPrunedFeeID // legacy
+	lpf, err := NewLastPrunedFeeIDStub(ab.stub).GetLastPrunedFeeID(token.DOCTYPEID)
+	if err == nil {
+		feeID = lpf.FeeID
+	} else if _, ok := err.(NotInitLastPrunedFeeIDError); !ok {
+		return nil, err
+	}
+	if len(feeID) == 0 {
+		return nil, nil
+	}
+	return parseUTXOIDTime(feeID)
+}
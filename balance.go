@@ -3,6 +3,14 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/protos/peer"
 	"github.com/key-inside/kiesnet-ccpkg/contract"
 	"github.com/key-inside/kiesnet-ccpkg/txtime"
 )
@@ -21,6 +29,32 @@ func (b *Balance) GetID() string {
 	return b.DOCTYPEID
 }
 
+// BalanceHistoryMaxSize is the max number of the modifications of balance/history.
+const BalanceHistoryMaxSize = 200
+
+// BalanceAt is the response payload of balance/at.
+type BalanceAt struct {
+	Address    string       `json:"address"`
+	Time       *txtime.Time `json:"time"`
+	Amount     Amount       `json:"amount"`
+	BalanceLog *BalanceLog  `json:"balance_log,omitempty"` // the last balance log at the time
+}
+
+// BalanceHistory is the modification of the balance state. (see stub.GetHistoryForKey)
+type BalanceHistory struct {
+	TxID      string       `json:"tx_id"`
+	Timestamp *txtime.Time `json:"timestamp"`
+	IsDelete  bool         `json:"is_delete,omitempty"`
+	Balance   *Balance     `json:"balance,omitempty"`
+}
+
+// BalanceHistoryResult is the response payload of balance/history.
+type BalanceHistoryResult struct {
+	Address string            `json:"address"`
+	History []*BalanceHistory `json:"history"`
+	HasMore bool              `json:"has_more"` // more modifications than the fetch size in the time range
+}
+
 // BalanceLogType _
 type BalanceLogType int8
 
@@ -53,6 +87,34 @@ const (
 	BalanceLogTypeWrapComplete
 	// BalanceLogTypeUnwrapComplete unwrap balance from bridge account
 	BalanceLogTypeUnwrapComplete
+	// BalanceLogTypeHTLCLock lock balance to htlc
+	BalanceLogTypeHTLCLock
+	// BalanceLogTypeHTLCClaim claim htlc balance with the preimage
+	BalanceLogTypeHTLCClaim
+	// BalanceLogTypeHTLCRefund refund htlc balance after the timelock
+	BalanceLogTypeHTLCRefund
+	// BalanceLogTypeDexTrade net change of the account by the dex order placement (lock, trades and unlock)
+	BalanceLogTypeDexTrade
+	// BalanceLogTypeDexCancel unlock balance of the cancelled dex order
+	BalanceLogTypeDexCancel
+	// BalanceLogTypePoolAdd add liquidity to the pool (or create the pool)
+	BalanceLogTypePoolAdd
+	// BalanceLogTypePoolRemove remove liquidity from the pool
+	BalanceLogTypePoolRemove
+	// BalanceLogTypePoolSwap swap tokens with the pool
+	BalanceLogTypePoolSwap
+	// BalanceLogTypeConvert convert the token to the other token at the issuer-set rate
+	BalanceLogTypeConvert
+	// BalanceLogTypeDistributionCreate lock balance of the payout account to the distribution
+	BalanceLogTypeDistributionCreate
+	// BalanceLogTypeDistributionClaim claim the share of the distribution
+	BalanceLogTypeDistributionClaim
+	// BalanceLogTypeDistributionClose return the remainder of the distribution to the payout account
+	BalanceLogTypeDistributionClose
+	// BalanceLogTypeWrapRefund return the amount of the expired wrap to the sender
+	BalanceLogTypeWrapRefund
+	// BalanceLogTypeFeeSponsor the fee of the other account's transaction borne by the sponsor
+	BalanceLogTypeFeeSponsor
 )
 
 // BalanceLog _
@@ -71,11 +133,111 @@ type BalanceLog struct {
 	OrderID      string         `json:"order_id,omitempty"`       // order id. vendor specific unique identifier.
 	ExtCode      string         `json:"ext_code,omitempty"`       // used for wrap, unwrap balance log : external token code
 	ExtTxID      string         `json:"ext_tx_id,omitempty"`      // used for unwrap, wrap/complete balance log : external tx hash
+	PrevHash     string         `json:"prev_hash,omitempty"`      // hash of the previous balance log of the account (empty if it is the first hashed log)
+	Hash         string         `json:"hash,omitempty"`           // hash of this balance log (see ComputeHash). legacy logs have no hash.
+}
+
+// ComputeHash returns the hex encoded SHA-256 hash of the JSON of the log without the hash field.
+// The JSON includes the previous hash and the resulting balance amount, so the logs of an account are chained.
+func (bl *BalanceLog) ComputeHash() (string, error) {
+	c := *bl
+	c.Hash = ""
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// BalanceLogHead is the hash of the last balance log of the account. (the head of the hash chain)
+type BalanceLogHead struct {
+	DOCTYPEID   string       `json:"@balance_log_head"` // address
+	Hash        string       `json:"hash"`
+	CreatedTime *txtime.Time `json:"created_time"` // created time of the last balance log
+}
+
+// BalanceLogsVerifyMaxSize is the max number of the balance logs of balance/logs/verify.
+const BalanceLogsVerifyMaxSize = 1000
+
+// BalanceLogsVerifyFetchSize _
+const BalanceLogsVerifyFetchSize = 200
+
+// BalanceLogBrokenLink _
+type BalanceLogBrokenLink struct {
+	Key      string `json:"key"` // balance log key (or balance log head key)
+	Reason   string `json:"reason"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// BalanceLogsVerification is the response payload of balance/logs/verify.
+type BalanceLogsVerification struct {
+	Address     string                `json:"address"`
+	Verified    int                   `json:"verified"` // number of the verified logs
+	Legacy      int                   `json:"legacy"`   // number of the legacy logs (no hash) before the first hashed log
+	Valid       bool                  `json:"valid"`
+	Broken      *BalanceLogBrokenLink `json:"broken,omitempty"` // the first broken link
+	HeadChecked bool                  `json:"head_checked"`     // the last log is compared with the head
+	Cursor      string                `json:"cursor,omitempty"` // unix nano time of the next log if there are more logs
 }
 
 // MemoMaxLength is used to limit memo field length (BalanceLog, PendingBalance, Pay)
 const MemoMaxLength = 1024
 
+// BalanceStatementTotal is the totals of the balance log type in the statement period.
+type BalanceStatementTotal struct {
+	Type  BalanceLogType `json:"type"`
+	Count int            `json:"count"`
+	Diff  Amount         `json:"diff"` // sum of the diffs
+	Fee   Amount         `json:"fee"`  // sum of the fees
+}
+
+// BalanceStatement is the response payload of balance/statement.
+// The period is [start time, end time), and the entries are the paged balance logs of the period. (latest first)
+type BalanceStatement struct {
+	Address        string                      `json:"address"`
+	StartTime      *txtime.Time                `json:"start_time"`
+	EndTime        *txtime.Time                `json:"end_time"`
+	OpeningBalance Amount                      `json:"opening_balance"` // balance before the start time
+	ClosingBalance Amount                      `json:"closing_balance"` // balance before the end time
+	Totals         []*BalanceStatementTotal    `json:"totals"`          // sorted by the type
+	TotalFee       Amount                      `json:"total_fee"`
+	Meta           *peer.QueryResponseMetadata `json:"meta,omitempty"`    // paging of the entries
+	Entries        json.RawMessage             `json:"entries,omitempty"` // JSON format
+	CSV            string                      `json:"csv,omitempty"`     // CSV format
+}
+
+// BalanceLogsCSVHeader is the header row of the CSV formatted balance logs.
+var BalanceLogsCSVHeader = []string{"created_time", "type", "rid", "diff", "fee", "amount", "memo", "order_id"}
+
+// RenderBalanceLogsCSV renders the balance logs as CSV. (with the header row)
+func RenderBalanceLogsCSV(logs []*BalanceLog) (string, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.Write(BalanceLogsCSVHeader); err != nil {
+		return "", err
+	}
+	for _, log := range logs {
+		createdTime, fee := "", "0"
+		if log.CreatedTime != nil {
+			createdTime = log.CreatedTime.String()
+		}
+		if log.Fee != nil {
+			fee = log.Fee.String()
+		}
+		record := []string{createdTime, strconv.Itoa(int(log.Type)), log.RID, log.Diff.String(), fee, log.Amount.String(), log.Memo, log.OrderID}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // NewBalanceSupplyLog _
 func NewBalanceSupplyLog(bal *Balance, diff Amount) *BalanceLog {
 	if diff.Sign() < 0 { // burn
@@ -113,12 +275,27 @@ func NewBalanceTransferLog(sender, receiver *Balance, diff Amount, fee *Amount,
 		Type:      BalanceLogTypeReceive,
 		RID:       sender.DOCTYPEID,
 		Diff:      diff,
+		Fee:       fee, // if the receiver bears the fee
 		Amount:    receiver.Amount,
 		Memo:      memo,
 		OrderID:   orderID,
 	}
 }
 
+// NewBalanceFeeSponsorLog _ (rid is the sponsored account)
+func NewBalanceFeeSponsorLog(sponsor *Balance, rid string, fee Amount, memo, orderID string) *BalanceLog {
+	return &BalanceLog{
+		DOCTYPEID: sponsor.DOCTYPEID,
+		Type:      BalanceLogTypeFeeSponsor,
+		RID:       rid,
+		Diff:      *ZeroAmount(),
+		Fee:       &fee,
+		Amount:    sponsor.Amount,
+		Memo:      memo,
+		OrderID:   orderID,
+	}
+}
+
 // NewBalanceDepositLog _
 func NewBalanceDepositLog(bal *Balance, pb *PendingBalance) *BalanceLog {
 	diff := pb.Amount.Copy().Neg()
@@ -256,6 +433,48 @@ func NewBalanceUnwrapCompleteLog(wrapper, receiver *Balance, diff Amount, extCod
 	}
 }
 
+// NewBalanceHTLCLockLog creates the sender's log of the htlc lock. (-(amount + fee))
+func NewBalanceHTLCLockLog(bal *Balance, htlc *HTLC) *BalanceLog {
+	diff := htlc.Amount.Copy().Add(&htlc.Fee).Neg()
+	return &BalanceLog{
+		DOCTYPEID: bal.DOCTYPEID,
+		Type:      BalanceLogTypeHTLCLock,
+		RID:       htlc.DOCTYPEID,
+		Diff:      *diff,
+		Fee:       htlc.Fee.Copy(),
+		Amount:    bal.Amount,
+		Memo:      htlc.Memo,
+		OrderID:   htlc.OrderID,
+	}
+}
+
+// NewBalanceHTLCClaimLog creates the receiver's log of the htlc claim.
+func NewBalanceHTLCClaimLog(bal *Balance, htlc *HTLC) *BalanceLog {
+	return &BalanceLog{
+		DOCTYPEID: bal.DOCTYPEID,
+		Type:      BalanceLogTypeHTLCClaim,
+		RID:       htlc.DOCTYPEID,
+		Diff:      htlc.Amount,
+		Amount:    bal.Amount,
+		Memo:      htlc.Memo,
+		OrderID:   htlc.OrderID,
+	}
+}
+
+// NewBalanceHTLCRefundLog creates the sender's log of the htlc refund. (amount + fee)
+func NewBalanceHTLCRefundLog(bal *Balance, htlc *HTLC) *BalanceLog {
+	diff := htlc.Amount.Copy().Add(&htlc.Fee)
+	return &BalanceLog{
+		DOCTYPEID: bal.DOCTYPEID,
+		Type:      BalanceLogTypeHTLCRefund,
+		RID:       htlc.DOCTYPEID,
+		Diff:      *diff,
+		Amount:    bal.Amount,
+		Memo:      htlc.Memo,
+		OrderID:   htlc.OrderID,
+	}
+}
+
 // PendingBalanceType _
 type PendingBalanceType int8
 
@@ -264,6 +483,12 @@ const (
 	PendingBalanceTypeAccount PendingBalanceType = iota
 	// PendingBalanceTypeContract _
 	PendingBalanceTypeContract
+	// PendingBalanceTypeHTLC is locked by htlc. (see htlc/claim and htlc/refund)
+	PendingBalanceTypeHTLC
+	// PendingBalanceTypeDex is locked by dex order. (see dex/order/cancel)
+	PendingBalanceTypeDex
+	// PendingBalanceTypeDistribution is locked by distribution. (see distribution/close)
+	PendingBalanceTypeDistribution
 )
 
 // PendingBalance _
@@ -5,6 +5,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/key-inside/kiesnet-ccpkg/contract"
@@ -104,7 +108,7 @@ func (bb *BalanceStub) GetQueryBalaceLogByDocumentID(id string) (*BalanceLog, er
 		return bl, nil
 	}
 	defer iter.Close()
-	return nil, errors.New("balance is not exists")
+	return nil, NotExistedBalanceLogError{}
 }
 
 // GetQueryBalanceLogs _
@@ -130,6 +134,147 @@ func (bb *BalanceStub) GetQueryBalanceLogs(id, typeStr, bookmark string, fetchSi
 	return NewQueryResult(meta, iter)
 }
 
+// GetBalanceLogAt returns the last balance log of the account at the time.
+// If there is no balance log before the time, it returns nil.
+func (bb *BalanceStub) GetBalanceLogAt(id string, t *txtime.Time) (*BalanceLog, error) {
+	query := CreateQueryBalanceLogAtTime(id, t)
+	iter, err := bb.stub.GetQueryResult(query)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	if !iter.HasNext() {
+		return nil, nil
+	}
+	kv, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	bl := &BalanceLog{}
+	if err = json.Unmarshal(kv.Value, bl); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the balance log")
+	}
+	return bl, nil
+}
+
+// GetBalanceAt returns the balance amount of the account at the time. (the amount of the last balance log)
+// If there is no balance log before the time, it returns zero.
+func (bb *BalanceStub) GetBalanceAt(id string, t *txtime.Time) (*Amount, error) {
+	bl, err := bb.GetBalanceLogAt(id, t)
+	if err != nil {
+		return nil, err
+	}
+	if bl == nil {
+		return ZeroAmount(), nil
+	}
+	return &bl.Amount, nil
+}
+
+// BalanceStatementTotalsPageSize is the page size of the balance logs which are summed for the statement totals.
+const BalanceStatementTotalsPageSize = 1000
+
+// GetBalanceStatementTotals returns the totals of the balance logs grouped by the type in [stime, etime), and the total fee.
+// It pages through all balance logs of the period with the bookmark, so that no page exceeds the query limit of the peer.
+func (bb *BalanceStub) GetBalanceStatementTotals(id string, stime, etime *txtime.Time) ([]*BalanceStatementTotal, *Amount, error) {
+	query := CreateQueryBalanceLogsByIDAndTimes(id, "", stime, etime)
+	totals := map[BalanceLogType]*BalanceStatementTotal{}
+	fee := ZeroAmount()
+	bookmark := ""
+	for {
+		next, n, err := bb.sumBalanceStatementPage(query, bookmark, totals, fee)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n < BalanceStatementTotalsPageSize || len(next) == 0 || next == bookmark {
+			break
+		}
+		bookmark = next
+	}
+
+	res := make([]*BalanceStatementTotal, 0, len(totals))
+	for _, total := range totals {
+		res = append(res, total)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Type < res[j].Type })
+	return res, fee, nil
+}
+
+// sumBalanceStatementPage adds the balance logs of the page to the totals, and returns the bookmark of the next page and the number of the logs.
+func (bb *BalanceStub) sumBalanceStatementPage(query, bookmark string, totals map[BalanceLogType]*BalanceStatementTotal, fee *Amount) (string, int, error) {
+	iter, meta, err := bb.stub.GetQueryResultWithPagination(query, BalanceStatementTotalsPageSize, bookmark)
+	if err != nil {
+		return "", 0, err
+	}
+	defer iter.Close()
+
+	n := 0
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return "", 0, err
+		}
+		bl := &BalanceLog{}
+		if err = json.Unmarshal(kv.Value, bl); err != nil {
+			return "", 0, errors.Wrap(err, "failed to unmarshal the balance log")
+		}
+		total, ok := totals[bl.Type]
+		if !ok {
+			total = &BalanceStatementTotal{Type: bl.Type, Diff: *ZeroAmount(), Fee: *ZeroAmount()}
+			totals[bl.Type] = total
+		}
+		total.Count++
+		total.Diff.Add(&bl.Diff)
+		if bl.Fee != nil {
+			total.Fee.Add(bl.Fee)
+			fee.Add(bl.Fee)
+		}
+		n++
+	}
+	return meta.GetBookmark(), n, nil
+}
+
+// GetBalanceHistory returns the modifications of the balance state in the time range. (oldest first)
+// If there are more than fetchSize modifications, the rest is truncated and hasMore is true.
+// The history database of the peer must be enabled.
+func (bb *BalanceStub) GetBalanceHistory(id string, fetchSize int, stime, etime *txtime.Time) ([]*BalanceHistory, bool, error) {
+	if fetchSize < 1 || fetchSize > BalanceHistoryMaxSize {
+		fetchSize = BalanceHistoryMaxSize
+	}
+	iter, err := bb.stub.GetHistoryForKey(bb.CreateKey(id))
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get the balance history")
+	}
+	defer iter.Close()
+
+	history := []*BalanceHistory{}
+	for iter.HasNext() {
+		km, err := iter.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		ts := txtime.Unix(km.GetTimestamp().GetSeconds(), int64(km.GetTimestamp().GetNanos()))
+		if stime != nil && ts.Cmp(stime) < 0 {
+			continue
+		}
+		if etime != nil && ts.Cmp(etime) > 0 {
+			break // oldest first
+		}
+		if len(history) == fetchSize {
+			return history, true, nil
+		}
+		h := &BalanceHistory{TxID: km.GetTxId(), Timestamp: ts, IsDelete: km.GetIsDelete()}
+		if !h.IsDelete {
+			h.Balance = &Balance{}
+			if err = json.Unmarshal(km.GetValue(), h.Balance); err != nil {
+				return nil, false, errors.Wrap(err, "failed to unmarshal the balance")
+			}
+		}
+		history = append(history, h)
+	}
+	return history, false, nil
+}
+
 // GetQueryBalaceLogByOrderID
 func (bb *BalanceStub) GetQueryBalaceLogByOrderID(orderID, typeStr string) (*BalanceLog, error) {
 	query := CreateQueryBalanceLogByOrderID(orderID, typeStr)
@@ -155,7 +300,7 @@ func (bb *BalanceStub) GetQueryBalaceLogByOrderID(orderID, typeStr string) (*Bal
 		return bl, nil
 	}
 	defer iter.Close()
-	return nil, errors.New("balance is not exists")
+	return nil, NotExistedBalanceLogError{}
 }
 
 // PutBalance _
@@ -175,7 +320,7 @@ func (bb *BalanceStub) CreateLogKey(id string, seq int64) string {
 	return fmt.Sprintf("BLOG_%s_%d", id, seq)
 }
 
-// PutBalanceLog _
+// PutBalanceLog chains the log to the head of the account and puts the log and the new head.
 func (bb *BalanceStub) PutBalanceLog(log *BalanceLog) error {
 	key := bb.CreateLogKey(log.DOCTYPEID, log.CreatedTime.UnixNano())
 	// check balance log key conflict
@@ -186,6 +331,19 @@ func (bb *BalanceStub) PutBalanceLog(log *BalanceLog) error {
 	if data != nil {
 		return errors.New("balance log key conflict")
 	}
+	// hash chain
+	head, err := bb.GetBalanceLogHead(log.DOCTYPEID)
+	if err != nil {
+		return err
+	}
+	if head != nil {
+		log.PrevHash = head.Hash
+	} else {
+		log.PrevHash = ""
+	}
+	if log.Hash, err = log.ComputeHash(); err != nil {
+		return errors.Wrap(err, "failed to compute the balance log hash")
+	}
 	// put balanc log
 	data, err = json.Marshal(log)
 	if err != nil {
@@ -194,9 +352,160 @@ func (bb *BalanceStub) PutBalanceLog(log *BalanceLog) error {
 	if err = bb.stub.PutState(key, data); err != nil {
 		return errors.Wrap(err, "failed to put the balance log state")
 	}
+	// put head
+	head = &BalanceLogHead{
+		DOCTYPEID:   log.DOCTYPEID,
+		Hash:        log.Hash,
+		CreatedTime: log.CreatedTime,
+	}
+	data, err = json.Marshal(head)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the balance log head")
+	}
+	if err = bb.stub.PutState(bb.CreateLogHeadKey(head.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the balance log head state")
+	}
 	return nil
 }
 
+// CreateLogHeadKey _
+func (bb *BalanceStub) CreateLogHeadKey(id string) string {
+	return "BLOGH_" + id
+}
+
+// GetBalanceLogHead returns the head of the hash chain of the account. If there is no hashed log, it returns nil.
+func (bb *BalanceStub) GetBalanceLogHead(id string) (*BalanceLogHead, error) {
+	data, err := bb.stub.GetState(bb.CreateLogHeadKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the balance log head state")
+	}
+	if data == nil {
+		return nil, nil
+	}
+	head := &BalanceLogHead{}
+	if err = json.Unmarshal(data, head); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the balance log head")
+	}
+	return head, nil
+}
+
+// VerifyBalanceLogs validates the hash chain of the balance logs in [stime, etime) and reports the first broken link.
+// The logs before the first hashed log are legacy logs and they are not verified.
+// If the walk reaches the last log of the account (etime is nil), the last log is compared with the head.
+func (bb *BalanceStub) VerifyBalanceLogs(id string, fetchSize int, stime, etime *txtime.Time) (*BalanceLogsVerification, error) {
+	if fetchSize < 1 {
+		fetchSize = BalanceLogsVerifyFetchSize
+	}
+	if fetchSize > BalanceLogsVerifyMaxSize {
+		fetchSize = BalanceLogsVerifyMaxSize
+	}
+	res := &BalanceLogsVerification{Address: id, Valid: true}
+
+	// anchor
+	var prev *BalanceLog
+	skey := "BLOG_" + id + "_" // see CreateLogKey
+	ekey := skey + string(utf8.MaxRune)
+	if stime != nil {
+		var err error
+		if prev, err = bb.GetBalanceLogAt(id, txtime.New(stime.Add(-time.Nanosecond))); err != nil {
+			return nil, err
+		}
+		skey = bb.CreateLogKey(id, stime.UnixNano())
+	}
+	if etime != nil {
+		ekey = bb.CreateLogKey(id, etime.UnixNano())
+	}
+
+	iter, err := bb.stub.GetStateByRange(skey, ekey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the balance log states")
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		log := &BalanceLog{}
+		if err = json.Unmarshal(kv.Value, log); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal the balance log")
+		}
+		if res.Verified+res.Legacy == fetchSize { // more
+			res.Cursor = strconv.FormatInt(log.CreatedTime.UnixNano(), 10)
+			return res, nil
+		}
+
+		if broken, err := verifyBalanceLogLink(prev, log); err != nil {
+			return nil, err
+		} else if broken != nil {
+			broken.Key = kv.Key
+			res.Valid = false
+			res.Broken = broken
+			return res, nil
+		}
+		if len(log.Hash) == 0 {
+			res.Legacy++
+		} else {
+			res.Verified++
+		}
+		prev = log
+	}
+
+	if etime != nil {
+		return res, nil
+	}
+	// head
+	head, err := bb.GetBalanceLogHead(id)
+	if err != nil {
+		return nil, err
+	}
+	res.HeadChecked = true
+	expected := ""
+	if prev != nil {
+		expected = prev.Hash
+	}
+	actual := ""
+	if head != nil {
+		actual = head.Hash
+	}
+	if expected != actual {
+		res.Valid = false
+		res.Broken = &BalanceLogBrokenLink{
+			Key:      bb.CreateLogHeadKey(id),
+			Reason:   "head mismatch",
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+	return res, nil
+}
+
+// verifyBalanceLogLink returns the broken link between the previous log and the log. If the link is valid, it returns nil.
+func verifyBalanceLogLink(prev, log *BalanceLog) (*BalanceLogBrokenLink, error) {
+	prevHash := ""
+	if prev != nil {
+		prevHash = prev.Hash
+	}
+	if len(log.Hash) == 0 {
+		if len(prevHash) > 0 {
+			return &BalanceLogBrokenLink{Reason: "missing hash", Expected: "hash"}, nil
+		}
+		return nil, nil // legacy
+	}
+	hash, err := log.ComputeHash()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute the balance log hash")
+	}
+	if hash != log.Hash {
+		return &BalanceLogBrokenLink{Reason: "hash mismatch", Expected: hash, Actual: log.Hash}, nil
+	}
+	if log.PrevHash != prevHash {
+		return &BalanceLogBrokenLink{Reason: "previous hash mismatch", Expected: prevHash, Actual: log.PrevHash}, nil
+	}
+	return nil, nil
+}
+
 // CreatePendingKey _
 func (bb *BalanceStub) CreatePendingKey(id string) string {
 	return "PBLC_" + id
@@ -215,7 +524,7 @@ func (bb *BalanceStub) GetPendingBalance(id string) (*PendingBalance, error) {
 		}
 		return balance, nil
 	}
-	return nil, errors.New("the pending balance is not exists")
+	return nil, NotExistedPendingBalanceError{}
 }
 
 // GetQueryPendingBalances _
@@ -278,12 +587,29 @@ func (bb *BalanceStub) Supply(bal *Balance, amount Amount) (*BalanceLog, error)
 }
 
 // Transfer _
-func (bb *BalanceStub) Transfer(sender, receiver *Balance, amount, fee Amount, memo, orderID string, pendingTime *txtime.Time) (*BalanceLog, error) {
+// The fee is charged to the bearer, and the sponsor is used only if the bearer is FeeBearerSponsor.
+// The pending time transfer supports only the sender bearer. (see getValidatedTransferParameters)
+func (bb *BalanceStub) Transfer(sender, receiver, sponsor *Balance, amount, fee Amount, bearer FeeBearer, memo, orderID string, pendingTime *txtime.Time) (*BalanceLog, error) {
 	ts, err := txtime.GetTime(bb.stub)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get the timestamp")
 	}
 
+	// fee of the logs
+	var sFee, rFee *Amount
+	payer := sender
+	switch bearer {
+	case FeeBearerReceiver:
+		payer = receiver
+		if fee.Sign() > 0 {
+			rFee = &fee
+		}
+	case FeeBearerSponsor:
+		payer = sponsor
+	default:
+		sFee = &fee
+	}
+
 	if pendingTime != nil && pendingTime.Cmp(ts) > 0 { // time lock
 		pb := NewPendingBalance(bb.stub.GetTxID(), receiver, sender, amount, nil, memo, orderID, pendingTime)
 		pb.CreatedTime = ts
@@ -292,32 +618,51 @@ func (bb *BalanceStub) Transfer(sender, receiver *Balance, amount, fee Amount, m
 		}
 	} else {
 		receiver.Amount.Add(&amount) // deposit
+		if rFee != nil {
+			receiver.Amount.Add(rFee.Copy().Neg()) // fee
+		}
 		receiver.UpdatedTime = ts
 		if err = bb.PutBalance(receiver); err != nil {
 			return nil, err
 		}
-		rbl := NewBalanceTransferLog(sender, receiver, amount, nil, memo, orderID)
+		rbl := NewBalanceTransferLog(sender, receiver, amount, rFee, memo, orderID)
 		rbl.CreatedTime = ts
 		if err = bb.PutBalanceLog(rbl); err != nil {
 			return nil, err
 		}
 	}
 
-	amount.Neg()                        // -
-	sender.Amount.Add(&amount)          // withdraw
-	sender.Amount.Add(fee.Copy().Neg()) // fee
+	amount.Neg()               // -
+	sender.Amount.Add(&amount) // withdraw
+	if sFee != nil {
+		sender.Amount.Add(sFee.Copy().Neg()) // fee
+	}
 	sender.UpdatedTime = ts
 	if err = bb.PutBalance(sender); err != nil {
 		return nil, err
 	}
-	sbl := NewBalanceTransferLog(sender, receiver, amount, &fee, memo, orderID)
+	sbl := NewBalanceTransferLog(sender, receiver, amount, sFee, memo, orderID)
 	sbl.CreatedTime = ts
 	if err = bb.PutBalanceLog(sbl); err != nil {
 		return nil, err
 	}
 
+	// sponsor
+	if bearer == FeeBearerSponsor && fee.Sign() > 0 {
+		sponsor.Amount.Add(fee.Copy().Neg())
+		sponsor.UpdatedTime = ts
+		if err = bb.PutBalance(sponsor); err != nil {
+			return nil, err
+		}
+		fbl := NewBalanceFeeSponsorLog(sponsor, sender.GetID(), fee, memo, orderID)
+		fbl.CreatedTime = ts
+		if err = bb.PutBalanceLog(fbl); err != nil {
+			return nil, err
+		}
+	}
+
 	// fee
-	if _, err := NewFeeStub(bb.stub).CreateFee(sender.GetID(), fee); err != nil {
+	if _, err := NewFeeStub(bb.stub).CreateFee(payer.GetID(), fee); err != nil {
 		return nil, err
 	}
 
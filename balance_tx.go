@@ -5,6 +5,8 @@ package main
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/hyperledger/fabric/protos/peer"
@@ -20,13 +22,13 @@ import (
 // params[5] : end time (time represented by int64 seconds)
 func balanceLogs(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, false)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	typeStr := ""
@@ -43,14 +45,14 @@ func balanceLogs(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 			if len(params) > 3 {
 				fetchSize, err = strconv.Atoi(params[3])
 				if err != nil {
-					return shim.Error("invalid fetch size")
+					return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
 				}
 				// start time
 				if len(params) > 4 {
 					if len(params[4]) > 0 {
 						seconds, err := strconv.ParseInt(params[4], 10, 64)
 						if err != nil {
-							return shim.Error("invalid start time: need seconds since 1970")
+							return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
 						}
 						stime = txtime.Unix(seconds, 0)
 					}
@@ -59,11 +61,11 @@ func balanceLogs(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 						if len(params[5]) > 0 {
 							seconds, err := strconv.ParseInt(params[5], 10, 64)
 							if err != nil {
-								return shim.Error("invalid end time: need seconds since 1970")
+								return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
 							}
 							etime = txtime.Unix(seconds, 0)
 							if stime != nil && stime.Cmp(etime) >= 0 {
-								return shim.Error("invalid time parameters")
+								return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
 							}
 						}
 					}
@@ -102,16 +104,307 @@ func balanceLogs(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 	return shim.Success(data)
 }
 
+// The balance at the time is the amount of the last balance log at the time.
+// params[0] : token code | account address
+// params[1] : time (time represented by int64 seconds)
+func balanceAt(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	seconds, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid time: need seconds since 1970")
+	}
+	t := txtime.Unix(seconds, 0)
+
+	var addr *Address
+	code, err := ValidateTokenCode(params[0])
+	if nil == err { // by token code
+		addr = NewAddress(code, AccountTypePersonal, kid)
+	} else { // by address
+		addr, err = ParseAddress(params[0])
+		if err != nil {
+			return responseError(err, "failed to parse the account address")
+		}
+	}
+
+	log, err := NewBalanceStub(stub).GetBalanceLogAt(addr.String(), t)
+	if err != nil {
+		return responseError(err, "failed to get the balance log")
+	}
+	res := &BalanceAt{Address: addr.String(), Time: t, Amount: *ZeroAmount(), BalanceLog: log}
+	if log != nil {
+		res.Amount = log.Amount
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the balance")
+	}
+	return shim.Success(data)
+}
+
+// The raw modifications of the balance state, to cross-check with the balance logs.
+// params[0] : token code | account address
+// params[1] : optional. fetch size (if < 1 => max size, max 200)
+// params[2] : optional. start time (time represented by int64 seconds)
+// params[3] : optional. end time (time represented by int64 seconds)
+func balanceHistory(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	fetchSize := 0
+	var stime, etime *txtime.Time
+	if len(params) > 1 && len(params[1]) > 0 {
+		fetchSize, err = strconv.Atoi(params[1])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+		}
+	}
+	if len(params) > 2 && len(params[2]) > 0 {
+		seconds, err := strconv.ParseInt(params[2], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
+		}
+		stime = txtime.Unix(seconds, 0)
+	}
+	if len(params) > 3 && len(params[3]) > 0 {
+		seconds, err := strconv.ParseInt(params[3], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
+		}
+		etime = txtime.Unix(seconds, 0)
+		if stime != nil && stime.Cmp(etime) >= 0 {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
+		}
+	}
+
+	var addr *Address
+	code, err := ValidateTokenCode(params[0])
+	if nil == err { // by token code
+		addr = NewAddress(code, AccountTypePersonal, kid)
+	} else { // by address
+		addr, err = ParseAddress(params[0])
+		if err != nil {
+			return responseError(err, "failed to parse the account address")
+		}
+	}
+
+	history, hasMore, err := NewBalanceStub(stub).GetBalanceHistory(addr.String(), fetchSize, stime, etime)
+	if err != nil {
+		return responseError(err, "failed to get the balance history")
+	}
+
+	data, err := json.Marshal(&BalanceHistoryResult{Address: addr.String(), History: history, HasMore: hasMore})
+	if err != nil {
+		return responseError(err, "failed to marshal the balance history")
+	}
+	return shim.Success(data)
+}
+
+// Validates the hash chain of the balance logs and reports the first broken link.
+// params[0] : token code | account address
+// params[1] : optional. start time (time represented by int64 seconds)
+// params[2] : optional. end time (time represented by int64 seconds, exclusive)
+// params[3] : optional. fetch size (if < 1 => default size, max 1000)
+// params[4] : optional. cursor (unix nano time of the next log in the previous result, overrides the start time)
+func balanceLogsVerify(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	fetchSize := 0
+	var stime, etime *txtime.Time
+	if len(params) > 1 && len(params[1]) > 0 {
+		seconds, err := strconv.ParseInt(params[1], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
+		}
+		stime = txtime.Unix(seconds, 0)
+	}
+	if len(params) > 2 && len(params[2]) > 0 {
+		seconds, err := strconv.ParseInt(params[2], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
+		}
+		etime = txtime.Unix(seconds, 0)
+	}
+	if len(params) > 3 && len(params[3]) > 0 {
+		fetchSize, err = strconv.Atoi(params[3])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+		}
+	}
+	if len(params) > 4 && len(params[4]) > 0 {
+		nanos, err := strconv.ParseInt(params[4], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid cursor")
+		}
+		stime = txtime.Unix(0, nanos)
+	}
+	if stime != nil && etime != nil && stime.Cmp(etime) >= 0 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
+	}
+
+	var addr *Address
+	code, err := ValidateTokenCode(params[0])
+	if nil == err { // by token code
+		addr = NewAddress(code, AccountTypePersonal, kid)
+	} else { // by address
+		addr, err = ParseAddress(params[0])
+		if err != nil {
+			return responseError(err, "failed to parse the account address")
+		}
+	}
+
+	res, err := NewBalanceStub(stub).VerifyBalanceLogs(addr.String(), fetchSize, stime, etime)
+	if err != nil {
+		return responseError(err, "failed to verify the balance logs")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the verification")
+	}
+	return shim.Success(data)
+}
+
+// The statement of the period [start time, end time).
+// params[0] : token code | account address
+// params[1] : start time (time represented by int64 seconds)
+// params[2] : end time (time represented by int64 seconds)
+// params[3] : optional. bookmark of the entries
+// params[4] : optional. fetch size of the entries (if < 1 => default size, max 200)
+// params[5] : optional. format of the entries ("json" | "csv", default "json")
+func balanceStatement(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 3 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 3+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	seconds, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
+	}
+	stime := txtime.Unix(seconds, 0)
+	seconds, err = strconv.ParseInt(params[2], 10, 64)
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
+	}
+	etime := txtime.Unix(seconds, 0)
+	if stime.Cmp(etime) >= 0 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
+	}
+
+	bookmark := ""
+	fetchSize := 0
+	format := "json"
+	if len(params) > 3 {
+		bookmark = params[3]
+		if len(params) > 4 && len(params[4]) > 0 {
+			fetchSize, err = strconv.Atoi(params[4])
+			if err != nil {
+				return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+			}
+		}
+		if len(params) > 5 && len(params[5]) > 0 {
+			format = strings.ToLower(params[5])
+			if format != "json" && format != "csv" {
+				return responseErrorCode(ErrorCodeInvalidParameter, "invalid format. expecting 'json' or 'csv'")
+			}
+		}
+	}
+
+	var addr *Address
+	code, err := ValidateTokenCode(params[0])
+	if nil == err { // by token code
+		addr = NewAddress(code, AccountTypePersonal, kid)
+	} else { // by address
+		addr, err = ParseAddress(params[0])
+		if err != nil {
+			return responseError(err, "failed to parse the account address")
+		}
+	}
+	id := addr.String()
+
+	bb := NewBalanceStub(stub)
+	st := &BalanceStatement{Address: id, StartTime: stime, EndTime: etime}
+	// the period excludes the end time
+	opening, err := bb.GetBalanceAt(id, txtime.New(stime.Add(-time.Nanosecond)))
+	if err != nil {
+		return responseError(err, "failed to get the opening balance")
+	}
+	closing, err := bb.GetBalanceAt(id, txtime.New(etime.Add(-time.Nanosecond)))
+	if err != nil {
+		return responseError(err, "failed to get the closing balance")
+	}
+	st.OpeningBalance, st.ClosingBalance = *opening, *closing
+
+	totals, fee, err := bb.GetBalanceStatementTotals(id, stime, etime)
+	if err != nil {
+		return responseError(err, "failed to get the totals")
+	}
+	st.Totals, st.TotalFee = totals, *fee
+
+	res, err := bb.GetQueryBalanceLogs(id, "", bookmark, fetchSize, stime, etime)
+	if err != nil {
+		return responseError(err, "failed to get balance logs")
+	}
+	st.Meta = res.Meta
+	if format == "csv" {
+		logs := []*BalanceLog{}
+		if err = json.Unmarshal(res.Records, &logs); err != nil {
+			return responseError(err, "failed to unmarshal balance logs")
+		}
+		if st.CSV, err = RenderBalanceLogsCSV(logs); err != nil {
+			return responseError(err, "failed to render balance logs")
+		}
+	} else {
+		st.Entries = res.Records
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return responseError(err, "failed to marshal the statement")
+	}
+	return shim.Success(data)
+}
+
 // params[0] : pending balance id
 func balancePendingGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	// authentication
 	_, err := kid.GetID(stub, false)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// pending balance
@@ -135,13 +428,13 @@ func balancePendingGet(stub shim.ChaincodeStubInterface, params []string) peer.R
 // params[3] : fetch size (if < 1 => default size, max 200)
 func balancePendingList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, false)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	sort := "pending_time"
@@ -157,7 +450,7 @@ func balancePendingList(stub shim.ChaincodeStubInterface, params []string) peer.
 			if len(params) > 3 {
 				fetchSize, err = strconv.Atoi(params[3])
 				if err != nil {
-					return shim.Error("invalid fetch size")
+					return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
 				}
 			}
 		}
@@ -190,7 +483,7 @@ func balancePendingList(stub shim.ChaincodeStubInterface, params []string) peer.
 // params[0] : pending balance id
 func balancePendingWithdraw(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	ts, err := txtime.GetTime(stub)
@@ -201,7 +494,7 @@ func balancePendingWithdraw(stub shim.ChaincodeStubInterface, params []string) p
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// pending balance
@@ -210,8 +503,16 @@ func balancePendingWithdraw(stub shim.ChaincodeStubInterface, params []string) p
 	if err != nil {
 		return responseError(err, "failed to get the pending balance")
 	}
+	switch pb.Type {
+	case PendingBalanceTypeHTLC:
+		return responseErrorCode(ErrorCodeInvalidState, "htlc pending balance. use htlc/refund")
+	case PendingBalanceTypeDex:
+		return responseErrorCode(ErrorCodeInvalidState, "dex pending balance. use dex/order/cancel")
+	case PendingBalanceTypeDistribution:
+		return responseErrorCode(ErrorCodeInvalidState, "distribution pending balance. use distribution/close")
+	}
 	if pb.PendingTime.Cmp(ts) > 0 {
-		return shim.Error("too early to withdraw")
+		return responseErrorCode(ErrorCodeInvalidState, "too early to withdraw")
 	}
 
 	// account
@@ -222,10 +523,10 @@ func balancePendingWithdraw(stub shim.ChaincodeStubInterface, params []string) p
 		return responseError(err, "failed to get the account")
 	}
 	if !account.HasHolder(kid) {
-		return shim.Error("invoker is not holder")
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
 	}
 	if account.IsSuspended() {
-		return shim.Error("the account is suspended")
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the account is suspended")
 	}
 
 	// withdraw
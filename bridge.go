@@ -0,0 +1,172 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// BridgeLedger is the running totals of the wrap bridge of the external token code.
+// The wrapped out amount is minted on the external chain, so
+// the external supply = wrapped out - unwrapped in. (the ledger counts from its first update)
+// The day totals are updated only if the wrap policy has the daily limits, so the ledger state isn't a hot key of every wrap.
+// The other totals are put as the bridge deltas of the txs, and they are folded into the ledger by bridge/compact.
+type BridgeLedger struct {
+	DOCTYPEID      string       `json:"@bridge"` // token code + "_" + external token code
+	Token          string       `json:"token"`
+	ExtCode        string       `json:"ext_code"`
+	Pending        Amount       `json:"pending"`            // requested wraps which are not completed, impossible or refunded
+	WrappedOut     Amount       `json:"wrapped_out"`        // completed wraps (amount - fee)
+	UnwrappedIn    Amount       `json:"unwrapped_in"`       // executed unwraps (except impossible unwraps)
+	WrapDay        string       `json:"wrap_day,omitempty"` // UTC date (YYYY-MM-DD) of the wrap day total
+	WrapDayTotal   Amount       `json:"wrap_day_total"`
+	UnwrapDay      string       `json:"unwrap_day,omitempty"` // UTC date (YYYY-MM-DD) of the unwrap day total
+	UnwrapDayTotal Amount       `json:"unwrap_day_total"`
+	UpdatedTime    *txtime.Time `json:"updated_time,omitempty"`
+}
+
+// NewBridgeLedger _
+func NewBridgeLedger(code, extCode string) *BridgeLedger {
+	return &BridgeLedger{
+		DOCTYPEID:      code + "_" + extCode,
+		Token:          code,
+		ExtCode:        extCode,
+		Pending:        *ZeroAmount(),
+		WrappedOut:     *ZeroAmount(),
+		UnwrappedIn:    *ZeroAmount(),
+		WrapDayTotal:   *ZeroAmount(),
+		UnwrapDayTotal: *ZeroAmount(),
+	}
+}
+
+// bridgeDay returns the UTC date of the time. The daily limits are reset at 00:00 UTC.
+func bridgeDay(t *txtime.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// Outstanding returns wrapped out - unwrapped in.
+func (l *BridgeLedger) Outstanding() *Amount {
+	return l.WrappedOut.Copy().Add(l.UnwrappedIn.Copy().Neg())
+}
+
+// WrapDayUsed returns the wrapped amount of the day of the time.
+func (l *BridgeLedger) WrapDayUsed(t *txtime.Time) *Amount {
+	if l.WrapDay != bridgeDay(t) {
+		return ZeroAmount()
+	}
+	return l.WrapDayTotal.Copy()
+}
+
+// UnwrapDayUsed returns the unwrapped amount of the day of the time.
+func (l *BridgeLedger) UnwrapDayUsed(t *txtime.Time) *Amount {
+	if l.UnwrapDay != bridgeDay(t) {
+		return ZeroAmount()
+	}
+	return l.UnwrapDayTotal.Copy()
+}
+
+// AddWrap checks the wrap limits of the policy and adds the amount to the day total.
+func (l *BridgeLedger) AddWrap(policy *WrapPolicy, amount *Amount, ts *txtime.Time) error {
+	used := l.WrapDayUsed(ts)
+	if err := policy.CheckWrapLimit(amount, used); err != nil {
+		return err
+	}
+	l.WrapDay = bridgeDay(ts)
+	l.WrapDayTotal = *used.Add(amount)
+	l.UpdatedTime = ts
+	return nil
+}
+
+// AddUnwrap checks the unwrap limits of the policy and adds the amount to the day total.
+func (l *BridgeLedger) AddUnwrap(policy *WrapPolicy, amount *Amount, ts *txtime.Time) error {
+	used := l.UnwrapDayUsed(ts)
+	if err := policy.CheckUnwrapLimit(amount, used); err != nil {
+		return err
+	}
+	l.UnwrapDay = bridgeDay(ts)
+	l.UnwrapDayTotal = *used.Add(amount)
+	l.UpdatedTime = ts
+	return nil
+}
+
+// Fold adds the delta to the totals.
+func (l *BridgeLedger) Fold(delta *BridgeDelta) {
+	l.Pending.Add(&delta.Pending)
+	l.WrappedOut.Add(&delta.WrappedOut)
+	l.UnwrappedIn.Add(&delta.UnwrappedIn)
+}
+
+// BridgeDelta is the changes of the bridge ledger totals in a tx.
+// Each tx puts its own delta state, so the wraps and the unwraps don't conflict with each other.
+type BridgeDelta struct {
+	DOCTYPEID   string       `json:"@bridge_delta"` // token code + "_" + external token code
+	TxID        string       `json:"tx_id"`
+	Pending     Amount       `json:"pending"`
+	WrappedOut  Amount       `json:"wrapped_out"`
+	UnwrappedIn Amount       `json:"unwrapped_in"`
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+}
+
+// NewBridgeDelta _
+func NewBridgeDelta(code, extCode, txid string, ts *txtime.Time) *BridgeDelta {
+	return &BridgeDelta{
+		DOCTYPEID:   code + "_" + extCode,
+		TxID:        txid,
+		Pending:     *ZeroAmount(),
+		WrappedOut:  *ZeroAmount(),
+		UnwrappedIn: *ZeroAmount(),
+		CreatedTime: ts,
+	}
+}
+
+// IsZero _
+func (d *BridgeDelta) IsZero() bool {
+	return d.Pending.Sign() == 0 && d.WrappedOut.Sign() == 0 && d.UnwrappedIn.Sign() == 0
+}
+
+// ReleaseWrap removes the wrap from the pending. (completed, impossible or refunded)
+// If the wrap is completed, it adds (amount - fee) to the wrapped out.
+func (d *BridgeDelta) ReleaseWrap(wrap *Wrap, fee *Amount) {
+	if wrap.Ledger { // the wraps requested before the ledger (and the migrated legacy wraps) are not in the pending
+		d.Pending.Add(wrap.Amount.Copy().Neg())
+	}
+	if wrap.Status == WrapStatusCompleted {
+		d.WrappedOut.Add(wrap.Amount.Copy().Add(fee.Copy().Neg()))
+	}
+}
+
+// BridgeCompactResult is the response payload of bridge/compact.
+type BridgeCompactResult struct {
+	Compacted int  `json:"compacted"` // number of the folded deltas
+	HasMore   bool `json:"has_more"`  // more deltas are left before the current hour
+}
+
+// BridgeLimit is the remaining amounts of the limits. nil is unlimited.
+type BridgeLimit struct {
+	PerTx          *Amount `json:"per_tx,omitempty"`
+	Daily          *Amount `json:"daily,omitempty"`
+	DailyRemaining *Amount `json:"daily_remaining,omitempty"`
+}
+
+// BridgeStatus is the response payload of bridge/status.
+type BridgeStatus struct {
+	*BridgeLedger
+	ExtChain    string       `json:"ext_chain"`
+	WrapAddress string       `json:"wrap_address"`
+	WrapBalance Amount       `json:"wrap_balance"` // balance of the wrap account
+	Outstanding *Amount      `json:"outstanding"`  // wrapped out - unwrapped in (the external supply)
+	WrapLimit   *BridgeLimit `json:"wrap_limit"`
+	UnwrapLimit *BridgeLimit `json:"unwrap_limit"`
+}
+
+// newBridgeLimit returns the limit with the remaining amount of the day.
+func newBridgeLimit(perTx, daily, used *Amount) *BridgeLimit {
+	limit := &BridgeLimit{PerTx: perTx, Daily: daily}
+	if daily != nil {
+		limit.DailyRemaining = daily.Copy().Add(used.Copy().Neg())
+		if limit.DailyRemaining.Sign() < 0 {
+			limit.DailyRemaining = ZeroAmount()
+		}
+	}
+	return limit
+}
@@ -0,0 +1,228 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// BridgeStub _
+type BridgeStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewBridgeStub _
+func NewBridgeStub(stub shim.ChaincodeStubInterface) *BridgeStub {
+	return &BridgeStub{stub}
+}
+
+// CreateKey _
+func (bb *BridgeStub) CreateKey(code, extCode string) string {
+	return "BRIDGE_" + code + "_" + extCode
+}
+
+// GetLedger returns the bridge ledger. If it is not exists, it returns a new ledger.
+func (bb *BridgeStub) GetLedger(code, extCode string) (*BridgeLedger, error) {
+	data, err := bb.stub.GetState(bb.CreateKey(code, extCode))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the bridge ledger state")
+	}
+	if data == nil {
+		return NewBridgeLedger(code, extCode), nil
+	}
+	ledger := &BridgeLedger{}
+	if err = json.Unmarshal(data, ledger); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the bridge ledger")
+	}
+	return ledger, nil
+}
+
+// PutLedger _
+func (bb *BridgeStub) PutLedger(ledger *BridgeLedger) error {
+	data, err := json.Marshal(ledger)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the bridge ledger")
+	}
+	if err = bb.stub.PutState(bb.CreateKey(ledger.Token, ledger.ExtCode), data); err != nil {
+		return errors.Wrap(err, "failed to put the bridge ledger state")
+	}
+	return nil
+}
+
+// BridgeCompactSize is the max number of the bridge deltas that one compaction reads.
+const BridgeCompactSize = 900
+
+// createDeltaPrefix _
+func (bb *BridgeStub) createDeltaPrefix(code, extCode string) string {
+	return "BRIDGED_" + code + "_" + extCode + "_"
+}
+
+// CreateDeltaKey _ (if txid is empty, it returns the range key of the deltas)
+func (bb *BridgeStub) CreateDeltaKey(code, extCode string, start int64, txid string) string {
+	key := bb.createDeltaPrefix(code, extCode) + sumBucketKeyTime(start)
+	if txid != "" {
+		key += "_" + txid
+	}
+	return key
+}
+
+// PutDelta puts the delta of the tx. The key has the hour of the tx time, so that the old deltas can be compacted.
+func (bb *BridgeStub) PutDelta(delta *BridgeDelta) error {
+	if delta.IsZero() {
+		return nil
+	}
+	data, err := json.Marshal(delta)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the bridge delta")
+	}
+	code, extCode := bb.splitID(delta.DOCTYPEID)
+	key := bb.CreateDeltaKey(code, extCode, sumBucketStart(delta.CreatedTime), delta.TxID)
+	if err = bb.stub.PutState(key, data); err != nil {
+		return errors.Wrap(err, "failed to put the bridge delta state")
+	}
+	return nil
+}
+
+// splitID returns the token code and the external token code of the ledger (or delta) ID.
+func (bb *BridgeStub) splitID(id string) (string, string) {
+	i := strings.Index(id, "_")
+	return id[:i], id[i+1:]
+}
+
+// GetLimitLedger returns the ledger to check the daily limit, and whether it must be put after the update.
+// If the daily limit isn't set, the ledger state is not read (a new ledger), and the day total is not tracked.
+func (bb *BridgeStub) GetLimitLedger(code, extCode string, daily *Amount) (*BridgeLedger, bool, error) {
+	if daily == nil {
+		return NewBridgeLedger(code, extCode), false, nil
+	}
+	ledger, err := bb.GetLedger(code, extCode)
+	if err != nil {
+		return nil, false, err
+	}
+	return ledger, true, nil
+}
+
+// RecordWrap checks the wrap limits and adds the wrap to the pending. (see BridgeLedger.AddWrap)
+func (bb *BridgeStub) RecordWrap(policy *WrapPolicy, code, extCode string, amount *Amount, ts *txtime.Time) error {
+	ledger, daily, err := bb.GetLimitLedger(code, extCode, policy.WrapLimitDaily)
+	if err != nil {
+		return err
+	}
+	if err = ledger.AddWrap(policy, amount, ts); err != nil {
+		return err
+	}
+	if daily {
+		if err = bb.PutLedger(ledger); err != nil {
+			return err
+		}
+	}
+	delta := NewBridgeDelta(code, extCode, bb.stub.GetTxID(), ts)
+	delta.Pending.Add(amount)
+	return bb.PutDelta(delta)
+}
+
+// ReleaseWrap releases the wrap from the pending. (see BridgeDelta.ReleaseWrap)
+func (bb *BridgeStub) ReleaseWrap(wrap *Wrap, fee *Amount, ts *txtime.Time) error {
+	delta := NewBridgeDelta(wrap.Token, wrap.ExtCode, bb.stub.GetTxID(), ts)
+	delta.ReleaseWrap(wrap, fee)
+	return bb.PutDelta(delta)
+}
+
+// RecordUnwrap checks the unwrap limits and adds the unwrap to the unwrapped in. (see BridgeLedger.AddUnwrap)
+func (bb *BridgeStub) RecordUnwrap(policy *WrapPolicy, code, extCode string, amount *Amount, ts *txtime.Time) error {
+	ledger, daily, err := bb.GetLimitLedger(code, extCode, policy.UnwrapLimitDaily)
+	if err != nil {
+		return err
+	}
+	if err = ledger.AddUnwrap(policy, amount, ts); err != nil {
+		return err
+	}
+	if daily {
+		if err = bb.PutLedger(ledger); err != nil {
+			return err
+		}
+	}
+	delta := NewBridgeDelta(code, extCode, bb.stub.GetTxID(), ts)
+	delta.UnwrappedIn.Add(amount)
+	return bb.PutDelta(delta)
+}
+
+// GetTotals returns the ledger with the deltas which are not compacted yet.
+func (bb *BridgeStub) GetTotals(code, extCode string) (*BridgeLedger, error) {
+	ledger, err := bb.GetLedger(code, extCode)
+	if err != nil {
+		return nil, err
+	}
+	prefix := bb.createDeltaPrefix(code, extCode)
+	iter, err := bb.stub.GetStateByRange(prefix, prefix+string(utf8.MaxRune))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the bridge delta states")
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		delta := &BridgeDelta{}
+		if err = json.Unmarshal(kv.Value, delta); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal the bridge delta")
+		}
+		ledger.Fold(delta)
+	}
+	return ledger, nil
+}
+
+// Compact folds the deltas before the hour of the time into the ledger, and deletes them.
+// The deltas of the current hour are left, so that the compaction doesn't conflict with the new deltas.
+// It folds up to fetchSize deltas, and returns the number of the folded deltas and whether more deltas are left.
+func (bb *BridgeStub) Compact(code, extCode string, fetchSize int, ts *txtime.Time) (*BridgeCompactResult, error) {
+	if fetchSize < 1 || fetchSize > BridgeCompactSize {
+		fetchSize = BridgeCompactSize
+	}
+	ledger, err := bb.GetLedger(code, extCode)
+	if err != nil {
+		return nil, err
+	}
+	end := sumBucketStart(ts)
+	iter, err := bb.stub.GetStateByRange(bb.createDeltaPrefix(code, extCode), bb.CreateDeltaKey(code, extCode, end, ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the bridge delta states")
+	}
+	defer iter.Close()
+
+	res := &BridgeCompactResult{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if res.Compacted == fetchSize {
+			res.HasMore = true
+			break
+		}
+		delta := &BridgeDelta{}
+		if err = json.Unmarshal(kv.Value, delta); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal the bridge delta")
+		}
+		ledger.Fold(delta)
+		if err = bb.stub.DelState(kv.Key); err != nil {
+			return nil, errors.Wrap(err, "failed to delete the bridge delta state")
+		}
+		res.Compacted++
+	}
+	if res.Compacted > 0 {
+		ledger.UpdatedTime = ts
+		if err = bb.PutLedger(ledger); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
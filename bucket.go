@@ -0,0 +1,66 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// SumBucketSize is the time span (seconds) of a pay/fee sum bucket.
+const SumBucketSize = 3600
+
+// SumBucketsPruneSize is the max number of the bucket states that one prune request reads.
+const SumBucketsPruneSize = 900
+
+// SumBucketOrigin is the start of the first complete bucket of the merchant or the token.
+// The pays/fees before it were put before the buckets, so they are summed by the query.
+type SumBucketOrigin struct {
+	DOCTYPEID   string       `json:"@sum_bucket_origin"` // merchant address or token code
+	Start       int64        `json:"start"`              // unix seconds
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+}
+
+// NewSumBucketOrigin returns the origin of the buckets which are put from the time. (the next bucket of the time)
+func NewSumBucketOrigin(id string, ts *txtime.Time) *SumBucketOrigin {
+	return &SumBucketOrigin{
+		DOCTYPEID:   id,
+		Start:       sumBucketStart(ts) + SumBucketSize,
+		CreatedTime: ts,
+	}
+}
+
+// sumBucketStart returns the start (unix seconds) of the bucket of the time.
+func sumBucketStart(t *txtime.Time) int64 {
+	s := t.Unix()
+	return s - s%SumBucketSize
+}
+
+// sumBucketKeyTime formats the bucket start in the fixed width, so that the bucket keys are sorted by the time.
+func sumBucketKeyTime(start int64) string {
+	return fmt.Sprintf("%012d", start)
+}
+
+// sumBucketRange returns the complete buckets [bstart, bend) in the prune range (stime, etime].
+// If there is no complete bucket in the range, bstart >= bend.
+func sumBucketRange(origin *SumBucketOrigin, stime, etime *txtime.Time) (bstart, bend int64) {
+	if origin == nil {
+		return 0, 0
+	}
+	bstart = sumBucketStart(stime) + SumBucketSize // the bucket of stime can have the pays/fees of stime, which are already pruned
+	if bstart < origin.Start {
+		bstart = origin.Start
+	}
+	bend = sumBucketStart(etime)
+	return
+}
+
+// sumBucketBoundaries returns the end time of the head range and the start time of the tail range
+// to query the pays/fees out of the buckets [bstart, bend). (the prune query range is exclusive start, inclusive end)
+func sumBucketBoundaries(bstart, bend int64) (headEnd, tailStart *txtime.Time) {
+	headEnd = txtime.New(time.Unix(bstart, 0).Add(-time.Nanosecond))
+	tailStart = txtime.New(time.Unix(bend, 0).Add(-time.Nanosecond))
+	return
+}
@@ -0,0 +1,59 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// SumBucketStub _
+type SumBucketStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewSumBucketStub _
+func NewSumBucketStub(stub shim.ChaincodeStubInterface) *SumBucketStub {
+	return &SumBucketStub{stub}
+}
+
+// CreateOriginKey _
+func (sb *SumBucketStub) CreateOriginKey(id string) string {
+	return "SUMBO_" + id
+}
+
+// GetOrigin returns the bucket origin of the merchant address or the token code. If it is not exists, it returns nil.
+func (sb *SumBucketStub) GetOrigin(id string) (*SumBucketOrigin, error) {
+	data, err := sb.stub.GetState(sb.CreateOriginKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the sum bucket origin state")
+	}
+	if data == nil {
+		return nil, nil
+	}
+	origin := &SumBucketOrigin{}
+	if err = json.Unmarshal(data, origin); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the sum bucket origin")
+	}
+	return origin, nil
+}
+
+// EnsureOrigin puts the bucket origin if it is not exists.
+// It is called when a new bucket is put, so the origin exists if any bucket exists.
+func (sb *SumBucketStub) EnsureOrigin(id string, ts *txtime.Time) error {
+	origin, err := sb.GetOrigin(id)
+	if err != nil || origin != nil {
+		return err
+	}
+	data, err := json.Marshal(NewSumBucketOrigin(id, ts))
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the sum bucket origin")
+	}
+	if err = sb.stub.PutState(sb.CreateOriginKey(id), data); err != nil {
+		return errors.Wrap(err, "failed to put the sum bucket origin state")
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// AccountType _
+type AccountType byte
+
+const (
+	// AccountTypeUnknown _
+	AccountTypeUnknown AccountType = iota
+	// AccountTypePersonal _
+	AccountTypePersonal
+	// AccountTypeJoint _
+	AccountTypeJoint
+)
+
+var _validateTokenCode = regexp.MustCompile(`^[A-Z0-9]{3,6}$`).MatchString
+
+// ValidateTokenCode validates a code and returns an uppercased code
+func ValidateTokenCode(code string) (string, error) {
+	code = strings.ToUpper(code)
+	if !_validateTokenCode(code) {
+		return "", fmt.Errorf("invalid token code: %s", code)
+	}
+	return code, nil
+}
+
+// Address _
+type Address struct {
+	Code string
+	Type AccountType
+	Hash []byte
+}
+
+// NewAddress _
+// id is the KID of the holder for the personal account, or the ID of the joint account.
+// Like the chaincode, the token code is used as it is, so it must be the upper-cased code. (see ValidateTokenCode)
+func NewAddress(tokenCode string, typeCode AccountType, id string) *Address {
+	addr := &Address{}
+	addr.Code = tokenCode
+	addr.Type = typeCode
+
+	idh, err := hex.DecodeString(id)
+	if err != nil || len(idh) != 20 { // not kid
+		idh = make([]byte, 20)
+		sha3.ShakeSum256(idh, []byte(id))
+	}
+
+	// add checksum to hash
+	buf := bytes.NewBuffer(idh)
+	buf.Write(addr.Checksum(idh))
+	addr.Hash = buf.Bytes()
+
+	return addr
+}
+
+// ParseAddress parses address string and validates it
+func ParseAddress(addr string) (*Address, error) {
+	addr = strings.ToUpper(addr)
+	l := len(addr)
+	if l < 50 {
+		return nil, fmt.Errorf("invalid account address: length")
+	}
+	i := l - 50 // start index of hex
+
+	idh, err := hex.DecodeString(addr[i:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid account address: hex")
+	}
+
+	_addr := &Address{}
+	_addr.Code = addr[0:i]
+	_addr.Type = AccountType(idh[0])
+	_addr.Hash = idh[1:]
+
+	if err = _addr.Validate(); err != nil {
+		return nil, err
+	}
+	return _addr, nil
+}
+
+// ID _
+func (addr *Address) ID() string {
+	return hex.EncodeToString(addr.Hash[:20])
+}
+
+// Checksum _
+func (addr *Address) Checksum(hash []byte) []byte {
+	buf := bytes.NewBuffer([]byte(addr.Code))
+	buf.WriteByte(byte(addr.Type))
+	buf.Write(hash)
+	h := make([]byte, 4)
+	sha3.ShakeSum256(h, buf.Bytes())
+	return h
+}
+
+// Equal _
+func (addr *Address) Equal(a *Address) bool {
+	return addr.Code == a.Code && addr.Type == a.Type && bytes.Equal(addr.Hash, a.Hash)
+}
+
+// String _
+func (addr *Address) String() string {
+	// token code + [50 bytes upper-case hex]
+	return fmt.Sprintf("%s%02X%X", addr.Code, byte(addr.Type), addr.Hash)
+}
+
+// Validate validates the token code, the account type and the checksum.
+func (addr *Address) Validate() error {
+	// token code
+	if _, err := ValidateTokenCode(addr.Code); err != nil {
+		return fmt.Errorf("invalid account address: token code")
+	}
+	// account type
+	if addr.Type <= AccountTypeUnknown || addr.Type > AccountTypeJoint {
+		return fmt.Errorf("invalid account address: account type")
+	}
+	// checksum
+	checksum := addr.Checksum(addr.Hash[:20])
+	if bytes.HasSuffix(addr.Hash, checksum) {
+		return nil // valid
+	}
+	return fmt.Errorf("invalid account address: checksum")
+}
@@ -0,0 +1,77 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+// the addresses are produced by NewAddress of the chaincode
+var chaincodeAddresses = []struct {
+	code    string
+	typ     AccountType
+	id      string
+	address string
+}{
+	{"KNT", AccountTypePersonal, "0123456789abcdef0123456789abcdef01234567", "KNT010123456789ABCDEF0123456789ABCDEF0123456796D66503"},
+	{"PCI", AccountTypeJoint, "joint-account-tx-id", "PCI02A77E08877CF9695D40BB9D3CDFA4C75738024718309F9BF6"},
+	{"USDT", AccountTypePersonal, "not-a-kid", "USDT01100483F282309AF2F9948401D219B1EA53102F9233AA315B"},
+}
+
+func TestNewAddress(t *testing.T) {
+	for _, c := range chaincodeAddresses {
+		if s := NewAddress(c.code, c.typ, c.id).String(); s != c.address {
+			t.Errorf("NewAddress(%s, %d, %s) = %s, want %s", c.code, c.typ, c.id, s, c.address)
+		}
+	}
+	// lower-case token code is not upper-cased, like the chaincode
+	if s := NewAddress("knt", AccountTypePersonal, chaincodeAddresses[0].id).String(); s != "knt010123456789ABCDEF0123456789ABCDEF01234567404A9888" {
+		t.Errorf("NewAddress with lower-case code = %s, want the address of the chaincode", s)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, c := range chaincodeAddresses {
+		for _, s := range []string{c.address, strings.ToLower(c.address)} {
+			addr, err := ParseAddress(s)
+			if err != nil {
+				t.Errorf("ParseAddress(%s) failed: %s", s, err)
+				continue
+			}
+			if addr.Code != c.code || addr.Type != c.typ {
+				t.Errorf("ParseAddress(%s) = %s/%d, want %s/%d", s, addr.Code, addr.Type, c.code, c.typ)
+			}
+			if addr.String() != c.address {
+				t.Errorf("ParseAddress(%s).String() = %s, want %s", s, addr.String(), c.address)
+			}
+			if !addr.Equal(NewAddress(c.code, c.typ, c.id)) {
+				t.Errorf("ParseAddress(%s) is not equal to NewAddress", s)
+			}
+		}
+	}
+	// kid of the personal account
+	addr, _ := ParseAddress(chaincodeAddresses[0].address)
+	if addr.ID() != chaincodeAddresses[0].id {
+		t.Errorf("ID() = %s, want %s", addr.ID(), chaincodeAddresses[0].id)
+	}
+}
+
+func TestParseAddressInvalid(t *testing.T) {
+	valid := chaincodeAddresses[0].address
+	cases := map[string]string{
+		"checksum":     valid[:len(valid)-1] + "4", // last hex digit of the checksum
+		"hash":         valid[:10] + "F" + valid[11:],
+		"token code":   "PCI" + valid[3:], // the checksum includes the token code
+		"account type": "KNT02" + valid[5:],
+		"unknown type": "KNT00" + valid[5:],
+		"length":       valid[:40],
+		"hex":          valid[:len(valid)-1] + "Z",
+		"short code":   "K" + valid[3:],
+	}
+	for name, s := range cases {
+		if _, err := ParseAddress(s); err == nil {
+			t.Errorf("ParseAddress accepted the invalid address (%s): %s", name, s)
+		}
+	}
+}
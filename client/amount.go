@@ -0,0 +1,120 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Amount is the big integer amount of the token. It is a JSON string.
+type Amount struct {
+	big.Int
+}
+
+// NewAmount _
+func NewAmount(val string) (*Amount, error) {
+	a := &Amount{}
+	if len(val) > 0 {
+		if _, ok := a.SetString(val, 10); !ok {
+			return nil, errors.New("invalid amount value: must be integer")
+		}
+	}
+	return a, nil
+}
+
+// NewAmountWithInt64 _
+func NewAmountWithInt64(val int64) *Amount {
+	a := &Amount{}
+	a.SetInt64(val)
+	return a
+}
+
+var _matchDecimalAmount = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`).MatchString
+
+// NewAmountWithDecimal converts the decimal-formatted amount string (e.g. "12.5") with 10^decimal.
+// It is strictly rejected if the fraction digits exceed the decimal. (the same as the chaincode)
+func NewAmountWithDecimal(val string, decimal int) (*Amount, error) {
+	if !_matchDecimalAmount(val) {
+		return nil, errors.New("invalid amount value: must be decimal number")
+	}
+	i := strings.IndexByte(val, '.')
+	integer, fraction := val[:i], strings.TrimRight(val[i+1:], "0")
+	if len(fraction) > decimal {
+		return nil, fmt.Errorf("invalid amount value: precision exceeded. max %d fraction digits", decimal)
+	}
+	fraction += strings.Repeat("0", decimal-len(fraction))
+	return NewAmount(integer + fraction)
+}
+
+// ZeroAmount _
+func ZeroAmount() *Amount {
+	return &Amount{}
+}
+
+// Add override
+func (a *Amount) Add(x *Amount) *Amount {
+	a.Int.Add(&a.Int, &x.Int)
+	return a
+}
+
+// Cmp override
+func (a *Amount) Cmp(x *Amount) int {
+	return a.Int.Cmp(&x.Int)
+}
+
+// Copy _
+func (a *Amount) Copy() *Amount {
+	n := &Amount{}
+	return n.Add(a)
+}
+
+// Neg override
+func (a *Amount) Neg() *Amount {
+	a.Int.Neg(&a.Int)
+	return a
+}
+
+// DecimalString returns the amount formatted with 10^decimal. (e.g. 12500000 with decimal 6 => "12.5")
+func (a *Amount) DecimalString(decimal int) string {
+	digits := new(big.Int).Abs(&a.Int).String()
+	if decimal > 0 {
+		if len(digits) <= decimal {
+			digits = strings.Repeat("0", decimal-len(digits)+1) + digits
+		}
+		i := len(digits) - decimal
+		if fraction := strings.TrimRight(digits[i:], "0"); len(fraction) > 0 {
+			digits = digits[:i] + "." + fraction
+		} else {
+			digits = digits[:i]
+		}
+	}
+	if a.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON override
+func (a *Amount) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer([]byte{'"'})
+	if _, err := buf.WriteString(a.String()); err != nil {
+		return nil, err
+	}
+	if err := buf.WriteByte('"'); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON override
+func (a *Amount) UnmarshalJSON(text []byte) error {
+	if len(text) < 2 || text[0] != '"' {
+		return errors.New("invalid amount value: must be JSON string")
+	}
+	return a.Int.UnmarshalJSON(text[1 : len(text)-1])
+}
@@ -0,0 +1,111 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewAmountWithDecimal(t *testing.T) {
+	cases := []struct {
+		val     string
+		decimal int
+		want    string
+	}{
+		{"12.5", 6, "12500000"},
+		{"12.500000", 6, "12500000"},
+		{"0.000001", 6, "1"},
+		{"1.0", 0, "1"},
+		{"100.00", 18, "100000000000000000000"},
+		{"-1.5", 2, "-150"},
+	}
+	for _, c := range cases {
+		a, err := NewAmountWithDecimal(c.val, c.decimal)
+		if err != nil {
+			t.Errorf("NewAmountWithDecimal(%s, %d) failed: %s", c.val, c.decimal, err)
+			continue
+		}
+		if a.String() != c.want {
+			t.Errorf("NewAmountWithDecimal(%s, %d) = %s, want %s", c.val, c.decimal, a.String(), c.want)
+		}
+	}
+}
+
+func TestNewAmountWithDecimalInvalid(t *testing.T) {
+	cases := []struct {
+		val     string
+		decimal int
+	}{
+		{"0.0000001", 6}, // precision exceeded
+		{"1.5", 0},
+		{"12", 6}, // no decimal point
+		{"12.", 6},
+		{".5", 6},
+		{"1,5", 6},
+		{"1e3", 6},
+		{"", 6},
+	}
+	for _, c := range cases {
+		if a, err := NewAmountWithDecimal(c.val, c.decimal); err == nil {
+			t.Errorf("NewAmountWithDecimal(%q, %d) = %s, want error", c.val, c.decimal, a.String())
+		}
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	cases := []struct {
+		val     string
+		decimal int
+		want    string
+	}{
+		{"12500000", 6, "12.5"},
+		{"12000000", 6, "12"},
+		{"1", 6, "0.000001"},
+		{"0", 6, "0"},
+		{"-150", 2, "-1.5"},
+		{"123", 0, "123"},
+	}
+	for _, c := range cases {
+		a, err := NewAmount(c.val)
+		if err != nil {
+			t.Fatalf("NewAmount(%s) failed: %s", c.val, err)
+		}
+		s := a.DecimalString(c.decimal)
+		if s != c.want {
+			t.Errorf("DecimalString(%s, %d) = %s, want %s", c.val, c.decimal, s, c.want)
+		}
+		// round trip
+		if c.decimal > 0 {
+			if !strings.Contains(s, ".") {
+				s += ".0"
+			}
+			b, err := NewAmountWithDecimal(s, c.decimal)
+			if err != nil || b.Cmp(a) != 0 {
+				t.Errorf("NewAmountWithDecimal(%s, %d) is not %s", s, c.decimal, c.val)
+			}
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	a, _ := NewAmount("123456789012345678901234567890")
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"123456789012345678901234567890"` {
+		t.Errorf("json.Marshal = %s", data)
+	}
+	b := &Amount{}
+	if err = json.Unmarshal(data, b); err != nil || b.Cmp(a) != 0 {
+		t.Errorf("json.Unmarshal(%s) = %s, %v", data, b.String(), err)
+	}
+	if err = json.Unmarshal([]byte("123"), b); err == nil {
+		t.Error("json.Unmarshal accepted the number")
+	}
+	if _, err = NewAmount("1.5"); err == nil {
+		t.Error("NewAmount accepted the decimal")
+	}
+}
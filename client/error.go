@@ -0,0 +1,34 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Error is the JSON error envelope of the failure response.
+// Code and Name are stable, use them instead of Message.
+type Error struct {
+	Code    int    `json:"code"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s(%d): %s", e.Name, e.Code, e.Message)
+}
+
+// ParseError parses the message of the failure response.
+// The legacy plain message (e.g. "found no record to prune.") is INTERNAL(1000) error.
+func ParseError(message string) *Error {
+	e := &Error{}
+	if strings.HasPrefix(message, "{") {
+		if err := json.Unmarshal([]byte(message), e); err == nil && e.Code > 0 {
+			return e
+		}
+	}
+	return &Error{Code: 1000, Name: "INTERNAL", Message: message}
+}
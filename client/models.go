@@ -0,0 +1,709 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Response types of the token chaincode.
+// Times are RFC3339 strings with nano seconds on the ledger, so time.Time is used for them.
+
+// Token _
+type Token struct {
+	Code            string                 `json:"@token"`
+	Decimal         int                    `json:"decimal"`
+	MaxSupply       Amount                 `json:"max_supply"`
+	Supply          Amount                 `json:"supply"`
+	LastPrunedFeeID string                 `json:"last_pruned_fee_id,omitempty"`
+	GenesisAccount  string                 `json:"genesis_account"`
+	FeePolicy       *FeePolicy             `json:"fee_policy,omitempty"`
+	WrapBridge      map[string]*WrapPolicy `json:"wrap_bridge,omitempty"`  // map of ext code and wrap policy
+	PruneWindow     int64                  `json:"prune_window,omitempty"` // seconds, 0 is the default (600)
+	CreatedTime     *time.Time             `json:"created_time,omitempty"`
+	UpdatedTime     *time.Time             `json:"updated_time,omitempty"`
+}
+
+// PayPrunePreview is the response of pay/prune/preview.
+type PayPrunePreview struct {
+	Address   string     `json:"address"`
+	StartTime *time.Time `json:"start_time"` // exclusive
+	EndTime   *time.Time `json:"end_time"`
+	StartID   string     `json:"start_id"`
+	EndID     string     `json:"end_id"`
+	Count     int        `json:"prune_count"`
+	Sum       Amount     `json:"sum"`
+	Fee       Amount     `json:"fee"`
+	Applied   Amount     `json:"applied"` // sum - fee
+	HasMore   bool       `json:"has_more"`
+}
+
+// FeePrunePreview is the response of fee/prune/preview.
+type FeePrunePreview struct {
+	Address   string     `json:"address"`
+	StartTime *time.Time `json:"start_time"` // exclusive
+	EndTime   *time.Time `json:"end_time"`
+	StartID   string     `json:"start_id"`
+	EndID     string     `json:"end_id"`
+	Count     int        `json:"count"`
+	Sum       Amount     `json:"sum"`
+	HasMore   bool       `json:"has_more"`
+	Shares    []FeeShare `json:"shares,omitempty"`
+}
+
+// FeeShare is the amount of the pruned fees distributed to the beneficiary.
+type FeeShare struct {
+	Address string `json:"address"`
+	Amount  Amount `json:"amount"`
+}
+
+// TokenAuditTotal is the accumulated total of the audit bucket.
+type TokenAuditTotal struct {
+	Sum   Amount `json:"sum"`
+	Count int    `json:"count"`
+}
+
+// TokenAuditDiscrepancy _
+type TokenAuditDiscrepancy struct {
+	Bucket   string  `json:"bucket"` // "balances" | "pending_balances" | "pays" | "fees" | "requested_wraps" | "supply"
+	Key      string  `json:"key,omitempty"`
+	Expected *Amount `json:"expected,omitempty"`
+	Actual   *Amount `json:"actual"`
+	Reason   string  `json:"reason"`
+}
+
+// TokenAudit is the response of token/audit. Sum and Holds are valid only if it is done.
+type TokenAudit struct {
+	Token         string                      `json:"token"`
+	Supply        Amount                      `json:"supply"`
+	Bucket        string                      `json:"bucket"`
+	Fetched       int                         `json:"fetched"`
+	Totals        map[string]*TokenAuditTotal `json:"totals"`
+	Done          bool                        `json:"done"`
+	Sum           *Amount                     `json:"sum,omitempty"`
+	Holds         bool                        `json:"holds"`
+	Cursor        string                      `json:"cursor,omitempty"`
+	Discrepancies []*TokenAuditDiscrepancy    `json:"discrepancies"`
+}
+
+// FeePolicy _
+type FeePolicy struct {
+	TargetAddress string             `json:"target_address"`
+	Rates         map[string]FeeRate `json:"rates"`                   // map of fn("transfer", "pay") and fee rate
+	Beneficiaries []FeeBeneficiary   `json:"beneficiaries,omitempty"` // empty is all to the target address
+}
+
+// FeeBeneficiary _
+type FeeBeneficiary struct {
+	Address string `json:"address"`
+	Share   int64  `json:"share"`
+}
+
+// FeeRate _
+type FeeRate struct {
+	Rate      string    `json:"rate"`             // numeric string of positive decimal fraction
+	MaxAmount int64     `json:"max_amount"`       // 0 is unlimit
+	Bearer    FeeBearer `json:"bearer,omitempty"` // default bearer of the fn
+}
+
+// FeeBearer is the account which bears the fee.
+type FeeBearer int8
+
+const (
+	// FeeBearerDefault the default bearer of the fn (transfer: sender, pay: receiver)
+	FeeBearerDefault FeeBearer = iota
+	// FeeBearerSender the fee is charged to the sender on top of the amount
+	FeeBearerSender
+	// FeeBearerReceiver the fee is deducted from the amount which the receiver receives
+	FeeBearerReceiver
+	// FeeBearerSponsor the fee is charged to the sponsor account
+	FeeBearerSponsor
+)
+
+// FeeSponsorship is the authorization of the sponsor account to bear the fees of the account.
+type FeeSponsorship struct {
+	Sponsor     string     `json:"@fee_sponsorship"`
+	Account     string     `json:"account"`
+	CreatedTime *time.Time `json:"created_time,omitempty"`
+}
+
+// WrapPolicy _
+type WrapPolicy struct {
+	WrapAddress      string   `json:"wrap_address"`
+	ExtChain         string   `json:"ext_chain"`
+	Expiry           int64    `json:"expiry,omitempty"` // seconds, 0 is no expiry
+	Validators       []string `json:"validators,omitempty"`
+	Threshold        int      `json:"threshold,omitempty"`         // 0 is unwrap by the wrap account holder
+	WrapLimitPerTx   *Amount  `json:"wrap_limit_per_tx,omitempty"` // nil is unlimited
+	WrapLimitDaily   *Amount  `json:"wrap_limit_daily,omitempty"`
+	UnwrapLimitPerTx *Amount  `json:"unwrap_limit_per_tx,omitempty"`
+	UnwrapLimitDaily *Amount  `json:"unwrap_limit_daily,omitempty"`
+}
+
+// WrapStatus _
+type WrapStatus int8
+
+const (
+	// WrapStatusRequested _
+	WrapStatusRequested WrapStatus = iota
+	// WrapStatusCompleted _
+	WrapStatusCompleted
+	// WrapStatusImpossible _
+	WrapStatusImpossible
+	// WrapStatusRefunded _
+	WrapStatusRefunded
+)
+
+// Wrap is the wrap request to the bridge. (wrap/get, wrap/list)
+type Wrap struct {
+	ID           string     `json:"@wrap"` // tx id
+	Token        string     `json:"token,omitempty"`
+	Address      string     `json:"address"` // sender
+	Amount       Amount     `json:"amount"`
+	ExtCode      string     `json:"ext_code"`
+	ExtID        string     `json:"ext_id"`
+	CompleteTxID string     `json:"complete_tx_id,omitempty"`
+	Memo         string     `json:"memo"`
+	OrderID      string     `json:"order_id,omitempty"`
+	Status       WrapStatus `json:"status"`
+	Expiry       *time.Time `json:"expiry,omitempty"` // refundable after it
+	Ledger       bool       `json:"ledger,omitempty"` // in the pending of the bridge ledger
+	CreatedTime  *time.Time `json:"created_time,omitempty"`
+	UpdatedTime  *time.Time `json:"updated_time,omitempty"`
+}
+
+// Unwrap is the unwrap record of the external tx. (unwrap/get)
+type Unwrap struct {
+	ExtTxID      string     `json:"@unwrap"`
+	CompleteTxID string     `json:"complete_tx_id,omitempty"`
+	Token        string     `json:"token,omitempty"`
+	Address      string     `json:"address,omitempty"` // receiver
+	Amount       *Amount    `json:"amount,omitempty"`
+	ExtCode      string     `json:"ext_code,omitempty"`
+	ExtID        string     `json:"ext_id,omitempty"`
+	Impossible   bool       `json:"impossible,omitempty"`
+	CreatedTime  *time.Time `json:"created_time,omitempty"`
+}
+
+// UnwrapAttestationStatus _
+type UnwrapAttestationStatus int8
+
+const (
+	// UnwrapAttestationStatusPending _
+	UnwrapAttestationStatusPending UnwrapAttestationStatus = iota
+	// UnwrapAttestationStatusExecuted _
+	UnwrapAttestationStatusExecuted
+)
+
+// UnwrapAttest is the attestation of a bridge validator.
+type UnwrapAttest struct {
+	Validator   string     `json:"validator"`
+	Receiver    string     `json:"receiver"`
+	ExtID       string     `json:"ext_id"`
+	Amount      Amount     `json:"amount"`
+	Digest      string     `json:"digest"`
+	CreatedTime *time.Time `json:"created_time,omitempty"`
+}
+
+// UnwrapAttestation is the attestations of the external tx. (unwrap/attestation/get)
+type UnwrapAttestation struct {
+	ExtTxID      string                  `json:"@unwrap_attestation"`
+	Token        string                  `json:"token"`
+	ExtCode      string                  `json:"ext_code"`
+	Threshold    int                     `json:"threshold"`
+	Attests      []*UnwrapAttest         `json:"attests"`
+	Conflicted   bool                    `json:"conflicted"`
+	Status       UnwrapAttestationStatus `json:"status"`
+	CompleteTxID string                  `json:"complete_tx_id,omitempty"`
+	CreatedTime  *time.Time              `json:"created_time,omitempty"`
+	UpdatedTime  *time.Time              `json:"updated_time,omitempty"`
+}
+
+// UnwrapAttestResult is the response of unwrap/attest.
+type UnwrapAttestResult struct {
+	Attestation *UnwrapAttestation `json:"attestation"`
+	BalanceLog  *BalanceLog        `json:"balance_log,omitempty"`
+}
+
+// WrapBatchItemResult is the result of an item of the batch. The failed item has the error and is skipped.
+type WrapBatchItemResult struct {
+	ID    string `json:"id"` // wrap id | normalized external tx id
+	Error *Error `json:"error,omitempty"`
+}
+
+// WrapBatchResult is the response of wrap/complete/batch and unwrap/batch.
+type WrapBatchResult struct {
+	BatchID     string                 `json:"batch_id"`
+	Applied     int                    `json:"applied"`
+	Items       []*WrapBatchItemResult `json:"items"`
+	BalanceLogs []*BalanceLog          `json:"balance_logs"`
+}
+
+// BridgeLimit is the limit of the wrap policy with the remaining amount of the day. nil is unlimited.
+type BridgeLimit struct {
+	PerTx          *Amount `json:"per_tx,omitempty"`
+	Daily          *Amount `json:"daily,omitempty"`
+	DailyRemaining *Amount `json:"daily_remaining,omitempty"`
+}
+
+// BridgeStatus is the bridge ledger of the external token code. (bridge/status)
+type BridgeStatus struct {
+	ID             string       `json:"@bridge"` // token code + "_" + external token code
+	Token          string       `json:"token"`
+	ExtCode        string       `json:"ext_code"`
+	Pending        Amount       `json:"pending"`
+	WrappedOut     Amount       `json:"wrapped_out"`
+	UnwrappedIn    Amount       `json:"unwrapped_in"`
+	WrapDay        string       `json:"wrap_day,omitempty"` // UTC date (YYYY-MM-DD)
+	WrapDayTotal   Amount       `json:"wrap_day_total"`
+	UnwrapDay      string       `json:"unwrap_day,omitempty"`
+	UnwrapDayTotal Amount       `json:"unwrap_day_total"`
+	UpdatedTime    *time.Time   `json:"updated_time,omitempty"`
+	ExtChain       string       `json:"ext_chain"`
+	WrapAddress    string       `json:"wrap_address"`
+	WrapBalance    Amount       `json:"wrap_balance"`
+	Outstanding    Amount       `json:"outstanding"` // wrapped out - unwrapped in
+	WrapLimit      *BridgeLimit `json:"wrap_limit"`
+	UnwrapLimit    *BridgeLimit `json:"unwrap_limit"`
+}
+
+// Account is the response of account/get. (personal or joint account with its balance)
+type Account struct {
+	Address       string      `json:"@account"`
+	Token         string      `json:"token"`
+	Type          AccountType `json:"type"`
+	Holders       []string    `json:"holders,omitempty"` // KIDs, joint account only
+	Balance       *Balance    `json:"balance,omitempty"`
+	CreatedTime   *time.Time  `json:"created_time,omitempty"`
+	UpdatedTime   *time.Time  `json:"updated_time,omitempty"`
+	SuspendedTime *time.Time  `json:"suspended_time,omitempty"`
+}
+
+// IsSuspended _
+func (a *Account) IsSuspended() bool {
+	return a.SuspendedTime != nil
+}
+
+// Balance _
+type Balance struct {
+	Address         string     `json:"@balance"`
+	Amount          Amount     `json:"amount"`
+	LastPrunedPayID string     `json:"last_pruned_pay_id,omitempty"`
+	CreatedTime     *time.Time `json:"created_time,omitempty"`
+	UpdatedTime     *time.Time `json:"updated_time,omitempty"`
+}
+
+// BalanceAt is the response of balance/at.
+type BalanceAt struct {
+	Address    string      `json:"address"`
+	Time       *time.Time  `json:"time"`
+	Amount     Amount      `json:"amount"`
+	BalanceLog *BalanceLog `json:"balance_log,omitempty"` // the last balance log at the time
+}
+
+// BalanceHistory is the modification of the balance state.
+type BalanceHistory struct {
+	TxID      string     `json:"tx_id"`
+	Timestamp *time.Time `json:"timestamp"`
+	IsDelete  bool       `json:"is_delete,omitempty"`
+	Balance   *Balance   `json:"balance,omitempty"`
+}
+
+// BalanceHistoryResult is the response of balance/history.
+type BalanceHistoryResult struct {
+	Address string            `json:"address"`
+	History []*BalanceHistory `json:"history"`
+	HasMore bool              `json:"has_more"`
+}
+
+// BalanceLogBrokenLink _
+type BalanceLogBrokenLink struct {
+	Key      string `json:"key"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// BalanceLogsVerification is the response of balance/logs/verify.
+type BalanceLogsVerification struct {
+	Address     string                `json:"address"`
+	Verified    int                   `json:"verified"`
+	Legacy      int                   `json:"legacy"`
+	Valid       bool                  `json:"valid"`
+	Broken      *BalanceLogBrokenLink `json:"broken,omitempty"` // the first broken link
+	HeadChecked bool                  `json:"head_checked"`
+	Cursor      string                `json:"cursor,omitempty"`
+}
+
+// BalanceStatementTotal is the totals of the balance log type in the statement period.
+type BalanceStatementTotal struct {
+	Type  BalanceLogType `json:"type"`
+	Count int            `json:"count"`
+	Diff  Amount         `json:"diff"`
+	Fee   Amount         `json:"fee"`
+}
+
+// BalanceStatement is the response of balance/statement.
+// Entries is the JSON array of the balance logs, or CSV is set if the format is "csv".
+type BalanceStatement struct {
+	Address        string                   `json:"address"`
+	StartTime      *time.Time               `json:"start_time"`
+	EndTime        *time.Time               `json:"end_time"`
+	OpeningBalance Amount                   `json:"opening_balance"`
+	ClosingBalance Amount                   `json:"closing_balance"`
+	Totals         []*BalanceStatementTotal `json:"totals"`
+	TotalFee       Amount                   `json:"total_fee"`
+	Meta           *QueryMeta               `json:"meta,omitempty"`
+	Entries        json.RawMessage          `json:"entries,omitempty"`
+	CSV            string                   `json:"csv,omitempty"`
+}
+
+// BalanceLogType _
+type BalanceLogType int8
+
+const (
+	// BalanceLogTypeMint _
+	BalanceLogTypeMint BalanceLogType = iota
+	// BalanceLogTypeBurn _
+	BalanceLogTypeBurn
+	// BalanceLogTypeSend _
+	BalanceLogTypeSend
+	// BalanceLogTypeReceive _
+	BalanceLogTypeReceive
+	// BalanceLogTypeDeposit deposit balance to contract
+	BalanceLogTypeDeposit
+	// BalanceLogTypeWithdraw withdraw balance from contract
+	BalanceLogTypeWithdraw
+	// BalanceLogTypePay pay amount of balance
+	BalanceLogTypePay
+	// BalanceLogTypeRefund refund amount of balance
+	BalanceLogTypeRefund
+	// BalanceLogTypePrunePay the amount of pruned payments
+	BalanceLogTypePrunePay
+	// BalanceLogTypePruneFee the amount of pruned fees
+	BalanceLogTypePruneFee
+	// BalanceLogTypeWrap wrap to bridge
+	BalanceLogTypeWrap
+	// BalanceLogTypeUnwrap unwrap from bridge
+	BalanceLogTypeUnwrap
+	// BalanceLogTypeWrapComplete wrap bridge handles fee
+	BalanceLogTypeWrapComplete
+	// BalanceLogTypeUnwrapComplete unwrap balance from bridge account
+	BalanceLogTypeUnwrapComplete
+	// BalanceLogTypeHTLCLock lock balance to htlc
+	BalanceLogTypeHTLCLock
+	// BalanceLogTypeHTLCClaim claim htlc balance with the preimage
+	BalanceLogTypeHTLCClaim
+	// BalanceLogTypeHTLCRefund refund htlc balance after the timelock
+	BalanceLogTypeHTLCRefund
+	// BalanceLogTypeDexTrade net change of the account by the dex order placement (lock, trades and unlock)
+	BalanceLogTypeDexTrade
+	// BalanceLogTypeDexCancel unlock balance of the cancelled dex order
+	BalanceLogTypeDexCancel
+	// BalanceLogTypePoolAdd add liquidity to the pool (or create the pool)
+	BalanceLogTypePoolAdd
+	// BalanceLogTypePoolRemove remove liquidity from the pool
+	BalanceLogTypePoolRemove
+	// BalanceLogTypePoolSwap swap tokens with the pool
+	BalanceLogTypePoolSwap
+	// BalanceLogTypeConvert convert the token to the other token at the issuer-set rate
+	BalanceLogTypeConvert
+	// BalanceLogTypeDistributionCreate lock balance of the payout account to the distribution
+	BalanceLogTypeDistributionCreate
+	// BalanceLogTypeDistributionClaim claim the share of the distribution
+	BalanceLogTypeDistributionClaim
+	// BalanceLogTypeDistributionClose return the remainder of the distribution to the payout account
+	BalanceLogTypeDistributionClose
+	// BalanceLogTypeWrapRefund return the amount of the expired wrap to the sender
+	BalanceLogTypeWrapRefund
+	// BalanceLogTypeFeeSponsor the fee of the other account's transaction borne by the sponsor
+	BalanceLogTypeFeeSponsor
+)
+
+// BalanceLog _
+type BalanceLog struct {
+	Address      string         `json:"@balance_log"`
+	Type         BalanceLogType `json:"type"`
+	RID          string         `json:"rid"` // relative ID
+	Diff         Amount         `json:"diff"`
+	Fee          *Amount        `json:"fee,omitempty"`
+	Amount       Amount         `json:"amount"`
+	Memo         string         `json:"memo,omitempty"`
+	CreatedTime  *time.Time     `json:"created_time,omitempty"`
+	PruneStartID string         `json:"prune_start_id,omitempty"`
+	PruneEndID   string         `json:"prune_end_id,omitempty"`
+	PayID        string         `json:"pay_id,omitempty"`
+	OrderID      string         `json:"order_id,omitempty"`
+	ExtCode      string         `json:"ext_code,omitempty"`
+	ExtTxID      string         `json:"ext_tx_id,omitempty"`
+	PrevHash     string         `json:"prev_hash,omitempty"`
+	Hash         string         `json:"hash,omitempty"`
+}
+
+// PendingBalanceType _
+type PendingBalanceType int8
+
+const (
+	// PendingBalanceTypeAccount _
+	PendingBalanceTypeAccount PendingBalanceType = iota
+	// PendingBalanceTypeContract _
+	PendingBalanceTypeContract
+	// PendingBalanceTypeHTLC is locked by htlc. (see htlc/claim and htlc/refund)
+	PendingBalanceTypeHTLC
+	// PendingBalanceTypeDex is locked by dex order. (see dex/order/cancel)
+	PendingBalanceTypeDex
+	// PendingBalanceTypeDistribution is locked by distribution. (see distribution/close)
+	PendingBalanceTypeDistribution
+)
+
+// PendingBalance _
+type PendingBalance struct {
+	ID          string             `json:"@pending_balance"`
+	Type        PendingBalanceType `json:"type"`
+	Account     string             `json:"account"`
+	RID         string             `json:"rid"` // relative ID - account or contract
+	Amount      Amount             `json:"amount"`
+	Fee         *Amount            `json:"fee,omitempty"`
+	Memo        string             `json:"memo"`
+	OrderID     string             `json:"order_id,omitempty"`
+	CreatedTime *time.Time         `json:"created_time,omitempty"`
+	PendingTime *time.Time         `json:"pending_time,omitempty"`
+}
+
+// Pay _
+type Pay struct {
+	Key         string     `json:"@pay"`
+	PayID       string     `json:"pay_id"`
+	Amount      Amount     `json:"amount"` // positive(pay) or negative(refund)
+	Fee         Amount     `json:"fee"`
+	TotalRefund Amount     `json:"total_refund,omitempty"`
+	RID         string     `json:"rid"`                 // payer or refund receiver
+	ParentID    string     `json:"parent_id,omitempty"` // refund only
+	OrderID     string     `json:"order_id,omitempty"`
+	Memo        string     `json:"memo"`
+	CreatedTime *time.Time `json:"created_time,omitempty"`
+}
+
+// HTLCStatus _
+type HTLCStatus int8
+
+const (
+	// HTLCStatusLocked _
+	HTLCStatusLocked HTLCStatus = iota
+	// HTLCStatusClaimed _
+	HTLCStatusClaimed
+	// HTLCStatusRefunded _
+	HTLCStatusRefunded
+)
+
+// HTLC is the hash time-locked contract. (htlc/get)
+type HTLC struct {
+	ID          string     `json:"@htlc"`
+	Sender      string     `json:"sender"`
+	Receiver    string     `json:"receiver"`
+	Amount      Amount     `json:"amount"`
+	Fee         Amount     `json:"fee"`
+	HashAlgo    string     `json:"hash_algo"` // "sha256" | "keccak256"
+	Hashlock    string     `json:"hashlock"`
+	Timelock    *time.Time `json:"timelock"`
+	Preimage    string     `json:"preimage,omitempty"` // revealed by the claim
+	Status      HTLCStatus `json:"status"`
+	Memo        string     `json:"memo,omitempty"`
+	OrderID     string     `json:"order_id,omitempty"`
+	CreatedTime *time.Time `json:"created_time,omitempty"`
+	UpdatedTime *time.Time `json:"updated_time,omitempty"`
+}
+
+// HTLCResult is the response of htlc/lock, htlc/claim and htlc/refund.
+type HTLCResult struct {
+	HTLC       *HTLC       `json:"htlc"`
+	BalanceLog *BalanceLog `json:"balance_log"`
+}
+
+// DexOrderSide _
+type DexOrderSide int8
+
+const (
+	// DexOrderSideBuy _
+	DexOrderSideBuy DexOrderSide = iota
+	// DexOrderSideSell _
+	DexOrderSideSell
+)
+
+// DexOrderStatus _
+type DexOrderStatus int8
+
+const (
+	// DexOrderStatusOpen _
+	DexOrderStatusOpen DexOrderStatus = iota
+	// DexOrderStatusFilled _
+	DexOrderStatusFilled
+	// DexOrderStatusCancelled _
+	DexOrderStatusCancelled
+)
+
+// DexOrder is the limit order of the order book. (dex/order/get)
+type DexOrder struct {
+	ID           string         `json:"@dex_order"`
+	Market       string         `json:"market"` // BASE/QUOTE
+	Side         DexOrderSide   `json:"side"`
+	Price        string         `json:"price"`     // decimal
+	Amount       Amount         `json:"amount"`    // base amount
+	Remaining    Amount         `json:"remaining"` // base amount not filled yet
+	Locked       Amount         `json:"locked"`    // sell: base, buy: quote
+	BaseAccount  string         `json:"base_account"`
+	QuoteAccount string         `json:"quote_account"`
+	Status       DexOrderStatus `json:"status"`
+	CreatedTime  *time.Time     `json:"created_time,omitempty"`
+	UpdatedTime  *time.Time     `json:"updated_time,omitempty"`
+}
+
+// DexTrade _
+type DexTrade struct {
+	Market       string       `json:"@dex_trade"`
+	TradeID      string       `json:"trade_id"`
+	Price        string       `json:"price"`
+	Amount       Amount       `json:"amount"`
+	QuoteAmount  Amount       `json:"quote_amount"`
+	MakerOrderID string       `json:"maker_order_id"`
+	TakerOrderID string       `json:"taker_order_id"`
+	TakerSide    DexOrderSide `json:"taker_side"`
+	BuyerFee     Amount       `json:"buyer_fee"`
+	SellerFee    Amount       `json:"seller_fee"`
+	CreatedTime  *time.Time   `json:"created_time,omitempty"`
+}
+
+// DexOrderResult is the response of dex/order/place and dex/order/cancel.
+type DexOrderResult struct {
+	Order       *DexOrder     `json:"order"`
+	Trades      []*DexTrade   `json:"trades,omitempty"`
+	BalanceLogs []*BalanceLog `json:"balance_logs"`
+}
+
+// Pool is the constant-product liquidity pool. (pool/get has price_a and price_b)
+type Pool struct {
+	ID          string     `json:"@pool"` // "A/B" (sorted token codes)
+	TokenA      string     `json:"token_a"`
+	TokenB      string     `json:"token_b"`
+	DecimalA    int        `json:"decimal_a"`
+	DecimalB    int        `json:"decimal_b"`
+	AccountA    string     `json:"account_a"`
+	AccountB    string     `json:"account_b"`
+	ReserveA    Amount     `json:"reserve_a"`
+	ReserveB    Amount     `json:"reserve_b"`
+	TotalShares Amount     `json:"total_shares"`
+	FeeRate     string     `json:"fee_rate"`
+	PriceA      string     `json:"price_a,omitempty"`
+	PriceB      string     `json:"price_b,omitempty"`
+	CreatedTime *time.Time `json:"created_time,omitempty"`
+	UpdatedTime *time.Time `json:"updated_time,omitempty"`
+}
+
+// PoolShare _
+type PoolShare struct {
+	PoolID      string     `json:"@pool_share"`
+	Provider    string     `json:"provider"`
+	Shares      Amount     `json:"shares"`
+	CreatedTime *time.Time `json:"created_time,omitempty"`
+	UpdatedTime *time.Time `json:"updated_time,omitempty"`
+}
+
+// PoolResult is the response of pool/create, pool/add, pool/remove and pool/swap.
+type PoolResult struct {
+	Pool        *Pool         `json:"pool"`
+	Share       *PoolShare    `json:"share,omitempty"`
+	AmountIn    *Amount       `json:"amount_in,omitempty"`
+	AmountOut   *Amount       `json:"amount_out,omitempty"`
+	Fee         *Amount       `json:"fee,omitempty"`
+	BalanceLogs []*BalanceLog `json:"balance_logs"`
+}
+
+// TransferMultiResult is the response of transfer/multi.
+type TransferMultiResult struct {
+	TransferID  string        `json:"transfer_id"`
+	OrderID     string        `json:"order_id,omitempty"`
+	BalanceLogs []*BalanceLog `json:"balance_logs"`
+}
+
+// Conversion is the issuer-configured conversion pair. (conversion/get has reserve_balance)
+type Conversion struct {
+	ID             string     `json:"@conversion"` // "SOURCE>TARGET"
+	Source         string     `json:"source"`
+	Target         string     `json:"target"`
+	Rate           string     `json:"rate"`
+	Mode           int8       `json:"mode"` // 0 = transfer, 1 = burn
+	ReserveAccount string     `json:"reserve_account"`
+	MinAmount      Amount     `json:"min_amount"`
+	MaxAmount      Amount     `json:"max_amount"`
+	Paused         bool       `json:"paused"`
+	LastContractID string     `json:"last_contract_id"`
+	TotalSource    Amount     `json:"total_source"`
+	TotalTarget    Amount     `json:"total_target"`
+	ReserveBalance *Amount    `json:"reserve_balance,omitempty"`
+	CreatedTime    *time.Time `json:"created_time,omitempty"`
+	UpdatedTime    *time.Time `json:"updated_time,omitempty"`
+}
+
+// ConversionResult is the response of convert.
+type ConversionResult struct {
+	Conversion   *Conversion   `json:"conversion"`
+	SourceAmount *Amount       `json:"source_amount"`
+	TargetAmount *Amount       `json:"target_amount"`
+	BalanceLogs  []*BalanceLog `json:"balance_logs"`
+}
+
+// Distribution is the pro-rata payout to the holders of the token.
+// (distribution/get has balance, claimable and claim of the account if it is given)
+type Distribution struct {
+	ID             string             `json:"@distribution"`
+	Token          string             `json:"token"`
+	PayoutToken    string             `json:"payout_token"`
+	PayoutAccount  string             `json:"payout_account"`
+	Amount         Amount             `json:"amount"`
+	Remaining      Amount             `json:"remaining"`
+	SnapshotTime   *time.Time         `json:"snapshot_time"`
+	EligibleSupply Amount             `json:"eligible_supply"`
+	Excluded       []string           `json:"excluded"`
+	ClaimCount     int                `json:"claim_count"`
+	Status         int8               `json:"status"` // 0 = open, 1 = closed
+	Memo           string             `json:"memo,omitempty"`
+	Expiry         *time.Time         `json:"expiry"`
+	Account        string             `json:"account,omitempty"`
+	Balance        *Amount            `json:"balance,omitempty"`
+	Claimable      *Amount            `json:"claimable,omitempty"`
+	Claim          *DistributionClaim `json:"claim,omitempty"`
+	CreatedTime    *time.Time         `json:"created_time,omitempty"`
+	UpdatedTime    *time.Time         `json:"updated_time,omitempty"`
+}
+
+// DistributionClaim _
+type DistributionClaim struct {
+	DistributionID string     `json:"@distribution_claim"`
+	Account        string     `json:"account"`
+	Receiver       string     `json:"receiver"`
+	Balance        Amount     `json:"balance"`
+	Amount         Amount     `json:"amount"`
+	CreatedTime    *time.Time `json:"created_time,omitempty"`
+}
+
+// DistributionResult is the response of distribution/create, distribution/claim and distribution/close.
+type DistributionResult struct {
+	Distribution *Distribution      `json:"distribution"`
+	Claim        *DistributionClaim `json:"claim,omitempty"`
+	BalanceLogs  []*BalanceLog      `json:"balance_logs"`
+}
+
+// PayResult is the response of pay and pay/refund.
+// Pay is nil if a multi-sig contract is created. (BalanceLog is the deposit log)
+type PayResult struct {
+	Pay        *Pay        `json:"pay"`
+	BalanceLog *BalanceLog `json:"balance_log"`
+}
+
+// TokenResult is the response of token/burn and token/mint.
+type TokenResult struct {
+	Token      *Token          `json:"token,omitempty"`
+	BalanceLog *BalanceLog     `json:"balance_log,omitempty"`
+	Contract   json.RawMessage `json:"contract,omitempty"` // kiesnet-contract
+}
@@ -0,0 +1,79 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+)
+
+// QueryResult is the response of the list queries. (e.g. balance/logs, pay/list)
+type QueryResult struct {
+	Meta    *QueryMeta      `json:"meta,omitempty"`
+	Records json.RawMessage `json:"records"`
+}
+
+// QueryMeta is the paging metadata of QueryResult.
+type QueryMeta struct {
+	FetchedRecordsCount int32  `json:"fetched_records_count,omitempty"`
+	Bookmark            string `json:"bookmark,omitempty"`
+}
+
+// ParseQueryResult _
+func ParseQueryResult(payload []byte) (*QueryResult, error) {
+	qr := &QueryResult{}
+	if err := json.Unmarshal(payload, qr); err != nil {
+		return nil, err
+	}
+	return qr, nil
+}
+
+// Bookmark returns the bookmark of the next page. It is empty if there is no metadata.
+func (qr *QueryResult) Bookmark() string {
+	if qr.Meta == nil {
+		return ""
+	}
+	return qr.Meta.Bookmark
+}
+
+// HasMore returns true if the next page may exist. (the page is full)
+func (qr *QueryResult) HasMore(fetchSize int) bool {
+	if qr.Meta == nil || len(qr.Meta.Bookmark) == 0 {
+		return false
+	}
+	if fetchSize < 1 {
+		fetchSize = DefaultFetchSize
+	}
+	return int(qr.Meta.FetchedRecordsCount) >= fetchSize
+}
+
+// Decode unmarshals the records. v is a pointer of slice. e.g. *[]*BalanceLog
+func (qr *QueryResult) Decode(v interface{}) error {
+	return json.Unmarshal(qr.Records, v)
+}
+
+// BalanceLogs _
+func (qr *QueryResult) BalanceLogs() ([]*BalanceLog, error) {
+	logs := []*BalanceLog{}
+	if err := qr.Decode(&logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Pays _
+func (qr *QueryResult) Pays() ([]*Pay, error) {
+	pays := []*Pay{}
+	if err := qr.Decode(&pays); err != nil {
+		return nil, err
+	}
+	return pays, nil
+}
+
+// PendingBalances _
+func (qr *QueryResult) PendingBalances() ([]*PendingBalance, error) {
+	pbs := []*PendingBalance{}
+	if err := qr.Decode(&pbs); err != nil {
+		return nil, err
+	}
+	return pbs, nil
+}
@@ -0,0 +1,605 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultFetchSize is the fetch size of the list queries if it is less than 1.
+	DefaultFetchSize = 20
+	// MaxFetchSize _
+	MaxFetchSize = 200
+	// MemoMaxLength is the max length of the memo. (longer memo is truncated by the chaincode)
+	MemoMaxLength = 1024
+)
+
+// Request is the typed request of a chaincode function.
+type Request interface {
+	Fn() string       // function name
+	Params() []string // positional params
+}
+
+// Args returns the chaincode args of the request. (function name is not included)
+func Args(req Request) [][]byte {
+	params := req.Params()
+	args := make([][]byte, len(params))
+	for i, p := range params {
+		args[i] = []byte(p)
+	}
+	return args
+}
+
+// FnArgs returns the chaincode args of the request including the function name. ([fn, params...])
+func FnArgs(req Request) [][]byte {
+	return append([][]byte{[]byte(req.Fn())}, Args(req)...)
+}
+
+// TokenGetRequest _
+type TokenGetRequest struct {
+	Code string
+}
+
+// Fn implements Request
+func (r *TokenGetRequest) Fn() string { return "token/get" }
+
+// Params implements Request
+func (r *TokenGetRequest) Params() []string {
+	return []string{r.Code}
+}
+
+// TokenAuditRequest _
+type TokenAuditRequest struct {
+	Code      string
+	Cursor    string // empty = the first page
+	FetchSize int    // if < 1, 200 (max 1000)
+}
+
+// Fn implements Request
+func (r *TokenAuditRequest) Fn() string { return "token/audit" }
+
+// Params implements Request
+func (r *TokenAuditRequest) Params() []string {
+	return trimParams([]string{r.Code, r.Cursor, strconv.Itoa(r.FetchSize)}, []string{2: "0"}, 1)
+}
+
+// NextPage returns the request of the next page of the audit. It returns nil if the audit is done.
+func (r *TokenAuditRequest) NextPage(audit *TokenAudit) *TokenAuditRequest {
+	if audit.Done {
+		return nil
+	}
+	next := *r
+	next.Cursor = audit.Cursor
+	return &next
+}
+
+// AccountGetRequest _
+type AccountGetRequest struct {
+	Address string // token code (PAOT) | account address
+}
+
+// Fn implements Request
+func (r *AccountGetRequest) Fn() string { return "account/get" }
+
+// Params implements Request
+func (r *AccountGetRequest) Params() []string {
+	return []string{r.Address}
+}
+
+// TransferRequest _
+type TransferRequest struct {
+	Sender      string // empty = PAOT
+	Receiver    string
+	Amount      *Amount
+	Memo        string
+	OrderID     string
+	PendingTime *time.Time    // optional. pending balance is created if it is future
+	Expiry      time.Duration // multi-sig only
+	FeeBearer   string        // "sender" | "receiver" | "sponsor", empty is the default (pending time, multi-sig: sender only)
+	Sponsor     string        // fee bearer "sponsor" only
+	Signers     []string      // extra signers (personal account addresses). need Expiry.
+}
+
+// Fn implements Request
+func (r *TransferRequest) Fn() string { return "transfer" }
+
+// Params implements Request
+func (r *TransferRequest) Params() []string {
+	params := []string{r.Sender, r.Receiver, amountParam(r.Amount), r.Memo, r.OrderID, timeParam(r.PendingTime, "0"), durationParam(r.Expiry), r.FeeBearer, r.Sponsor}
+	if len(r.Signers) > 0 && len(params[6]) > 0 { // expiry
+		return append(params, r.Signers...)
+	}
+	return trimParams(params, []string{5: "0"}, 3)
+}
+
+// TransferLeg is a leg of TransferMultiRequest.
+type TransferLeg struct {
+	Sender    string  `json:"sender"` // empty = PAOT
+	Receiver  string  `json:"receiver"`
+	Amount    *Amount `json:"amount"`
+	FeeBearer string  `json:"fee_bearer,omitempty"` // "sender" | "receiver" | "sponsor", empty is the default
+	Sponsor   string  `json:"sponsor,omitempty"`    // fee bearer "sponsor" only
+}
+
+// TransferMultiRequest transfers all legs atomically. (response: TransferMultiResult)
+type TransferMultiRequest struct {
+	Legs    []*TransferLeg
+	OrderID string // shared by the balance logs
+	Memo    string
+}
+
+// Fn implements Request
+func (r *TransferMultiRequest) Fn() string { return "transfer/multi" }
+
+// Params implements Request
+func (r *TransferMultiRequest) Params() []string {
+	legs, _ := json.Marshal(r.Legs) // never fails
+	return trimParams([]string{string(legs), r.OrderID, r.Memo}, nil, 1)
+}
+
+// PayRequest _
+type PayRequest struct {
+	Sender    string // empty = PAOT
+	Receiver  string
+	Amount    *Amount
+	OrderID   string
+	Memo      string
+	Expiry    time.Duration // multi-sig only
+	FeeBearer string        // "sender" | "receiver" | "sponsor", empty is the default (multi-sig: receiver only)
+	Sponsor   string        // fee bearer "sponsor" only
+}
+
+// Fn implements Request
+func (r *PayRequest) Fn() string { return "pay" }
+
+// Params implements Request
+func (r *PayRequest) Params() []string {
+	params := []string{r.Sender, r.Receiver, amountParam(r.Amount), r.OrderID, r.Memo, durationParam(r.Expiry), r.FeeBearer, r.Sponsor}
+	return trimParams(params, nil, 3)
+}
+
+// PayRefundRequest _
+type PayRefundRequest struct {
+	PayID   string // original pay id
+	Amount  *Amount
+	Memo    string
+	OrderID string
+}
+
+// Fn implements Request
+func (r *PayRefundRequest) Fn() string { return "pay/refund" }
+
+// Params implements Request
+func (r *PayRefundRequest) Params() []string {
+	params := []string{r.PayID, amountParam(r.Amount), r.Memo, r.OrderID}
+	return trimParams(params, nil, 2)
+}
+
+// PayPrunePreviewRequest _
+type PayPrunePreviewRequest struct {
+	Address string // token code (PAOT) | account address
+	Safely  bool   // exclude the pays in the prune window of the token
+	EndTime *time.Time
+}
+
+// Fn implements Request
+func (r *PayPrunePreviewRequest) Fn() string { return "pay/prune/preview" }
+
+// Params implements Request
+func (r *PayPrunePreviewRequest) Params() []string {
+	return trimParams([]string{r.Address, strconv.FormatBool(r.Safely), timeParam(r.EndTime, "")}, nil, 2)
+}
+
+// FeePrunePreviewRequest _
+type FeePrunePreviewRequest struct {
+	Token   string
+	Safely  bool // exclude the fees in the prune window of the token
+	EndTime *time.Time
+}
+
+// Fn implements Request
+func (r *FeePrunePreviewRequest) Fn() string { return "fee/prune/preview" }
+
+// Params implements Request
+func (r *FeePrunePreviewRequest) Params() []string {
+	return trimParams([]string{r.Token, strconv.FormatBool(r.Safely), timeParam(r.EndTime, "")}, nil, 2)
+}
+
+// FeeSponsorRequest authorizes the sponsor to bear the fees of the account. (response: FeeSponsorship)
+type FeeSponsorRequest struct {
+	Sponsor string
+	Account string
+}
+
+// Fn implements Request
+func (r *FeeSponsorRequest) Fn() string { return "fee/sponsor" }
+
+// Params implements Request
+func (r *FeeSponsorRequest) Params() []string {
+	return []string{r.Sponsor, r.Account}
+}
+
+// FeeSponsorRevokeRequest _ (response: FeeSponsorship)
+type FeeSponsorRevokeRequest struct {
+	Sponsor string
+	Account string
+}
+
+// Fn implements Request
+func (r *FeeSponsorRevokeRequest) Fn() string { return "fee/sponsor/revoke" }
+
+// Params implements Request
+func (r *FeeSponsorRevokeRequest) Params() []string {
+	return []string{r.Sponsor, r.Account}
+}
+
+// FeeSponsorGetRequest _ (response: FeeSponsorship)
+type FeeSponsorGetRequest struct {
+	Sponsor string
+	Account string
+}
+
+// Fn implements Request
+func (r *FeeSponsorGetRequest) Fn() string { return "fee/sponsor/get" }
+
+// Params implements Request
+func (r *FeeSponsorGetRequest) Params() []string {
+	return []string{r.Sponsor, r.Account}
+}
+
+// PayListRequest _
+type PayListRequest struct {
+	Address   string // token code (PAOT) | account address
+	Ascending bool   // default is descending order
+	Bookmark  string
+	FetchSize int // if < 1, DefaultFetchSize (max MaxFetchSize)
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// Fn implements Request
+func (r *PayListRequest) Fn() string { return "pay/list" }
+
+// Params implements Request
+func (r *PayListRequest) Params() []string {
+	sortOrder := "desc"
+	if r.Ascending {
+		sortOrder = "asc"
+	}
+	params := []string{r.Address, sortOrder, r.Bookmark, strconv.Itoa(r.FetchSize), timeParam(r.StartTime, ""), timeParam(r.EndTime, "")}
+	return trimParams(params, []string{1: "desc", 3: "0"}, 1)
+}
+
+// WrapGetRequest _
+type WrapGetRequest struct {
+	ID string // wrap tx id
+}
+
+// Fn implements Request
+func (r *WrapGetRequest) Fn() string { return "wrap/get" }
+
+// Params implements Request
+func (r *WrapGetRequest) Params() []string {
+	return []string{r.ID}
+}
+
+// WrapRefundRequest _
+type WrapRefundRequest struct {
+	ID string // wrap tx id
+}
+
+// Fn implements Request
+func (r *WrapRefundRequest) Fn() string { return "wrap/refund" }
+
+// Params implements Request
+func (r *WrapRefundRequest) Params() []string {
+	return []string{r.ID}
+}
+
+// WrapListRequest _
+type WrapListRequest struct {
+	Token     string
+	ExtCode   string
+	Sender    string
+	Status    string // "requested" | "completed" | "impossible" | "refunded", empty is all
+	Bookmark  string
+	FetchSize int // if < 1, DefaultFetchSize (max MaxFetchSize)
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// Fn implements Request
+func (r *WrapListRequest) Fn() string { return "wrap/list" }
+
+// Params implements Request
+func (r *WrapListRequest) Params() []string {
+	params := []string{r.Token, r.ExtCode, r.Sender, r.Status, r.Bookmark, strconv.Itoa(r.FetchSize), timeParam(r.StartTime, ""), timeParam(r.EndTime, "")}
+	return trimParams(params, []string{5: "0"}, 1)
+}
+
+// NextPage returns the request of the next page of the result.
+func (r *WrapListRequest) NextPage(qr *QueryResult) *WrapListRequest {
+	next := *r
+	next.Bookmark = qr.Bookmark()
+	return &next
+}
+
+// UnwrapGetRequest _
+type UnwrapGetRequest struct {
+	ExtTxID string
+	Token   string // optional. with ExtCode, the tx id is normalized by the external chain
+	ExtCode string
+}
+
+// Fn implements Request
+func (r *UnwrapGetRequest) Fn() string { return "unwrap/get" }
+
+// Params implements Request
+func (r *UnwrapGetRequest) Params() []string {
+	return trimParams([]string{r.ExtTxID, r.Token, r.ExtCode}, nil, 1)
+}
+
+// UnwrapAttestRequest _
+type UnwrapAttestRequest struct {
+	Receiver string // receiver address | token code (impossible unwrap)
+	ExtCode  string
+	ExtID    string
+	ExtTxID  string
+	Amount   *Amount
+}
+
+// Fn implements Request
+func (r *UnwrapAttestRequest) Fn() string { return "unwrap/attest" }
+
+// Params implements Request
+func (r *UnwrapAttestRequest) Params() []string {
+	return []string{r.Receiver, r.ExtCode, r.ExtID, r.ExtTxID, amountParam(r.Amount)}
+}
+
+// WrapCompleteItem _
+type WrapCompleteItem struct {
+	WrapID  string  `json:"wrap_id"`
+	Fee     *Amount `json:"fee,omitempty"`
+	ExtTxID string  `json:"ext_tx_id,omitempty"` // empty = impossible wrap
+}
+
+// WrapCompleteBatchRequest completes the wraps in a transaction. (response: WrapBatchResult)
+type WrapCompleteBatchRequest struct {
+	Items []*WrapCompleteItem // max 50
+}
+
+// Fn implements Request
+func (r *WrapCompleteBatchRequest) Fn() string { return "wrap/complete/batch" }
+
+// Params implements Request
+func (r *WrapCompleteBatchRequest) Params() []string {
+	items, _ := json.Marshal(r.Items) // never fails
+	return []string{string(items)}
+}
+
+// UnwrapItem _
+type UnwrapItem struct {
+	Receiver string  `json:"receiver,omitempty"` // empty = impossible unwrap
+	ExtID    string  `json:"ext_id"`
+	ExtTxID  string  `json:"ext_tx_id"`
+	Amount   *Amount `json:"amount"`
+}
+
+// UnwrapBatchRequest unwraps the items in a transaction. (response: WrapBatchResult)
+type UnwrapBatchRequest struct {
+	Token   string
+	ExtCode string
+	Items   []*UnwrapItem // max 50
+}
+
+// Fn implements Request
+func (r *UnwrapBatchRequest) Fn() string { return "unwrap/batch" }
+
+// Params implements Request
+func (r *UnwrapBatchRequest) Params() []string {
+	items, _ := json.Marshal(r.Items) // never fails
+	return []string{r.Token, r.ExtCode, string(items)}
+}
+
+// UnwrapAttestationGetRequest _
+type UnwrapAttestationGetRequest struct {
+	ExtTxID string
+	Token   string // optional. with ExtCode, the tx id is normalized by the external chain
+	ExtCode string
+}
+
+// Fn implements Request
+func (r *UnwrapAttestationGetRequest) Fn() string { return "unwrap/attestation/get" }
+
+// Params implements Request
+func (r *UnwrapAttestationGetRequest) Params() []string {
+	return trimParams([]string{r.ExtTxID, r.Token, r.ExtCode}, nil, 1)
+}
+
+// BridgeStatusRequest _
+type BridgeStatusRequest struct {
+	Token   string
+	ExtCode string // optional. all external token codes if it is empty
+}
+
+// Fn implements Request
+func (r *BridgeStatusRequest) Fn() string { return "bridge/status" }
+
+// Params implements Request
+func (r *BridgeStatusRequest) Params() []string {
+	return trimParams([]string{r.Token, r.ExtCode}, nil, 1)
+}
+
+// BalanceLogsRequest _
+type BalanceLogsRequest struct {
+	Address   string          // token code (PAOT) | account address
+	Type      *BalanceLogType // nil = all types
+	Bookmark  string
+	FetchSize int // if < 1, DefaultFetchSize (max MaxFetchSize)
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// Fn implements Request
+func (r *BalanceLogsRequest) Fn() string { return "balance/logs" }
+
+// Params implements Request
+func (r *BalanceLogsRequest) Params() []string {
+	typeStr := ""
+	if r.Type != nil {
+		typeStr = strconv.Itoa(int(*r.Type))
+	}
+	params := []string{r.Address, typeStr, r.Bookmark, strconv.Itoa(r.FetchSize), timeParam(r.StartTime, ""), timeParam(r.EndTime, "")}
+	return trimParams(params, []string{3: "0"}, 1)
+}
+
+// BalanceAtRequest _
+type BalanceAtRequest struct {
+	Address string // token code (PAOT) | account address
+	Time    time.Time
+}
+
+// Fn implements Request
+func (r *BalanceAtRequest) Fn() string { return "balance/at" }
+
+// Params implements Request
+func (r *BalanceAtRequest) Params() []string {
+	return []string{r.Address, timeParam(&r.Time, "")}
+}
+
+// BalanceHistoryRequest _
+type BalanceHistoryRequest struct {
+	Address   string // token code (PAOT) | account address
+	FetchSize int    // if < 1, MaxFetchSize
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// Fn implements Request
+func (r *BalanceHistoryRequest) Fn() string { return "balance/history" }
+
+// Params implements Request
+func (r *BalanceHistoryRequest) Params() []string {
+	params := []string{r.Address, strconv.Itoa(r.FetchSize), timeParam(r.StartTime, ""), timeParam(r.EndTime, "")}
+	return trimParams(params, []string{1: "0"}, 1)
+}
+
+// BalanceStatementRequest _
+type BalanceStatementRequest struct {
+	Address   string // token code (PAOT) | account address
+	StartTime time.Time
+	EndTime   time.Time
+	Bookmark  string
+	FetchSize int    // if < 1, DefaultFetchSize (max MaxFetchSize)
+	Format    string // "json"(default) | "csv"
+}
+
+// Fn implements Request
+func (r *BalanceStatementRequest) Fn() string { return "balance/statement" }
+
+// Params implements Request
+func (r *BalanceStatementRequest) Params() []string {
+	format := r.Format
+	if len(format) == 0 {
+		format = "json"
+	}
+	params := []string{r.Address, timeParam(&r.StartTime, ""), timeParam(&r.EndTime, ""), r.Bookmark, strconv.Itoa(r.FetchSize), format}
+	return trimParams(params, []string{4: "0", 5: "json"}, 3)
+}
+
+// BalanceLogsVerifyRequest _
+type BalanceLogsVerifyRequest struct {
+	Address   string // token code (PAOT) | account address
+	StartTime *time.Time
+	EndTime   *time.Time
+	FetchSize int // if < 1, 200 (max 1000)
+	Cursor    string
+}
+
+// Fn implements Request
+func (r *BalanceLogsVerifyRequest) Fn() string { return "balance/logs/verify" }
+
+// Params implements Request
+func (r *BalanceLogsVerifyRequest) Params() []string {
+	params := []string{r.Address, timeParam(r.StartTime, ""), timeParam(r.EndTime, ""), strconv.Itoa(r.FetchSize), r.Cursor}
+	return trimParams(params, []string{3: "0"}, 1)
+}
+
+// NextPage returns the request of the next logs. It returns nil if the chain is broken or there are no more logs.
+func (r *BalanceLogsVerifyRequest) NextPage(v *BalanceLogsVerification) *BalanceLogsVerifyRequest {
+	if !v.Valid || len(v.Cursor) == 0 {
+		return nil
+	}
+	next := *r
+	next.Cursor = v.Cursor
+	return &next
+}
+
+// NextPage returns the request of the next page of the statement.
+func (r *BalanceStatementRequest) NextPage(st *BalanceStatement) *BalanceStatementRequest {
+	next := *r
+	next.Bookmark = ""
+	if st.Meta != nil {
+		next.Bookmark = st.Meta.Bookmark
+	}
+	return &next
+}
+
+// NextPage returns the request of the next page of the result.
+func (r *BalanceLogsRequest) NextPage(qr *QueryResult) *BalanceLogsRequest {
+	next := *r
+	next.Bookmark = qr.Bookmark()
+	return &next
+}
+
+// NextPage returns the request of the next page of the result.
+func (r *PayListRequest) NextPage(qr *QueryResult) *PayListRequest {
+	next := *r
+	next.Bookmark = qr.Bookmark()
+	return &next
+}
+
+// helpers
+
+func amountParam(a *Amount) string {
+	if a == nil {
+		return "0"
+	}
+	return a.String()
+}
+
+// timeParam returns int64 seconds string of the time.
+func timeParam(t *time.Time, def string) string {
+	if t == nil {
+		return def
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// durationParam returns int64 seconds string of the duration. (0 = empty)
+func durationParam(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+// trimParams cuts the trailing optional params that are empty or equal to their defaults.
+// Params before the 'required' index are never cut.
+func trimParams(params, defaults []string, required int) []string {
+	n := len(params)
+	for n > required {
+		def := ""
+		if n-1 < len(defaults) {
+			def = defaults[n-1]
+		}
+		if params[n-1] != def {
+			break
+		}
+		n--
+	}
+	return params[:n]
+}
@@ -0,0 +1,176 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package client
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// loadArgSchemas parses the named argument schemas of the chaincode (../args.go),
+// and returns the argument names of the routes in the positional order.
+func loadArgSchemas(t *testing.T) map[string][]string {
+	f, err := parser.ParseFile(token.NewFileSet(), "../args.go", nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the chaincode args: %s", err)
+	}
+	schemas := map[string][]string{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		vs, ok := n.(*ast.ValueSpec)
+		if !ok || len(vs.Names) != 1 || vs.Names[0].Name != "argSchemas" {
+			return true
+		}
+		for _, e := range vs.Values[0].(*ast.CompositeLit).Elts {
+			kv := e.(*ast.KeyValueExpr)
+			fn, _ := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+			names := []string{}
+			for _, se := range kv.Value.(*ast.CompositeLit).Elts {
+				for _, fe := range se.(*ast.CompositeLit).Elts {
+					field := fe.(*ast.KeyValueExpr)
+					if field.Key.(*ast.Ident).Name == "Name" {
+						name, _ := strconv.Unquote(field.Value.(*ast.BasicLit).Value)
+						names = append(names, name)
+					}
+				}
+			}
+			schemas[fn] = names
+		}
+		return false
+	})
+	if len(schemas) == 0 {
+		t.Fatal("argSchemas is not found in the chaincode args")
+	}
+	return schemas
+}
+
+func TestRequestParams(t *testing.T) {
+	schemas := loadArgSchemas(t)
+	amount := NewAmountWithInt64(100)
+	stime := time.Unix(1500000000, 0)
+	etime := time.Unix(1600000000, 0)
+	logType := BalanceLogTypeWrap
+	cases := []struct {
+		req  Request
+		want map[string]string // argument name -> param
+	}{
+		{&TokenGetRequest{Code: "KNT"}, map[string]string{"code": "KNT"}},
+		{&TokenAuditRequest{Code: "KNT", Cursor: "c", FetchSize: 10}, map[string]string{"code": "KNT", "cursor": "c", "fetch_size": "10"}},
+		{&AccountGetRequest{Address: "KNT"}, map[string]string{"address": "KNT"}},
+		{&TransferRequest{Sender: "S", Receiver: "R", Amount: amount, Memo: "m", OrderID: "o", PendingTime: &stime, Expiry: time.Hour, FeeBearer: "sponsor", Sponsor: "P"},
+			map[string]string{"sender": "S", "receiver": "R", "amount": "100", "memo": "m", "order_id": "o", "pending_time": "1500000000", "expiry": "3600", "fee_bearer": "sponsor", "sponsor": "P"}},
+		{&TransferMultiRequest{Legs: []*TransferLeg{}, OrderID: "o", Memo: "m"}, map[string]string{"legs": "[]", "order_id": "o", "memo": "m"}},
+		{&PayRequest{Sender: "S", Receiver: "R", Amount: amount, OrderID: "o", Memo: "m", Expiry: time.Hour, FeeBearer: "sponsor", Sponsor: "P"},
+			map[string]string{"sender": "S", "receiver": "R", "amount": "100", "order_id": "o", "memo": "m", "expiry": "3600", "fee_bearer": "sponsor", "sponsor": "P"}},
+		{&PayRefundRequest{PayID: "p", Amount: amount, Memo: "m", OrderID: "o"}, map[string]string{"pay_id": "p", "amount": "100", "memo": "m", "order_id": "o"}},
+		{&PayPrunePreviewRequest{Address: "A", Safely: true, EndTime: &etime}, map[string]string{"address": "A", "safely": "true", "end_time": "1600000000"}},
+		{&FeePrunePreviewRequest{Token: "KNT", Safely: true, EndTime: &etime}, map[string]string{"code": "KNT", "safely": "true", "end_time": "1600000000"}},
+		{&FeeSponsorRequest{Sponsor: "P", Account: "A"}, map[string]string{"sponsor": "P", "account": "A"}},
+		{&FeeSponsorRevokeRequest{Sponsor: "P", Account: "A"}, map[string]string{"sponsor": "P", "account": "A"}},
+		{&FeeSponsorGetRequest{Sponsor: "P", Account: "A"}, map[string]string{"sponsor": "P", "account": "A"}},
+		{&PayListRequest{Address: "A", Ascending: true, Bookmark: "b", FetchSize: 10, StartTime: &stime, EndTime: &etime},
+			map[string]string{"address": "A", "sort_order": "asc", "bookmark": "b", "fetch_size": "10", "start_time": "1500000000", "end_time": "1600000000"}},
+		{&WrapGetRequest{ID: "w"}, map[string]string{"wrap_id": "w"}},
+		{&WrapRefundRequest{ID: "w"}, map[string]string{"wrap_id": "w"}},
+		{&WrapListRequest{Token: "KNT", ExtCode: "WKNT", Sender: "S", Status: "completed", Bookmark: "b", FetchSize: 10, StartTime: &stime, EndTime: &etime},
+			map[string]string{"token": "KNT", "ext_code": "WKNT", "sender": "S", "status": "completed", "bookmark": "b", "fetch_size": "10", "start_time": "1500000000", "end_time": "1600000000"}},
+		{&UnwrapGetRequest{ExtTxID: "x", Token: "KNT", ExtCode: "WKNT"}, map[string]string{"ext_tx_id": "x", "token": "KNT", "ext_code": "WKNT"}},
+		{&UnwrapAttestRequest{Receiver: "R", ExtCode: "WKNT", ExtID: "e", ExtTxID: "x", Amount: amount},
+			map[string]string{"receiver": "R", "ext_code": "WKNT", "ext_id": "e", "ext_tx_id": "x", "amount": "100"}},
+		{&WrapCompleteBatchRequest{Items: []*WrapCompleteItem{}}, map[string]string{"items": "[]"}},
+		{&UnwrapBatchRequest{Token: "KNT", ExtCode: "WKNT", Items: []*UnwrapItem{}}, map[string]string{"token": "KNT", "ext_code": "WKNT", "items": "[]"}},
+		{&UnwrapAttestationGetRequest{ExtTxID: "x", Token: "KNT", ExtCode: "WKNT"}, map[string]string{"ext_tx_id": "x", "token": "KNT", "ext_code": "WKNT"}},
+		{&BridgeStatusRequest{Token: "KNT", ExtCode: "WKNT"}, map[string]string{"token": "KNT", "ext_code": "WKNT"}},
+		{&BalanceLogsRequest{Address: "A", Type: &logType, Bookmark: "b", FetchSize: 10, StartTime: &stime, EndTime: &etime},
+			map[string]string{"address": "A", "type": strconv.Itoa(int(logType)), "bookmark": "b", "fetch_size": "10", "start_time": "1500000000", "end_time": "1600000000"}},
+		{&BalanceAtRequest{Address: "A", Time: stime}, map[string]string{"address": "A", "time": "1500000000"}},
+		{&BalanceHistoryRequest{Address: "A", FetchSize: 10, StartTime: &stime, EndTime: &etime},
+			map[string]string{"address": "A", "fetch_size": "10", "start_time": "1500000000", "end_time": "1600000000"}},
+		{&BalanceStatementRequest{Address: "A", StartTime: stime, EndTime: etime, Bookmark: "b", FetchSize: 10, Format: "csv"},
+			map[string]string{"address": "A", "start_time": "1500000000", "end_time": "1600000000", "bookmark": "b", "fetch_size": "10", "format": "csv"}},
+		{&BalanceLogsVerifyRequest{Address: "A", StartTime: &stime, EndTime: &etime, FetchSize: 10, Cursor: "c"},
+			map[string]string{"address": "A", "start_time": "1500000000", "end_time": "1600000000", "fetch_size": "10", "cursor": "c"}},
+	}
+	for _, c := range cases {
+		fn := c.req.Fn()
+		names, ok := schemas[fn]
+		if !ok {
+			t.Errorf("%s: no argument schema of the chaincode", fn)
+			continue
+		}
+		params := c.req.Params()
+		if len(params) != len(c.want) {
+			t.Errorf("%s: %d params, want %d", fn, len(params), len(c.want))
+		}
+		for i, name := range names {
+			want, ok := c.want[name]
+			if !ok {
+				continue
+			}
+			if i >= len(params) {
+				t.Errorf("%s: %s is missing", fn, name)
+			} else if params[i] != want {
+				t.Errorf("%s: params[%d] (%s) = %q, want %q", fn, i, name, params[i], want)
+			}
+		}
+	}
+}
+
+func TestRequestParamsTrimmed(t *testing.T) {
+	amount := NewAmountWithInt64(100)
+	cases := []struct {
+		req  Request
+		want []string
+	}{
+		{&TokenAuditRequest{Code: "KNT"}, []string{"KNT"}},
+		{&TransferRequest{Receiver: "R", Amount: amount}, []string{"", "R", "100"}},
+		{&TransferRequest{Receiver: "R", Amount: amount, Sponsor: "P"}, []string{"", "R", "100", "", "", "0", "", "", "P"}},
+		{&PayRequest{Receiver: "R", Amount: amount, Memo: "m"}, []string{"", "R", "100", "", "m"}},
+		{&PayListRequest{Address: "A", Bookmark: "b"}, []string{"A", "desc", "b"}},
+		{&WrapListRequest{Token: "KNT", Status: "requested"}, []string{"KNT", "", "", "requested"}},
+		{&BalanceStatementRequest{Address: "A", StartTime: time.Unix(1, 0), EndTime: time.Unix(2, 0)}, []string{"A", "1", "2"}},
+		{&BridgeStatusRequest{Token: "KNT"}, []string{"KNT"}},
+	}
+	for _, c := range cases {
+		if params := c.req.Params(); !reflect.DeepEqual(params, c.want) {
+			t.Errorf("%s: params = %q, want %q", c.req.Fn(), params, c.want)
+		}
+	}
+}
+
+func TestTransferRequestSigners(t *testing.T) {
+	schemas := loadArgSchemas(t)
+	names := schemas["transfer"]
+	if names[len(names)-1] != "signers" {
+		t.Fatalf("the last argument of transfer is %s, want signers", names[len(names)-1])
+	}
+	amount := NewAmountWithInt64(100)
+
+	// the signers follow the fixed params
+	req := &TransferRequest{Receiver: "R", Amount: amount, Expiry: time.Minute, Signers: []string{"S1", "S2"}}
+	want := []string{"", "R", "100", "", "", "0", "60", "", "", "S1", "S2"}
+	if params := req.Params(); !reflect.DeepEqual(params, want) {
+		t.Errorf("params = %q, want %q", params, want)
+	}
+	if len(want)-len(req.Signers) != len(names)-1 {
+		t.Errorf("the signers start at %d, want %d", len(want)-len(req.Signers), len(names)-1)
+	}
+
+	// the signers need the expiry
+	req = &TransferRequest{Receiver: "R", Amount: amount, Signers: []string{"S1"}}
+	if params := req.Params(); !reflect.DeepEqual(params, []string{"", "R", "100"}) {
+		t.Errorf("params without expiry = %q", params)
+	}
+}
+
+func TestFnArgs(t *testing.T) {
+	args := FnArgs(&BalanceAtRequest{Address: "A", Time: time.Unix(1, 0)})
+	want := [][]byte{[]byte("balance/at"), []byte("A"), []byte("1")}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("FnArgs = %q, want %q", args, want)
+	}
+}
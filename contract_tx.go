@@ -21,6 +21,8 @@ var ctrRoutes = map[string][]CtrFunc{
 	"account/create":        []CtrFunc{contractVoid, executeAccountCreate},
 	"account/holder/add":    []CtrFunc{contractVoid, executeAccountHolderAdd},
 	"account/holder/remove": []CtrFunc{contractVoid, executeAccountHolderRemove},
+	"conversion/register":   []CtrFunc{contractVoid, executeConversionRegister},
+	"conversion/update":     []CtrFunc{contractVoid, executeConversionUpdate},
 	"pay":                   []CtrFunc{cancelTransfer, executePay},
 	"token/burn":            []CtrFunc{contractVoid, executeTokenBurn},
 	"token/create":          []CtrFunc{contractVoid, executeTokenCreate},
@@ -34,21 +36,21 @@ var ctrRoutes = map[string][]CtrFunc{
 // params[1] : contract document
 func contractCallback(stub shim.ChaincodeStubInterface, fnIdx int, params []string) peer.Response {
 	if len(params) != 2 {
-		return shim.Error("incorrect number of parameters. expecting 2")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2")
 	}
 
 	ccid, err := ccid.GetID(stub)
 	if err != nil {
-		return shim.Error("failed to get ccid")
+		return responseError(err, "failed to get ccid")
 	}
 	if "kiesnet-contract" != ccid && "kiesnet-cc-contract" != ccid {
-		return shim.Error("invalid access")
+		return responseErrorCode(ErrorCodeInvalidAccess, "invalid access")
 	}
 
 	// authentication
 	_, err = kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	cid := params[0] // contract ID
@@ -61,7 +63,7 @@ func contractCallback(stub shim.ChaincodeStubInterface, fnIdx int, params []stri
 	if ctrFn := ctrRoutes[dtype][fnIdx]; ctrFn != nil {
 		return ctrFn(stub, cid, doc)
 	}
-	return shim.Error("unknown contract: [" + dtype + "]")
+	return responseErrorCode(ErrorCodeUnknownFunction, "unknown contract: ["+dtype+"]")
 }
 
 func contractCancel(stub shim.ChaincodeStubInterface, params []string) peer.Response {
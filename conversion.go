@@ -0,0 +1,143 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// ConversionLogsFetchSize _
+const ConversionLogsFetchSize = 20
+
+// ConversionMode is how the source token is collected.
+type ConversionMode int8
+
+const (
+	// ConversionModeTransfer transfers the source token to the genesis account of the source token
+	ConversionModeTransfer ConversionMode = iota
+	// ConversionModeBurn burns the source token (the supply decreases)
+	ConversionModeBurn
+)
+
+// ParseConversionMode _
+func ParseConversionMode(mode string) (ConversionMode, error) {
+	switch strings.ToLower(mode) {
+	case "", "transfer":
+		return ConversionModeTransfer, nil
+	case "burn":
+		return ConversionModeBurn, nil
+	}
+	return 0, InvalidParameterError{reason: "invalid mode. expecting 'transfer' or 'burn'"}
+}
+
+// ConversionID returns the ID of the conversion pair. ("SOURCE>TARGET")
+func ConversionID(source, target string) string {
+	return source + ">" + target
+}
+
+// ParseConversionRate validates the rate. (target amount per 1 source amount, both are big int amounts)
+func ParseConversionRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return nil, InvalidParameterError{reason: "invalid rate. must be greater than 0"}
+	}
+	return r, nil
+}
+
+// Conversion is the issuer-configured conversion pair.
+// The target token is released from the reserve account at the fixed rate.
+type Conversion struct {
+	DOCTYPEID      string         `json:"@conversion"` // "SOURCE>TARGET"
+	Source         string         `json:"source"`      // source token code
+	Target         string         `json:"target"`      // target token code
+	Rate           string         `json:"rate"`        // target amount per 1 source amount (big int amounts, rational)
+	Mode           ConversionMode `json:"mode"`
+	ReserveAccount string         `json:"reserve_account"`  // target token account which releases the target token
+	MinAmount      Amount         `json:"min_amount"`       // min source amount per convert
+	MaxAmount      Amount         `json:"max_amount"`       // max source amount per convert, 0 = unlimited
+	Paused         bool           `json:"paused"`           // convert is not available
+	LastContractID string         `json:"last_contract_id"` // the contract of the last registration/update
+	TotalSource    Amount         `json:"total_source"`     // accumulated source amount
+	TotalTarget    Amount         `json:"total_target"`     // accumulated target amount
+	CreatedTime    *txtime.Time   `json:"created_time,omitempty"`
+	UpdatedTime    *txtime.Time   `json:"updated_time,omitempty"`
+}
+
+// GetID implements Identifiable
+func (c *Conversion) GetID() string {
+	return c.DOCTYPEID
+}
+
+// TargetAmount returns the target amount of the source amount. (floor)
+func (c *Conversion) TargetAmount(amount *Amount) *Amount {
+	rate, _ := ParseConversionRate(c.Rate) // validated
+	return amount.Copy().MulRat(rate)
+}
+
+// ValidateAmount checks the limits.
+func (c *Conversion) ValidateAmount(amount *Amount) error {
+	if amount.Cmp(&c.MinAmount) < 0 {
+		return InvalidAmountError{reason: "less than the min amount " + c.MinAmount.String()}
+	}
+	if c.MaxAmount.Sign() > 0 && amount.Cmp(&c.MaxAmount) > 0 {
+		return InvalidAmountError{reason: "greater than the max amount " + c.MaxAmount.String()}
+	}
+	return nil
+}
+
+// ConversionLogType _
+type ConversionLogType int8
+
+const (
+	// ConversionLogTypeRegister _
+	ConversionLogTypeRegister ConversionLogType = iota
+	// ConversionLogTypeUpdate rate, limits or paused is changed
+	ConversionLogTypeUpdate
+)
+
+// ConversionLog is the audit log of the conversion settings.
+type ConversionLog struct {
+	DOCTYPEID      string            `json:"@conversion_log"` // conversion ID
+	Type           ConversionLogType `json:"type"`
+	ContractID     string            `json:"contract_id"`
+	Rate           string            `json:"rate"`
+	Mode           ConversionMode    `json:"mode"`
+	ReserveAccount string            `json:"reserve_account"`
+	MinAmount      Amount            `json:"min_amount"`
+	MaxAmount      Amount            `json:"max_amount"`
+	Paused         bool              `json:"paused"`
+	CreatedTime    *txtime.Time      `json:"created_time,omitempty"`
+}
+
+// NewConversionLog creates the log of the current settings.
+func NewConversionLog(c *Conversion, logType ConversionLogType) *ConversionLog {
+	return &ConversionLog{
+		DOCTYPEID:      c.DOCTYPEID,
+		Type:           logType,
+		ContractID:     c.LastContractID,
+		Rate:           c.Rate,
+		Mode:           c.Mode,
+		ReserveAccount: c.ReserveAccount,
+		MinAmount:      c.MinAmount,
+		MaxAmount:      c.MaxAmount,
+		Paused:         c.Paused,
+		CreatedTime:    c.UpdatedTime,
+	}
+}
+
+// ConversionState is the response payload of conversion/get.
+type ConversionState struct {
+	*Conversion
+	ReserveBalance *Amount `json:"reserve_balance"`
+}
+
+// ConversionResult is the response payload of convert.
+type ConversionResult struct {
+	Conversion   *Conversion   `json:"conversion"`
+	SourceAmount *Amount       `json:"source_amount"`
+	TargetAmount *Amount       `json:"target_amount"`
+	BalanceLogs  []*BalanceLog `json:"balance_logs"`
+}
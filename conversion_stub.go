@@ -0,0 +1,170 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// ConversionStub _
+type ConversionStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewConversionStub _
+func NewConversionStub(stub shim.ChaincodeStubInterface) *ConversionStub {
+	return &ConversionStub{stub}
+}
+
+// CreateKey _
+func (cb *ConversionStub) CreateKey(id string) string {
+	return "CONV_" + id
+}
+
+// CreateLogKey _
+func (cb *ConversionStub) CreateLogKey(id string, seq int64) string {
+	return fmt.Sprintf("CONVLOG_%s_%d", id, seq)
+}
+
+// GetConversion _
+func (cb *ConversionStub) GetConversion(id string) (*Conversion, error) {
+	data, err := cb.stub.GetState(cb.CreateKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the conversion state")
+	}
+	if data == nil {
+		return nil, NotExistedConversionError{}
+	}
+	con := &Conversion{}
+	if err = json.Unmarshal(data, con); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the conversion")
+	}
+	return con, nil
+}
+
+// PutConversion _
+func (cb *ConversionStub) PutConversion(con *Conversion) error {
+	data, err := json.Marshal(con)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the conversion")
+	}
+	if err = cb.stub.PutState(cb.CreateKey(con.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the conversion state")
+	}
+	return nil
+}
+
+// PutConversionLog _
+func (cb *ConversionStub) PutConversionLog(log *ConversionLog) error {
+	data, err := json.Marshal(log)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the conversion log")
+	}
+	if err = cb.stub.PutState(cb.CreateLogKey(log.DOCTYPEID, log.CreatedTime.UnixNano()), data); err != nil {
+		return errors.Wrap(err, "failed to put the conversion log state")
+	}
+	return nil
+}
+
+// GetQueryConversionLogs _
+func (cb *ConversionStub) GetQueryConversionLogs(id, bookmark string, fetchSize int) (*QueryResult, error) {
+	if fetchSize < 1 {
+		fetchSize = ConversionLogsFetchSize
+	}
+	if fetchSize > 200 {
+		fetchSize = 200
+	}
+	query := CreateQueryConversionLogsByID(id)
+	iter, meta, err := cb.stub.GetQueryResultWithPagination(query, int32(fetchSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	return NewQueryResult(meta, iter)
+}
+
+// Register puts the new conversion and its log.
+// It does not validate the conversion!
+func (cb *ConversionStub) Register(con *Conversion) error {
+	ts, err := txtime.GetTime(cb.stub)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the timestamp")
+	}
+	con.CreatedTime = ts
+	con.UpdatedTime = ts
+	if err = cb.PutConversion(con); err != nil {
+		return err
+	}
+	return cb.PutConversionLog(NewConversionLog(con, ConversionLogTypeRegister))
+}
+
+// Update puts the updated conversion and its log.
+// It does not validate the conversion!
+func (cb *ConversionStub) Update(con *Conversion) error {
+	ts, err := txtime.GetTime(cb.stub)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the timestamp")
+	}
+	con.UpdatedTime = ts
+	if err = cb.PutConversion(con); err != nil {
+		return err
+	}
+	return cb.PutConversionLog(NewConversionLog(con, ConversionLogTypeUpdate))
+}
+
+// Convert collects the source amount of the converter (transfer or burn) and releases the target amount from the reserve.
+// It does not validate the parameters!
+func (cb *ConversionStub) Convert(con *Conversion, source *Token, sBal, tBal *Balance, amount, target *Amount, memo string) (*ConversionResult, error) {
+	ts, err := txtime.GetTime(cb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	s := newBalanceSettlement(cb.stub)
+	s.memo = memo
+	s.cache(sBal)
+	s.cache(tBal)
+	s.add(sBal.DOCTYPEID, amount.Copy().Neg(), nil)
+	if con.Mode == ConversionModeBurn {
+		source.Supply.Add(amount.Copy().Neg())
+		source.UpdatedTime = ts
+		tb := NewTokenStub(cb.stub)
+		if err = tb.PutToken(source); err != nil {
+			return nil, errors.Wrap(err, "failed to update the token")
+		}
+		// the burned amount is not logged on the genesis account, so the supply history needs the log
+		if err = tb.PutSupplyLog(&SupplyLog{
+			DOCTYPEID:   source.DOCTYPEID,
+			RID:         con.DOCTYPEID,
+			Diff:        *amount.Copy().Neg(),
+			Supply:      source.Supply,
+			CreatedTime: ts,
+		}); err != nil {
+			return nil, err
+		}
+	} else {
+		s.add(source.GenesisAccount, amount, nil)
+	}
+	s.add(con.ReserveAccount, target.Copy().Neg(), nil)
+	s.add(tBal.DOCTYPEID, target, nil)
+
+	logs, err := s.apply(BalanceLogTypeConvert, con.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	con.TotalSource.Add(amount)
+	con.TotalTarget.Add(target)
+	con.UpdatedTime = ts
+	if err = cb.PutConversion(con); err != nil {
+		return nil, err
+	}
+
+	return &ConversionResult{Conversion: con, SourceAmount: amount, TargetAmount: target, BalanceLogs: logs}, nil
+}
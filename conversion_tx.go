@@ -0,0 +1,442 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/key-inside/kiesnet-ccpkg/kid"
+	"github.com/key-inside/kiesnet-ccpkg/stringset"
+)
+
+// The conversion settings are approved by the contract between the holders of
+// both genesis accounts and the holders of the reserve account.
+
+// params[0] : source token code
+// params[1] : target token code
+// params[2] : rate (target amount per 1 source amount, big int amounts, e.g. "1/100", "0.25")
+// params[3] : reserve account address (target token account)
+// params[4] : optional. mode ("transfer" | "burn", default "transfer")
+// params[5] : optional. min source amount per convert (big int string | decimal-formatted string)
+// params[6] : optional. max source amount per convert (0 = unlimited)
+func conversionRegister(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 4 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 4+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	source, target, err := ParseDexMarket(params[0] + "/" + params[1])
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid token codes")
+	}
+	id := ConversionID(source, target)
+	if _, err = NewConversionStub(stub).GetConversion(id); err == nil {
+		return responseErrorCode(ErrorCodeInvalidState, "already registered conversion")
+	} else if _, ok := err.(NotExistedConversionError); !ok {
+		return responseError(err, "failed to get the conversion")
+	}
+
+	con := &Conversion{DOCTYPEID: id, Source: source, Target: target, Rate: params[2]}
+	if _, err = ParseConversionRate(con.Rate); err != nil {
+		return responseError(err, "")
+	}
+	if len(params) > 4 {
+		if con.Mode, err = ParseConversionMode(params[4]); err != nil {
+			return responseError(err, "")
+		}
+	}
+	limits := []string{}
+	if len(params) > 5 {
+		limits = params[5:]
+	}
+	if err = setConversionLimits(stub, con, limits); err != nil {
+		return responseError(err, "")
+	}
+
+	// reserve account
+	addr, err := ParseAddress(params[3])
+	if err != nil {
+		return responseError(err, "failed to parse the reserve account address")
+	}
+	if addr.Code != target {
+		return responseErrorCode(ErrorCodeInvalidParameter, "the reserve account must be the target token account")
+	}
+	con.ReserveAccount = addr.String()
+
+	signers, err := getConversionSigners(stub, con)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if !signers.Contains(kid) {
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
+	}
+
+	doc := []interface{}{"conversion/register", source, target, con.Rate, strconv.Itoa(int(con.Mode)), con.ReserveAccount, con.MinAmount.String(), con.MaxAmount.String()}
+	return invokeContract(stub, doc, signers)
+}
+
+// params[0] : conversion id ("SOURCE>TARGET")
+// params[1] : rate
+// params[2] : optional. min source amount per convert
+// params[3] : optional. max source amount per convert (0 = unlimited)
+// params[4] : optional. paused ("true" | "false", default "false")
+func conversionUpdate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	con, err := NewConversionStub(stub).GetConversion(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the conversion")
+	}
+	con.Rate = params[1]
+	if _, err = ParseConversionRate(con.Rate); err != nil {
+		return responseError(err, "")
+	}
+	limits := params[2:]
+	if len(limits) > 2 {
+		limits = limits[:2]
+	}
+	con.MinAmount, con.MaxAmount = *ZeroAmount(), *ZeroAmount()
+	if err = setConversionLimits(stub, con, limits); err != nil {
+		return responseError(err, "")
+	}
+	paused := false
+	if len(params) > 4 && len(params[4]) > 0 {
+		if paused, err = strconv.ParseBool(params[4]); err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid paused. expecting 'true' or 'false'")
+		}
+	}
+
+	signers, err := getConversionSigners(stub, con)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if !signers.Contains(kid) {
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
+	}
+
+	doc := []interface{}{"conversion/update", con.DOCTYPEID, con.Rate, con.MinAmount.String(), con.MaxAmount.String(), strconv.FormatBool(paused)}
+	return invokeContract(stub, doc, signers)
+}
+
+// params[0] : conversion id ("SOURCE>TARGET")
+func conversionGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	con, err := NewConversionStub(stub).GetConversion(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the conversion")
+	}
+	bal, err := NewBalanceStub(stub).GetBalance(con.ReserveAccount)
+	if err != nil {
+		return responseError(err, "failed to get the reserve balance")
+	}
+
+	data, err := json.Marshal(&ConversionState{Conversion: con, ReserveBalance: &bal.Amount})
+	if err != nil {
+		return responseError(err, "failed to marshal the conversion")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : conversion id ("SOURCE>TARGET")
+// params[1] : optional. bookmark
+// params[2] : optional. fetch size (default 20, max 200)
+func conversionLogs(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	bookmark := ""
+	fetchSize := 0
+	if len(params) > 1 {
+		bookmark = params[1]
+		if len(params) > 2 {
+			fetchSize, err = strconv.Atoi(params[2])
+			if err != nil {
+				return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+			}
+		}
+	}
+
+	res, err := NewConversionStub(stub).GetQueryConversionLogs(params[0], bookmark, fetchSize)
+	if err != nil {
+		return responseError(err, "failed to get the conversion logs")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the conversion logs")
+	}
+
+	return shim.Success(data)
+}
+
+// Convert the source token of the invoker's PAOT to the target token of the invoker's PAOT.
+// params[0] : conversion id ("SOURCE>TARGET")
+// params[1] : source amount (big int string | decimal-formatted string)
+// params[2] : optional. memo (see MemoMaxLength)
+func convert(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	cb := NewConversionStub(stub)
+	con, err := cb.GetConversion(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the conversion")
+	}
+	if con.Paused {
+		return responseErrorCode(ErrorCodeInvalidState, "the conversion is paused")
+	}
+
+	source, err := NewTokenStub(stub).GetToken(con.Source)
+	if err != nil {
+		return responseError(err, "failed to get the source token")
+	}
+	amount, err := source.ParseAmount(params[1])
+	if err != nil {
+		return responseError(err, "")
+	}
+	if amount.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+	}
+	if err = con.ValidateAmount(amount); err != nil {
+		return responseError(err, "")
+	}
+	target := con.TargetAmount(amount)
+	if target.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "too small amount")
+	}
+
+	memo := ""
+	if len(params) > 2 {
+		memo = params[2]
+		if len(memo) > MemoMaxLength { // length limit
+			memo = memo[:MemoMaxLength]
+		}
+	}
+
+	// accounts
+	bb := NewBalanceStub(stub)
+	bals := make([]*Balance, 2)
+	for i, code := range []string{con.Source, con.Target} {
+		account, err := NewAccountStub(stub, code).GetAccount(NewAddress(code, AccountTypePersonal, kid))
+		if err != nil {
+			return responseError(err, "failed to get the account")
+		}
+		if account.IsSuspended() {
+			return responseErrorCode(ErrorCodeSuspendedAccount, "the account is suspended: "+account.GetID())
+		}
+		if bals[i], err = bb.GetBalance(account.GetID()); err != nil {
+			return responseError(err, "failed to get the balance")
+		}
+	}
+	if bals[0].Amount.Cmp(amount) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	// reserve
+	rAddr, _ := ParseAddress(con.ReserveAccount) // err is nil
+	reserve, err := NewAccountStub(stub, con.Target).GetAccount(rAddr)
+	if err != nil {
+		return responseError(err, "failed to get the reserve account")
+	}
+	if reserve.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the reserve account is suspended")
+	}
+	if reserve.GetID() != bals[1].DOCTYPEID {
+		rBal, err := bb.GetBalance(reserve.GetID())
+		if err != nil {
+			return responseError(err, "failed to get the reserve balance")
+		}
+		if rBal.Amount.Cmp(target) < 0 {
+			return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough reserve balance")
+		}
+	}
+
+	res, err := cb.Convert(con, source, bals[0], bals[1], amount, target, memo)
+	if err != nil {
+		return responseError(err, "failed to convert")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+
+	return shim.Success(data)
+}
+
+// helpers
+
+// setConversionLimits sets the min and max amounts. (params[0] : min, params[1] : max)
+func setConversionLimits(stub shim.ChaincodeStubInterface, con *Conversion, params []string) error {
+	tb := NewTokenStub(stub)
+	for i, p := range params {
+		if i > 1 || len(p) == 0 {
+			break
+		}
+		amount, err := tb.ParseAmount(con.Source, p)
+		if err != nil {
+			return err
+		}
+		if amount.Sign() < 0 {
+			return InvalidAmountError{reason: "invalid limit. must be greater than or equal to 0"}
+		}
+		if i == 0 {
+			con.MinAmount = *amount
+		} else {
+			con.MaxAmount = *amount
+		}
+	}
+	if con.MaxAmount.Sign() > 0 && con.MaxAmount.Cmp(&con.MinAmount) < 0 {
+		return InvalidAmountError{reason: "invalid limits. max amount is less than min amount"}
+	}
+	return nil
+}
+
+// getConversionSigners returns the holders of both genesis accounts and the reserve account.
+func getConversionSigners(stub shim.ChaincodeStubInterface, con *Conversion) (*stringset.Set, error) {
+	tb := NewTokenStub(stub)
+	signers := stringset.New()
+	for _, code := range []string{con.Source, con.Target} {
+		token, err := tb.GetToken(code)
+		if err != nil {
+			return nil, err
+		}
+		kids, err := NewAccountStub(stub, code).GetSignableIDs(token.GenesisAccount)
+		if err != nil {
+			return nil, err
+		}
+		signers.AppendSlice(kids)
+	}
+	kids, err := NewAccountStub(stub, con.Target).GetSignableIDs(con.ReserveAccount)
+	if err != nil {
+		return nil, err
+	}
+	signers.AppendSlice(kids)
+	if signers.Size() > 128 {
+		return nil, TooManySignersError{}
+	}
+	return signers, nil
+}
+
+// contract callbacks
+
+// doc: ["conversion/register", source, target, rate, mode, reserve-account, min-amount, max-amount]
+func executeConversionRegister(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
+	if len(doc) < 8 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
+	}
+
+	source, target := doc[1].(string), doc[2].(string)
+	cb := NewConversionStub(stub)
+	id := ConversionID(source, target)
+	if _, err := cb.GetConversion(id); err == nil {
+		return responseErrorCode(ErrorCodeInvalidState, "already registered conversion")
+	}
+
+	mode, err := strconv.Atoi(doc[4].(string))
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
+	}
+	min, err := NewAmount(doc[6].(string))
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid min amount")
+	}
+	max, err := NewAmount(doc[7].(string))
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid max amount")
+	}
+
+	con := &Conversion{
+		DOCTYPEID:      id,
+		Source:         source,
+		Target:         target,
+		Rate:           doc[3].(string),
+		Mode:           ConversionMode(mode),
+		ReserveAccount: doc[5].(string),
+		MinAmount:      *min,
+		MaxAmount:      *max,
+		LastContractID: cid,
+	}
+	if err = cb.Register(con); err != nil {
+		return responseError(err, "failed to register the conversion")
+	}
+
+	return shim.Success(nil)
+}
+
+// doc: ["conversion/update", conversion-ID, rate, min-amount, max-amount, paused]
+func executeConversionUpdate(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
+	if len(doc) < 6 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
+	}
+
+	cb := NewConversionStub(stub)
+	con, err := cb.GetConversion(doc[1].(string))
+	if err != nil {
+		return responseError(err, "failed to get the conversion")
+	}
+
+	min, err := NewAmount(doc[3].(string))
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid min amount")
+	}
+	max, err := NewAmount(doc[4].(string))
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid max amount")
+	}
+	paused, err := strconv.ParseBool(doc[5].(string))
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
+	}
+
+	con.Rate = doc[2].(string)
+	con.MinAmount = *min
+	con.MaxAmount = *max
+	con.Paused = paused
+	con.LastContractID = cid
+	if err = cb.Update(con); err != nil {
+		return responseError(err, "failed to update the conversion")
+	}
+
+	return shim.Success(nil)
+}
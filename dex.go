@@ -0,0 +1,197 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// DexPriceDecimal is the max fraction digits of the price.
+const DexPriceDecimal = 18
+
+// dexPriceKeyWidth is the width of the price in the order book key. (sortable)
+const dexPriceKeyWidth = 40
+
+var (
+	dexPriceScale  = new(big.Int).Exp(big.NewInt(10), big.NewInt(DexPriceDecimal), nil)
+	dexPriceKeyMax = new(big.Int).Exp(big.NewInt(10), big.NewInt(dexPriceKeyWidth), nil)
+)
+
+// DexOrderSide _
+type DexOrderSide int8
+
+const (
+	// DexOrderSideBuy buys the base token with the quote token
+	DexOrderSideBuy DexOrderSide = iota
+	// DexOrderSideSell sells the base token for the quote token
+	DexOrderSideSell
+)
+
+// ParseDexOrderSide _
+func ParseDexOrderSide(side string) (DexOrderSide, error) {
+	switch strings.ToLower(side) {
+	case "buy":
+		return DexOrderSideBuy, nil
+	case "sell":
+		return DexOrderSideSell, nil
+	}
+	return 0, InvalidParameterError{reason: "invalid side. expecting 'buy' or 'sell'"}
+}
+
+// Opposite _
+func (s DexOrderSide) Opposite() DexOrderSide {
+	if s == DexOrderSideBuy {
+		return DexOrderSideSell
+	}
+	return DexOrderSideBuy
+}
+
+// DexOrderStatus _
+type DexOrderStatus int8
+
+const (
+	// DexOrderStatusOpen _
+	DexOrderStatusOpen DexOrderStatus = iota
+	// DexOrderStatusFilled _
+	DexOrderStatusFilled
+	// DexOrderStatusCancelled _
+	DexOrderStatusCancelled
+)
+
+// ParseDexMarket parses the market string ("BASE/QUOTE") and returns the token codes.
+func ParseDexMarket(market string) (string, string, error) {
+	codes := strings.Split(market, "/")
+	if len(codes) != 2 {
+		return "", "", InvalidParameterError{reason: "invalid market. expecting 'BASE/QUOTE'"}
+	}
+	base, err := ValidateTokenCode(codes[0])
+	if err != nil {
+		return "", "", err
+	}
+	quote, err := ValidateTokenCode(codes[1])
+	if err != nil {
+		return "", "", err
+	}
+	if base == quote {
+		return "", "", InvalidParameterError{reason: "invalid market. same tokens"}
+	}
+	return base, quote, nil
+}
+
+// ParseDexPrice parses the price (quote amount per 1 base amount, decimal string)
+// and returns the price scaled by 10^DexPriceDecimal.
+func ParseDexPrice(price string) (*big.Int, error) {
+	if !IsDecimalAmount(price) {
+		price += ".0"
+	}
+	scaled, err := NewAmountWithDecimal(price, DexPriceDecimal)
+	if err != nil {
+		return nil, InvalidParameterError{reason: "invalid price: " + err.Error()}
+	}
+	if scaled.Sign() <= 0 || scaled.Int.Cmp(dexPriceKeyMax) >= 0 {
+		return nil, InvalidParameterError{reason: "invalid price: out of range"}
+	}
+	return &scaled.Int, nil
+}
+
+// FormatDexPrice returns the canonical decimal string of the scaled price. (no trailing zeros, e.g. "1.00" => "1")
+func FormatDexPrice(price *big.Int) string {
+	return NewAmountWithBigInt(price).DecimalString(DexPriceDecimal)
+}
+
+// DexQuoteAmount returns the quote amount of the base amount at the scaled price.
+// If ceil is false, it is floor.
+func DexQuoteAmount(base *Amount, price *big.Int, ceil bool) *Amount {
+	n := new(big.Int).Mul(&base.Int, price)
+	q, m := new(big.Int).QuoRem(n, dexPriceScale, new(big.Int))
+	if ceil && m.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return NewAmountWithBigInt(q)
+}
+
+// DexOrder is the limit order of the order book.
+// The locked amount is kept in the pending balance of the same ID.
+type DexOrder struct {
+	DOCTYPEID    string         `json:"@dex_order"` // tx id
+	Market       string         `json:"market"`     // BASE/QUOTE
+	Side         DexOrderSide   `json:"side"`
+	Price        string         `json:"price"`     // quote amount per 1 base amount (canonical, see FormatDexPrice)
+	Amount       Amount         `json:"amount"`    // base amount
+	Remaining    Amount         `json:"remaining"` // base amount not filled yet
+	Locked       Amount         `json:"locked"`    // locked amount not settled yet (sell: base, buy: quote)
+	BaseAccount  string         `json:"base_account"`
+	QuoteAccount string         `json:"quote_account"`
+	Status       DexOrderStatus `json:"status"`
+	CreatedTime  *txtime.Time   `json:"created_time,omitempty"`
+	UpdatedTime  *txtime.Time   `json:"updated_time,omitempty"`
+}
+
+// GetID implements Identifiable
+func (o *DexOrder) GetID() string {
+	return o.DOCTYPEID
+}
+
+// PayAccount returns the account address that locks the amount.
+func (o *DexOrder) PayAccount() string {
+	if o.Side == DexOrderSideBuy {
+		return o.QuoteAccount
+	}
+	return o.BaseAccount
+}
+
+// ScaledPrice returns the price scaled by 10^DexPriceDecimal.
+func (o *DexOrder) ScaledPrice() *big.Int {
+	price, _ := ParseDexPrice(o.Price) // validated
+	return price
+}
+
+// PriceKey returns the sortable price of the order book key. (best price first)
+func (o *DexOrder) PriceKey() string {
+	price := o.ScaledPrice()
+	if o.Side == DexOrderSideBuy { // descending
+		price = new(big.Int).Sub(dexPriceKeyMax, price)
+		price.Sub(price, big.NewInt(1))
+	}
+	return fmt.Sprintf("%0*s", dexPriceKeyWidth, price.String())
+}
+
+// DexTrade is the matched trade of the orders.
+type DexTrade struct {
+	DOCTYPEID    string       `json:"@dex_trade"` // market
+	TradeID      string       `json:"trade_id"`   // tx id + sequence
+	Price        string       `json:"price"`      // maker's price
+	Amount       Amount       `json:"amount"`     // base amount
+	QuoteAmount  Amount       `json:"quote_amount"`
+	MakerOrderID string       `json:"maker_order_id"`
+	TakerOrderID string       `json:"taker_order_id"`
+	TakerSide    DexOrderSide `json:"taker_side"`
+	BuyerFee     Amount       `json:"buyer_fee"`  // base token
+	SellerFee    Amount       `json:"seller_fee"` // quote token
+	CreatedTime  *txtime.Time `json:"created_time,omitempty"`
+}
+
+// DexBookLevel is the aggregated orders of a price.
+type DexBookLevel struct {
+	Price  string  `json:"price"`
+	Amount *Amount `json:"amount"` // remaining base amount
+	Count  int     `json:"count"`
+}
+
+// DexBook is the response payload of dex/book.
+type DexBook struct {
+	Market string          `json:"market"`
+	Bids   []*DexBookLevel `json:"bids"` // buy orders, best(highest) price first
+	Asks   []*DexBookLevel `json:"asks"` // sell orders, best(lowest) price first
+}
+
+// DexOrderResult is response payload of dex/order/place and dex/order/cancel.
+type DexOrderResult struct {
+	Order       *DexOrder     `json:"order"`
+	Trades      []*DexTrade   `json:"trades,omitempty"`
+	BalanceLogs []*BalanceLog `json:"balance_logs"`
+}
@@ -0,0 +1,405 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// DexTradesFetchSize _
+const DexTradesFetchSize = 20
+
+// DexMaxMatches is the max number of the trades of an order placement.
+// The rest of the order remains in the order book.
+const DexMaxMatches = 50
+
+// dexMaxScans is the max number of the maker orders to be scanned by an order placement.
+const dexMaxScans = 200
+
+// DexStub _
+type DexStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewDexStub _
+func NewDexStub(stub shim.ChaincodeStubInterface) *DexStub {
+	return &DexStub{stub}
+}
+
+// CreateOrderKey _
+func (db *DexStub) CreateOrderKey(id string) string {
+	return "DEXO_" + id
+}
+
+// CreateTradeKey _
+func (db *DexStub) CreateTradeKey(id string) string {
+	return "DEXT_" + id
+}
+
+// CreateBookKey returns the composite key of the order book. (market, side, price, time, id)
+func (db *DexStub) CreateBookKey(order *DexOrder) (string, error) {
+	return db.stub.CreateCompositeKey("DEXB", []string{
+		order.Market,
+		fmt.Sprintf("%d", order.Side),
+		order.PriceKey(),
+		fmt.Sprintf("%019d", order.CreatedTime.UnixNano()),
+		order.DOCTYPEID,
+	})
+}
+
+// GetOrder _
+func (db *DexStub) GetOrder(id string) (*DexOrder, error) {
+	data, err := db.stub.GetState(db.CreateOrderKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the dex order state")
+	}
+	if data == nil {
+		return nil, NotExistedDexOrderError{}
+	}
+	order := &DexOrder{}
+	if err = json.Unmarshal(data, order); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the dex order")
+	}
+	return order, nil
+}
+
+// PutOrder _
+func (db *DexStub) PutOrder(order *DexOrder) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the dex order")
+	}
+	if err = db.stub.PutState(db.CreateOrderKey(order.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the dex order state")
+	}
+	return nil
+}
+
+// PutTrade _
+func (db *DexStub) PutTrade(trade *DexTrade) error {
+	data, err := json.Marshal(trade)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the dex trade")
+	}
+	if err = db.stub.PutState(db.CreateTradeKey(trade.TradeID), data); err != nil {
+		return errors.Wrap(err, "failed to put the dex trade state")
+	}
+	return nil
+}
+
+// GetBookIterator returns the iterator of the order book keys. (best price first)
+func (db *DexStub) GetBookIterator(market string, side DexOrderSide) (shim.StateQueryIteratorInterface, error) {
+	return db.stub.GetStateByPartialCompositeKey("DEXB", []string{market, fmt.Sprintf("%d", side)})
+}
+
+// GetBook returns the aggregated order book of the market. (max 'depth' price levels of each side)
+func (db *DexStub) GetBook(market string, depth int) (*DexBook, error) {
+	book := &DexBook{Market: market}
+	var err error
+	if book.Bids, err = db.getBookLevels(market, DexOrderSideBuy, depth); err != nil {
+		return nil, err
+	}
+	if book.Asks, err = db.getBookLevels(market, DexOrderSideSell, depth); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (db *DexStub) getBookLevels(market string, side DexOrderSide, depth int) ([]*DexBookLevel, error) {
+	iter, err := db.GetBookIterator(market, side)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the order book")
+	}
+	defer iter.Close()
+
+	levels := []*DexBookLevel{}
+	var level *DexBookLevel
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attrs, err := db.stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		order, err := db.GetOrder(attrs[4])
+		if err != nil {
+			return nil, err
+		}
+		price := FormatDexPrice(order.ScaledPrice()) // the orders before the canonical price
+		if level == nil || level.Price != price {
+			if len(levels) >= depth {
+				break
+			}
+			level = &DexBookLevel{Price: price, Amount: ZeroAmount()}
+			levels = append(levels, level)
+		}
+		level.Amount.Add(&order.Remaining)
+		level.Count++
+	}
+	return levels, nil
+}
+
+// GetQueryTrades _
+func (db *DexStub) GetQueryTrades(market, bookmark string, fetchSize int) (*QueryResult, error) {
+	if fetchSize < 1 {
+		fetchSize = DexTradesFetchSize
+	}
+	if fetchSize > 200 {
+		fetchSize = 200
+	}
+	query := CreateQueryDexTradesByMarket(market)
+	iter, meta, err := db.stub.GetQueryResultWithPagination(query, int32(fetchSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	return NewQueryResult(meta, iter)
+}
+
+// PlaceOrder locks the amount of the order, matches it with the maker orders of the opposite side
+// and settles the trades. The rest of the order remains in the order book.
+// It does not validate the order and the balance!
+func (db *DexStub) PlaceOrder(order *DexOrder, payBal *Balance) (*DexOrderResult, error) {
+	ts, err := txtime.GetTime(db.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+	order.CreatedTime = ts
+	order.UpdatedTime = ts
+
+	price := order.ScaledPrice()
+	payAddr := order.PayAccount()
+
+	// lock
+	s := newBalanceSettlement(db.stub)
+	s.cache(payBal)
+	s.add(payAddr, order.Locked.Copy().Neg(), nil)
+
+	// match
+	iter, err := db.GetBookIterator(order.Market, order.Side.Opposite())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the order book")
+	}
+	defer iter.Close()
+
+	bb := NewBalanceStub(db.stub)
+	trades := []*DexTrade{}
+	for scans := 0; iter.HasNext() && scans < dexMaxScans && len(trades) < DexMaxMatches && order.Remaining.Sign() > 0; scans++ {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attrs, err := db.stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		maker, err := db.GetOrder(attrs[4])
+		if err != nil {
+			return nil, err
+		}
+		makerPrice := maker.ScaledPrice()
+		if order.Side == DexOrderSideBuy && makerPrice.Cmp(price) > 0 {
+			break // too expensive
+		}
+		if order.Side == DexOrderSideSell && makerPrice.Cmp(price) < 0 {
+			break // too cheap
+		}
+		if maker.BaseAccount == order.BaseAccount || maker.QuoteAccount == order.QuoteAccount {
+			continue // self trade
+		}
+
+		// fill
+		amount := order.Remaining.Copy()
+		if maker.Remaining.Cmp(amount) < 0 {
+			amount = maker.Remaining.Copy()
+		}
+		quoteAmount := DexQuoteAmount(amount, makerPrice, false)
+		if quoteAmount.Sign() == 0 {
+			continue // dust
+		}
+		trade, err := db.settle(s, order, maker, amount, quoteAmount, len(trades), ts)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+
+		// maker
+		if maker.Remaining.Sign() == 0 { // filled
+			maker.Status = DexOrderStatusFilled
+			if maker.Locked.Sign() > 0 { // unlock the rest (buy)
+				s.add(maker.PayAccount(), maker.Locked.Copy(), nil)
+				maker.Locked = *ZeroAmount()
+			}
+			if err = db.removeFromBook(maker); err != nil {
+				return nil, err
+			}
+			if err = bb.DeletePendingBalance(&PendingBalance{DOCTYPEID: maker.DOCTYPEID}); err != nil {
+				return nil, err
+			}
+		} else if err = db.putPendingBalance(maker); err != nil {
+			return nil, err
+		}
+		maker.UpdatedTime = ts
+		if err = db.PutOrder(maker); err != nil {
+			return nil, err
+		}
+	}
+
+	// taker
+	if order.Remaining.Sign() == 0 { // filled
+		order.Status = DexOrderStatusFilled
+		if order.Locked.Sign() > 0 { // unlock the rest (buy)
+			s.add(payAddr, order.Locked.Copy(), nil)
+			order.Locked = *ZeroAmount()
+		}
+	} else { // remains in the order book
+		if order.Side == DexOrderSideBuy { // unlock the price improvement
+			locked := DexQuoteAmount(&order.Remaining, price, true)
+			if diff := order.Locked.Copy().Add(locked.Copy().Neg()); diff.Sign() > 0 {
+				s.add(payAddr, diff, nil)
+				order.Locked = *locked
+			}
+		}
+		key, err := db.CreateBookKey(order)
+		if err != nil {
+			return nil, err
+		}
+		if err = db.stub.PutState(key, []byte(order.DOCTYPEID)); err != nil {
+			return nil, errors.Wrap(err, "failed to put the order book state")
+		}
+		if err = db.putPendingBalance(order); err != nil {
+			return nil, err
+		}
+	}
+	if err = db.PutOrder(order); err != nil {
+		return nil, err
+	}
+
+	logs, err := s.apply(BalanceLogTypeDexTrade, order.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DexOrderResult{Order: order, Trades: trades, BalanceLogs: logs}, nil
+}
+
+// CancelOrder unlocks the locked amount of the order and removes it from the order book.
+// It does not validate the order!
+func (db *DexStub) CancelOrder(order *DexOrder) (*DexOrderResult, error) {
+	ts, err := txtime.GetTime(db.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	s := newBalanceSettlement(db.stub)
+	s.add(order.PayAccount(), order.Locked.Copy(), nil)
+
+	if err = db.removeFromBook(order); err != nil {
+		return nil, err
+	}
+	if err = NewBalanceStub(db.stub).DeletePendingBalance(&PendingBalance{DOCTYPEID: order.DOCTYPEID}); err != nil {
+		return nil, err
+	}
+
+	order.Locked = *ZeroAmount()
+	order.Status = DexOrderStatusCancelled
+	order.UpdatedTime = ts
+	if err = db.PutOrder(order); err != nil {
+		return nil, err
+	}
+
+	logs, err := s.apply(BalanceLogTypeDexCancel, order.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DexOrderResult{Order: order, BalanceLogs: logs}, nil
+}
+
+// settle settles a trade between the taker and the maker.
+// Each receiver pays the fee(dex/taker or dex/maker) from the received amount.
+func (db *DexStub) settle(s *balanceSettlement, taker, maker *DexOrder, amount, quoteAmount *Amount, seq int, ts *txtime.Time) (*DexTrade, error) {
+	buyer, seller := taker, maker
+	buyerFn, sellerFn := "dex/taker", "dex/maker"
+	if taker.Side == DexOrderSideSell {
+		buyer, seller = maker, taker
+		buyerFn, sellerFn = "dex/maker", "dex/taker"
+	}
+
+	fb := NewFeeStub(db.stub)
+	// base: seller(locked) -> buyer
+	buyerAddr, _ := ParseAddress(buyer.BaseAccount) // err is nil
+	buyerFee, err := fb.CalcFee(buyerAddr, buyerFn, *amount)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the fee amount")
+	}
+	if buyerFee.Cmp(amount) > 0 {
+		buyerFee = amount.Copy()
+	}
+	s.add(buyer.BaseAccount, amount, buyerFee)
+	// quote: buyer(locked) -> seller
+	sellerAddr, _ := ParseAddress(seller.QuoteAccount) // err is nil
+	sellerFee, err := fb.CalcFee(sellerAddr, sellerFn, *quoteAmount)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the fee amount")
+	}
+	if sellerFee.Cmp(quoteAmount) > 0 {
+		sellerFee = quoteAmount.Copy()
+	}
+	s.add(seller.QuoteAccount, quoteAmount, sellerFee)
+
+	seller.Locked.Add(amount.Copy().Neg())
+	buyer.Locked.Add(quoteAmount.Copy().Neg())
+	taker.Remaining.Add(amount.Copy().Neg())
+	maker.Remaining.Add(amount.Copy().Neg())
+
+	trade := &DexTrade{
+		DOCTYPEID:    taker.Market,
+		TradeID:      fmt.Sprintf("%s_%d", taker.DOCTYPEID, seq),
+		Price:        FormatDexPrice(maker.ScaledPrice()),
+		Amount:       *amount,
+		QuoteAmount:  *quoteAmount,
+		MakerOrderID: maker.DOCTYPEID,
+		TakerOrderID: taker.DOCTYPEID,
+		TakerSide:    taker.Side,
+		BuyerFee:     *buyerFee,
+		SellerFee:    *sellerFee,
+		CreatedTime:  ts,
+	}
+	if err = db.PutTrade(trade); err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+func (db *DexStub) removeFromBook(order *DexOrder) error {
+	key, err := db.CreateBookKey(order)
+	if err != nil {
+		return err
+	}
+	if err = db.stub.DelState(key); err != nil {
+		return errors.Wrap(err, "failed to delete the order book state")
+	}
+	return nil
+}
+
+// putPendingBalance puts the locked amount of the order as the pending balance.
+func (db *DexStub) putPendingBalance(order *DexOrder) error {
+	pb := &PendingBalance{
+		DOCTYPEID:   order.DOCTYPEID,
+		Type:        PendingBalanceTypeDex,
+		Account:     order.PayAccount(),
+		RID:         order.Market,
+		Amount:      order.Locked,
+		CreatedTime: order.CreatedTime,
+	}
+	return NewBalanceStub(db.stub).PutPendingBalance(pb)
+}
@@ -0,0 +1,284 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/key-inside/kiesnet-ccpkg/kid"
+)
+
+// DexBookDepth _
+const DexBookDepth = 20
+
+// params[0] : market ("BASE/QUOTE")
+// params[1] : side ("buy" | "sell")
+// params[2] : price (quote amount per 1 base amount, decimal string)
+// params[3] : amount of the base token (big int string | decimal-formatted string)
+// params[4] : optional. base token account address (empty string = personal account)
+// params[5] : optional. quote token account address (empty string = personal account)
+func dexOrderPlace(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 4 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 4+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	baseCode, quoteCode, err := ParseDexMarket(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+	side, err := ParseDexOrderSide(params[1])
+	if err != nil {
+		return responseError(err, "")
+	}
+	price, err := ParseDexPrice(params[2])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// tokens
+	tb := NewTokenStub(stub)
+	if _, err = tb.GetToken(quoteCode); err != nil {
+		return responseError(err, "failed to get the quote token")
+	}
+	amount, err := tb.ParseAmount(baseCode, params[3])
+	if err != nil {
+		return responseError(err, "")
+	}
+	if amount.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+	}
+
+	// accounts
+	addrs := []string{"", ""}
+	copy(addrs, params[4:])
+	accounts := make([]AccountInterface, 2)
+	for i, code := range []string{baseCode, quoteCode} {
+		var addr *Address
+		if len(addrs[i]) > 0 {
+			addr, err = ParseAddress(addrs[i])
+			if err != nil {
+				return responseError(err, "failed to parse the account address")
+			}
+			if addr.Code != code {
+				return responseErrorCode(ErrorCodeInvalidParameter, "the account is not the token account of the market: "+addr.String())
+			}
+		} else {
+			addr = NewAddress(code, AccountTypePersonal, kid)
+		}
+		account, err := NewAccountStub(stub, code).GetAccount(addr)
+		if err != nil {
+			return responseError(err, "failed to get the account: "+addr.String())
+		}
+		if !account.HasHolder(kid) {
+			return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder: "+addr.String())
+		}
+		if account.IsSuspended() {
+			return responseErrorCode(ErrorCodeSuspendedAccount, "the account is suspended: "+addr.String())
+		}
+		accounts[i] = account
+	}
+
+	order := &DexOrder{
+		DOCTYPEID:    stub.GetTxID(),
+		Market:       baseCode + "/" + quoteCode,
+		Side:         side,
+		Price:        FormatDexPrice(price),
+		Amount:       *amount,
+		Remaining:    *amount.Copy(),
+		BaseAccount:  accounts[0].GetID(),
+		QuoteAccount: accounts[1].GetID(),
+		Status:       DexOrderStatusOpen,
+	}
+	if side == DexOrderSideBuy {
+		order.Locked = *DexQuoteAmount(amount, price, true)
+		if order.Locked.Sign() == 0 {
+			return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. the quote amount is 0")
+		}
+	} else {
+		order.Locked = *amount.Copy()
+	}
+
+	// balance
+	payBal, err := NewBalanceStub(stub).GetBalance(order.PayAccount())
+	if err != nil {
+		return responseError(err, "failed to get the balance")
+	}
+	if payBal.Amount.Cmp(&order.Locked) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	res, err := NewDexStub(stub).PlaceOrder(order, payBal)
+	if err != nil {
+		return responseError(err, "failed to place the order")
+	}
+
+	return responseDexOrderResult(res)
+}
+
+// params[0] : order id
+func dexOrderCancel(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	db := NewDexStub(stub)
+	order, err := db.GetOrder(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the order")
+	}
+	if order.Status != DexOrderStatusOpen {
+		return responseErrorCode(ErrorCodeInvalidState, "the order is not open")
+	}
+
+	addr, _ := ParseAddress(order.PayAccount()) // err is nil
+	account, err := NewAccountStub(stub, addr.Code).GetAccount(addr)
+	if err != nil {
+		return responseError(err, "failed to get the account")
+	}
+	if !account.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
+	}
+
+	res, err := db.CancelOrder(order)
+	if err != nil {
+		return responseError(err, "failed to cancel the order")
+	}
+
+	return responseDexOrderResult(res)
+}
+
+// params[0] : order id
+func dexOrderGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	order, err := NewDexStub(stub).GetOrder(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the order")
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return responseError(err, "failed to marshal the order")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : market ("BASE/QUOTE")
+// params[1] : optional. depth (price levels of each side, default 20, max 200)
+func dexBook(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	baseCode, quoteCode, err := ParseDexMarket(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	depth := DexBookDepth
+	if len(params) > 1 && len(params[1]) > 0 {
+		depth, err = strconv.Atoi(params[1])
+		if err != nil || depth < 1 {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid depth")
+		}
+		if depth > 200 {
+			depth = 200
+		}
+	}
+
+	book, err := NewDexStub(stub).GetBook(baseCode+"/"+quoteCode, depth)
+	if err != nil {
+		return responseError(err, "failed to get the order book")
+	}
+
+	data, err := json.Marshal(book)
+	if err != nil {
+		return responseError(err, "failed to marshal the order book")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : market ("BASE/QUOTE")
+// params[1] : optional. bookmark
+// params[2] : optional. fetch size (default 20, max 200)
+func dexTrades(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	baseCode, quoteCode, err := ParseDexMarket(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	bookmark := ""
+	fetchSize := 0
+	if len(params) > 1 {
+		bookmark = params[1]
+		if len(params) > 2 {
+			fetchSize, err = strconv.Atoi(params[2])
+			if err != nil {
+				return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+			}
+		}
+	}
+
+	res, err := NewDexStub(stub).GetQueryTrades(baseCode+"/"+quoteCode, bookmark, fetchSize)
+	if err != nil {
+		return responseError(err, "failed to get the trades")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the trades")
+	}
+
+	return shim.Success(data)
+}
+
+// helpers
+
+func responseDexOrderResult(res *DexOrderResult) peer.Response {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+	return shim.Success(data)
+}
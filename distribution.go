@@ -0,0 +1,91 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// DistributionMaxExcluded is the max number of the additional excluded accounts.
+const DistributionMaxExcluded = 50
+
+// DistributionStatus _
+type DistributionStatus int8
+
+const (
+	// DistributionStatusOpen holders can claim
+	DistributionStatusOpen DistributionStatus = iota
+	// DistributionStatusClosed the remainder is returned to the payout account
+	DistributionStatusClosed
+)
+
+// Distribution is the pro-rata payout to the holders of the token.
+// The eligible balances are snapshotted at the snapshot time (balance logs), and each holder claims its share.
+// The share of the account = floor(amount * balance at the snapshot time / eligible supply).
+// The rounding dust and the unclaimed amount are returned to the payout account when it is closed.
+type Distribution struct {
+	DOCTYPEID      string             `json:"@distribution"`  // tx id
+	Token          string             `json:"token"`          // holding token code
+	PayoutToken    string             `json:"payout_token"`   // payout token code
+	PayoutAccount  string             `json:"payout_account"` // payout token account which funds the distribution
+	Amount         Amount             `json:"amount"`         // total payout amount
+	Remaining      Amount             `json:"remaining"`      // not claimed amount (locked as the pending balance)
+	SnapshotTime   *txtime.Time       `json:"snapshot_time"`
+	EligibleSupply Amount             `json:"eligible_supply"` // supply at the snapshot time - balances of the excluded accounts
+	Excluded       []string           `json:"excluded"`        // excluded accounts (genesis, fee target, wrap bridge, pool reserves, payout and the given accounts)
+	ClaimCount     int                `json:"claim_count"`
+	Status         DistributionStatus `json:"status"`
+	Memo           string             `json:"memo,omitempty"`
+	Expiry         *txtime.Time       `json:"expiry"` // claim deadline, closable after it
+	CreatedTime    *txtime.Time       `json:"created_time,omitempty"`
+	UpdatedTime    *txtime.Time       `json:"updated_time,omitempty"`
+}
+
+// GetID implements Identifiable
+func (d *Distribution) GetID() string {
+	return d.DOCTYPEID
+}
+
+// IsExcluded _
+func (d *Distribution) IsExcluded(addr string) bool {
+	for _, e := range d.Excluded {
+		if e == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimAmount returns the share of the balance at the snapshot time. (floor)
+func (d *Distribution) ClaimAmount(balance *Amount) *Amount {
+	if d.EligibleSupply.Sign() <= 0 || balance.Sign() <= 0 {
+		return ZeroAmount()
+	}
+	return mulDivAmount(&d.Amount, balance, &d.EligibleSupply, false)
+}
+
+// DistributionClaim is the claim record of the holding account. An account can claim only once.
+type DistributionClaim struct {
+	DOCTYPEID   string       `json:"@distribution_claim"` // distribution id
+	Account     string       `json:"account"`             // holding account
+	Receiver    string       `json:"receiver"`            // payout token account which received the amount
+	Balance     Amount       `json:"balance"`             // balance at the snapshot time
+	Amount      Amount       `json:"amount"`
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+}
+
+// DistributionState is the response payload of distribution/get.
+type DistributionState struct {
+	*Distribution
+	Account   string             `json:"account,omitempty"`
+	Balance   *Amount            `json:"balance,omitempty"`   // balance of the account at the snapshot time
+	Claimable *Amount            `json:"claimable,omitempty"` // 0 if it is claimed or excluded
+	Claim     *DistributionClaim `json:"claim,omitempty"`
+}
+
+// DistributionResult is the response payload of distribution/create, distribution/claim and distribution/close.
+type DistributionResult struct {
+	Distribution *Distribution      `json:"distribution"`
+	Claim        *DistributionClaim `json:"claim,omitempty"`
+	BalanceLogs  []*BalanceLog      `json:"balance_logs"`
+}
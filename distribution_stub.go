@@ -0,0 +1,235 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// DistributionStub _
+type DistributionStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewDistributionStub _
+func NewDistributionStub(stub shim.ChaincodeStubInterface) *DistributionStub {
+	return &DistributionStub{stub}
+}
+
+// CreateKey _
+func (db *DistributionStub) CreateKey(id string) string {
+	return "DIST_" + id
+}
+
+// CreateClaimKey _
+func (db *DistributionStub) CreateClaimKey(id, addr string) string {
+	return "DISTC_" + id + "_" + addr
+}
+
+// GetDistribution _
+func (db *DistributionStub) GetDistribution(id string) (*Distribution, error) {
+	data, err := db.stub.GetState(db.CreateKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the distribution state")
+	}
+	if data == nil {
+		return nil, NotExistedDistributionError{}
+	}
+	dist := &Distribution{}
+	if err = json.Unmarshal(data, dist); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the distribution")
+	}
+	return dist, nil
+}
+
+// PutDistribution _
+func (db *DistributionStub) PutDistribution(dist *Distribution) error {
+	data, err := json.Marshal(dist)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the distribution")
+	}
+	if err = db.stub.PutState(db.CreateKey(dist.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the distribution state")
+	}
+	return nil
+}
+
+// GetClaim returns the claim of the account. If the account didn't claim, it returns nil.
+func (db *DistributionStub) GetClaim(id, addr string) (*DistributionClaim, error) {
+	data, err := db.stub.GetState(db.CreateClaimKey(id, addr))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the distribution claim state")
+	}
+	if data == nil {
+		return nil, nil
+	}
+	claim := &DistributionClaim{}
+	if err = json.Unmarshal(data, claim); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the distribution claim")
+	}
+	return claim, nil
+}
+
+// PutClaim _
+func (db *DistributionStub) PutClaim(claim *DistributionClaim) error {
+	data, err := json.Marshal(claim)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the distribution claim")
+	}
+	if err = db.stub.PutState(db.CreateClaimKey(claim.DOCTYPEID, claim.Account), data); err != nil {
+		return errors.Wrap(err, "failed to put the distribution claim state")
+	}
+	return nil
+}
+
+// GetEligibleSupply returns the supply of the token at the time except the balances of the excluded accounts.
+// The non-balance holdings at the time (pending balances, unpruned pays and fees, requested wraps) can't be
+// reconstructed at the past time, so they remain in the eligible supply. Their shares are not claimed, and
+// they are returned to the payout account by distribution/close.
+func (db *DistributionStub) GetEligibleSupply(token *Token, t *txtime.Time, excluded []string) (*Amount, error) {
+	supply, err := NewTokenStub(db.stub).GetSupplyAt(token, t)
+	if err != nil {
+		return nil, err
+	}
+	bb := NewBalanceStub(db.stub)
+	for _, addr := range excluded {
+		bal, err := bb.GetBalanceAt(addr, t)
+		if err != nil {
+			return nil, err
+		}
+		supply.Add(bal.Copy().Neg())
+	}
+	return supply, nil
+}
+
+// Create locks the amount of the payout account as the pending balance and puts the distribution.
+// It does not validate the distribution!
+func (db *DistributionStub) Create(dist *Distribution, payout *Balance) (*DistributionResult, error) {
+	ts, err := txtime.GetTime(db.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	s := newBalanceSettlement(db.stub)
+	s.memo = dist.Memo
+	s.cache(payout)
+	s.add(payout.DOCTYPEID, dist.Amount.Copy().Neg(), nil)
+	logs, err := s.apply(BalanceLogTypeDistributionCreate, dist.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	pb := &PendingBalance{
+		DOCTYPEID:   dist.DOCTYPEID,
+		Type:        PendingBalanceTypeDistribution,
+		Account:     payout.DOCTYPEID,
+		RID:         dist.Token,
+		Amount:      dist.Amount,
+		Memo:        dist.Memo,
+		CreatedTime: ts,
+		PendingTime: dist.Expiry,
+	}
+	if err = NewBalanceStub(db.stub).PutPendingBalance(pb); err != nil {
+		return nil, err
+	}
+
+	dist.Remaining = dist.Amount
+	dist.CreatedTime = ts
+	dist.UpdatedTime = ts
+	if err = db.PutDistribution(dist); err != nil {
+		return nil, err
+	}
+
+	return &DistributionResult{Distribution: dist, BalanceLogs: logs}, nil
+}
+
+// Claim releases the amount from the pending balance to the receiver and records the claim of the account.
+// It does not validate the claim!
+func (db *DistributionStub) Claim(dist *Distribution, account string, balance *Amount, receiver *Balance, amount *Amount) (*DistributionResult, error) {
+	ts, err := txtime.GetTime(db.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	bb := NewBalanceStub(db.stub)
+	pb, err := bb.GetPendingBalance(dist.DOCTYPEID)
+	if err != nil {
+		return nil, err
+	}
+	pb.Amount.Add(amount.Copy().Neg())
+	if pb.Amount.Sign() < 0 { // never here
+		return nil, NotEnoughBalanceError{}
+	}
+	if err = bb.PutPendingBalance(pb); err != nil {
+		return nil, err
+	}
+
+	s := newBalanceSettlement(db.stub)
+	s.memo = dist.Memo
+	s.cache(receiver)
+	s.add(receiver.DOCTYPEID, amount, nil)
+	logs, err := s.apply(BalanceLogTypeDistributionClaim, dist.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	claim := &DistributionClaim{
+		DOCTYPEID:   dist.DOCTYPEID,
+		Account:     account,
+		Receiver:    receiver.DOCTYPEID,
+		Balance:     *balance,
+		Amount:      *amount,
+		CreatedTime: ts,
+	}
+	if err = db.PutClaim(claim); err != nil {
+		return nil, err
+	}
+
+	dist.Remaining = pb.Amount
+	dist.ClaimCount++
+	dist.UpdatedTime = ts
+	if err = db.PutDistribution(dist); err != nil {
+		return nil, err
+	}
+
+	return &DistributionResult{Distribution: dist, Claim: claim, BalanceLogs: logs}, nil
+}
+
+// Close returns the remainder (the unclaimed amount and the rounding dust) to the payout account.
+// It does not validate the expiry!
+func (db *DistributionStub) Close(dist *Distribution, payout *Balance) (*DistributionResult, error) {
+	ts, err := txtime.GetTime(db.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	bb := NewBalanceStub(db.stub)
+	pb, err := bb.GetPendingBalance(dist.DOCTYPEID)
+	if err != nil {
+		return nil, err
+	}
+	if err = bb.DeletePendingBalance(pb); err != nil {
+		return nil, err
+	}
+
+	s := newBalanceSettlement(db.stub)
+	s.cache(payout)
+	s.add(payout.DOCTYPEID, &pb.Amount, nil)
+	logs, err := s.apply(BalanceLogTypeDistributionClose, dist.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	dist.Remaining = *ZeroAmount()
+	dist.Status = DistributionStatusClosed
+	dist.UpdatedTime = ts
+	if err = db.PutDistribution(dist); err != nil {
+		return nil, err
+	}
+
+	return &DistributionResult{Distribution: dist, BalanceLogs: logs}, nil
+}
@@ -0,0 +1,407 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/key-inside/kiesnet-ccpkg/kid"
+	"github.com/key-inside/kiesnet-ccpkg/stringset"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// The genesis account, the fee target account, the fee beneficiaries, the wrap bridge accounts, the pool reserve accounts
+// and the payout account are always excluded from the distribution.
+
+// Only the holder of the genesis account of the holding token can create the distribution,
+// and the invoker must be the holder of the payout account.
+// params[0] : holding token code
+// params[1] : payout account address (funds the distribution)
+// params[2] : amount (big int string | decimal-formatted string of the payout token)
+// params[3] : expiry (time represented by int64 seconds) - claim deadline
+// params[4] : optional. snapshot time (time represented by int64 seconds, default now)
+// params[5] : optional. memo (see MemoMaxLength)
+// params[6:] : optional. additional excluded account addresses (see DistributionMaxExcluded)
+func distributionCreate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 4 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 4+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	// holding token
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+	tb := NewTokenStub(stub)
+	token, err := tb.GetToken(code)
+	if err != nil {
+		return responseError(err, "failed to get the token")
+	}
+	kids, err := NewAccountStub(stub, code).GetSignableIDs(token.GenesisAccount)
+	if err != nil {
+		return responseError(err, "failed to get the genesis account holders")
+	}
+	if !stringset.New(kids...).Contains(kid) {
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
+	}
+
+	// payout account
+	pAddr, err := ParseAddress(params[1])
+	if err != nil {
+		return responseError(err, "failed to parse the payout account address")
+	}
+	payout, err := NewAccountStub(stub, pAddr.Code).GetAccount(pAddr)
+	if err != nil {
+		return responseError(err, "failed to get the payout account")
+	}
+	if !payout.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder of the payout account")
+	}
+	if payout.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the payout account is suspended")
+	}
+
+	// amount
+	amount, err := tb.ParseAmount(pAddr.Code, params[2])
+	if err != nil {
+		return responseError(err, "")
+	}
+	if amount.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+	}
+	bb := NewBalanceStub(stub)
+	pBal, err := bb.GetBalance(payout.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the payout account balance")
+	}
+	if pBal.Amount.Cmp(amount) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	// times
+	seconds, err := strconv.ParseInt(params[3], 10, 64)
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid expiry: need seconds since 1970")
+	}
+	expiry := txtime.Unix(seconds, 0)
+	if expiry.Cmp(ts) <= 0 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid expiry: must be future")
+	}
+	snapshot := ts
+	if len(params) > 4 && len(params[4]) > 0 {
+		seconds, err = strconv.ParseInt(params[4], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid snapshot time: need seconds since 1970")
+		}
+		snapshot = txtime.Unix(seconds, 0)
+		if snapshot.Cmp(ts) > 0 {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid snapshot time: must not be future")
+		}
+	}
+
+	memo := ""
+	if len(params) > 5 {
+		memo = params[5]
+		if len(memo) > MemoMaxLength { // length limit
+			memo = memo[:MemoMaxLength]
+		}
+	}
+
+	// excluded accounts
+	excluded := stringset.New(token.GenesisAccount)
+	if token.FeePolicy != nil {
+		if len(token.FeePolicy.TargetAddress) > 0 {
+			excluded.Add(token.FeePolicy.TargetAddress)
+		}
+		for _, b := range token.FeePolicy.Beneficiaries { // receive the fee revenue like the fee target
+			excluded.Add(b.Address)
+		}
+	}
+	for _, policy := range token.WrapBridge {
+		excluded.Add(policy.WrapAddress)
+	}
+	reserves, err := NewPoolStub(stub).GetReserveAccounts(code) // chaincode-owned, no holder can claim
+	if err != nil {
+		return responseError(err, "failed to get the pool reserve accounts")
+	}
+	excluded.AppendSlice(reserves)
+	if pAddr.Code == code {
+		excluded.Add(payout.GetID())
+	}
+	if len(params) > 6 {
+		if len(params[6:]) > DistributionMaxExcluded {
+			return responseErrorCode(ErrorCodeInvalidParameter, "too many excluded accounts")
+		}
+		for _, p := range params[6:] {
+			addr, err := ParseAddress(p)
+			if err != nil {
+				return responseError(err, "failed to parse the excluded account address")
+			}
+			if addr.Code != code {
+				return responseErrorCode(ErrorCodeInvalidParameter, "the excluded account must be the holding token account")
+			}
+			excluded.Add(addr.String())
+		}
+	}
+
+	addrs := excluded.Strings()
+	sort.Strings(addrs) // deterministic
+
+	db := NewDistributionStub(stub)
+	eligible, err := db.GetEligibleSupply(token, snapshot, addrs)
+	if err != nil {
+		return responseError(err, "failed to get the eligible supply")
+	}
+	if eligible.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidState, "no eligible supply")
+	}
+
+	dist := &Distribution{
+		DOCTYPEID:      stub.GetTxID(),
+		Token:          code,
+		PayoutToken:    pAddr.Code,
+		PayoutAccount:  payout.GetID(),
+		Amount:         *amount,
+		SnapshotTime:   snapshot,
+		EligibleSupply: *eligible,
+		Excluded:       addrs,
+		Status:         DistributionStatusOpen,
+		Memo:           memo,
+		Expiry:         expiry,
+	}
+	res, err := db.Create(dist, pBal)
+	if err != nil {
+		return responseError(err, "failed to create the distribution")
+	}
+
+	return responseDistributionResult(res)
+}
+
+// The share of the personal account is paid to the invoker's PAOT of the payout token.
+// The share of the joint account is paid to the joint account itself, so the payout token must be the holding token.
+// params[0] : distribution id
+// params[1] : optional. holding account address (default PAOT)
+func distributionClaim(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	db := NewDistributionStub(stub)
+	dist, err := db.GetDistribution(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the distribution")
+	}
+	if dist.Status != DistributionStatusOpen {
+		return responseErrorCode(ErrorCodeInvalidState, "the distribution is closed")
+	}
+	if dist.Expiry.Cmp(ts) < 0 {
+		return responseErrorCode(ErrorCodeInvalidState, "the distribution is expired")
+	}
+
+	// holding account
+	var addr *Address
+	if len(params) > 1 && len(params[1]) > 0 {
+		if addr, err = ParseAddress(params[1]); err != nil {
+			return responseError(err, "failed to parse the account address")
+		}
+		if addr.Code != dist.Token {
+			return responseErrorCode(ErrorCodeInvalidParameter, "the account must be the holding token account")
+		}
+	} else {
+		addr = NewAddress(dist.Token, AccountTypePersonal, kid)
+	}
+	account, err := NewAccountStub(stub, dist.Token).GetAccount(addr)
+	if err != nil {
+		return responseError(err, "failed to get the account")
+	}
+	if !account.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
+	}
+	if account.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the account is suspended")
+	}
+	if dist.IsExcluded(account.GetID()) {
+		return responseErrorCode(ErrorCodeInvalidState, "the account is excluded from the distribution")
+	}
+	if claim, err := db.GetClaim(dist.DOCTYPEID, account.GetID()); err != nil {
+		return responseError(err, "failed to get the claim")
+	} else if claim != nil {
+		return responseErrorCode(ErrorCodeInvalidState, "already claimed")
+	}
+
+	// amount
+	bb := NewBalanceStub(stub)
+	balance, err := bb.GetBalanceAt(account.GetID(), dist.SnapshotTime)
+	if err != nil {
+		return responseError(err, "failed to get the balance at the snapshot time")
+	}
+	amount := dist.ClaimAmount(balance)
+	if amount.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "nothing to claim")
+	}
+	if amount.Cmp(&dist.Remaining) > 0 { // the sum of the claims can't exceed the amount
+		return responseErrorCode(ErrorCodeInvalidState, "the claim exceeds the remaining amount of the distribution")
+	}
+
+	// receiver
+	var receiver AccountInterface
+	if account.GetType() == AccountTypePersonal {
+		if receiver, err = NewAccountStub(stub, dist.PayoutToken).GetAccount(NewAddress(dist.PayoutToken, AccountTypePersonal, kid)); err != nil {
+			return responseError(err, "failed to get the payout token account")
+		}
+		if receiver.IsSuspended() {
+			return responseErrorCode(ErrorCodeSuspendedAccount, "the payout token account is suspended")
+		}
+	} else if dist.PayoutToken == dist.Token {
+		receiver = account
+	} else {
+		return responseErrorCode(ErrorCodeInvalidState, "the joint account can claim only if the payout token is the holding token")
+	}
+	rBal, err := bb.GetBalance(receiver.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the receiver's balance")
+	}
+
+	res, err := db.Claim(dist, account.GetID(), balance, rBal, amount)
+	if err != nil {
+		return responseError(err, "failed to claim")
+	}
+
+	return responseDistributionResult(res)
+}
+
+// Only the holder of the payout account can close the distribution after the expiry.
+// params[0] : distribution id
+func distributionClose(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	db := NewDistributionStub(stub)
+	dist, err := db.GetDistribution(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the distribution")
+	}
+	if dist.Status != DistributionStatusOpen {
+		return responseErrorCode(ErrorCodeInvalidState, "already closed")
+	}
+	if dist.Expiry.Cmp(ts) >= 0 {
+		return responseErrorCode(ErrorCodeInvalidState, "too early to close")
+	}
+
+	addr, _ := ParseAddress(dist.PayoutAccount) // err is nil
+	payout, err := NewAccountStub(stub, addr.Code).GetAccount(addr)
+	if err != nil {
+		return responseError(err, "failed to get the payout account")
+	}
+	if !payout.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
+	}
+	pBal, err := NewBalanceStub(stub).GetBalance(payout.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the payout account balance")
+	}
+
+	res, err := db.Close(dist, pBal)
+	if err != nil {
+		return responseError(err, "failed to close the distribution")
+	}
+
+	return responseDistributionResult(res)
+}
+
+// params[0] : distribution id
+// params[1] : optional. holding account address - the balance at the snapshot time and the claimable amount
+func distributionGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	db := NewDistributionStub(stub)
+	dist, err := db.GetDistribution(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the distribution")
+	}
+	state := &DistributionState{Distribution: dist}
+
+	if len(params) > 1 && len(params[1]) > 0 {
+		addr, err := ParseAddress(params[1])
+		if err != nil {
+			return responseError(err, "failed to parse the account address")
+		}
+		if addr.Code != dist.Token {
+			return responseErrorCode(ErrorCodeInvalidParameter, "the account must be the holding token account")
+		}
+		state.Account = addr.String()
+		if state.Balance, err = NewBalanceStub(stub).GetBalanceAt(state.Account, dist.SnapshotTime); err != nil {
+			return responseError(err, "failed to get the balance at the snapshot time")
+		}
+		if state.Claim, err = db.GetClaim(dist.DOCTYPEID, state.Account); err != nil {
+			return responseError(err, "failed to get the claim")
+		}
+		state.Claimable = ZeroAmount()
+		if state.Claim == nil && dist.Status == DistributionStatusOpen && !dist.IsExcluded(state.Account) {
+			state.Claimable = dist.ClaimAmount(state.Balance)
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return responseError(err, "failed to marshal the distribution")
+	}
+
+	return shim.Success(data)
+}
+
+// helpers
+
+func responseDistributionResult(res *DistributionResult) peer.Response {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+	return shim.Success(data)
+}
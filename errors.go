@@ -4,8 +4,208 @@ package main
 
 import (
 	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// response status codes of the errors
+// XXX Fabric treats every status greater than or equal to 400 as an error.
+const (
+	// StatusBadRequest is the status of client errors
+	StatusBadRequest int32 = 400
+	// StatusUnauthorized is the status of authentication failures
+	StatusUnauthorized int32 = 401
+	// StatusForbidden is the status of authorization failures
+	StatusForbidden int32 = 403
+	// StatusNotFound is the status of not-found errors
+	StatusNotFound int32 = 404
+	// StatusInternalServerError is the status of internal errors (same as shim.ERROR)
+	StatusInternalServerError int32 = 500
+)
+
+// ErrorCode is the stable machine-readable code of the error.
+// DO NOT CHANGE the number of the existing code. Clients depend on it.
+type ErrorCode int
+
+const (
+	// ErrorCodeInternal _
+	ErrorCodeInternal ErrorCode = 1000
+	// ErrorCodeUnknownFunction _
+	ErrorCodeUnknownFunction ErrorCode = 1001
+
+	// client errors (2xxx)
+
+	// ErrorCodeInvalidParameter _
+	ErrorCodeInvalidParameter ErrorCode = 2000
+	// ErrorCodeInvalidAmount _
+	ErrorCodeInvalidAmount ErrorCode = 2001
+	// ErrorCodeInvalidAccountAddr _
+	ErrorCodeInvalidAccountAddr ErrorCode = 2002
+	// ErrorCodeInvalidTokenCode _
+	ErrorCodeInvalidTokenCode ErrorCode = 2003
+	// ErrorCodeNotEnoughBalance _
+	ErrorCodeNotEnoughBalance ErrorCode = 2004
+	// ErrorCodeSuspendedAccount _
+	ErrorCodeSuspendedAccount ErrorCode = 2005
+	// ErrorCodeTooManySigners _
+	ErrorCodeTooManySigners ErrorCode = 2006
+	// ErrorCodeHolderLimit _
+	ErrorCodeHolderLimit ErrorCode = 2007
+	// ErrorCodeExistedAccount _
+	ErrorCodeExistedAccount ErrorCode = 2008
+	// ErrorCodeExistedHolder _
+	ErrorCodeExistedHolder ErrorCode = 2009
+	// ErrorCodeAlreadyIssuedToken _
+	ErrorCodeAlreadyIssuedToken ErrorCode = 2010
+	// ErrorCodeSupply _
+	ErrorCodeSupply ErrorCode = 2011
+	// ErrorCodeInvalidState _
+	ErrorCodeInvalidState ErrorCode = 2012
+	// ErrorCodeDuplicateWrapComplete _
+	ErrorCodeDuplicateWrapComplete ErrorCode = 2013
+	// ErrorCodeDuplicateUnwrapComplete _
+	ErrorCodeDuplicateUnwrapComplete ErrorCode = 2014
+	// ErrorCodeExistedPool _
+	ErrorCodeExistedPool ErrorCode = 2015
+	// ErrorCodeSlippage _
+	ErrorCodeSlippage ErrorCode = 2016
+	// ErrorCodeBridgeLimit _
+	ErrorCodeBridgeLimit ErrorCode = 2017
+
+	// authentication/authorization failures (3xxx)
+
+	// ErrorCodeUnauthenticated _
+	ErrorCodeUnauthenticated ErrorCode = 3000
+	// ErrorCodeInvalidAccess _
+	ErrorCodeInvalidAccess ErrorCode = 3001
+	// ErrorCodeNotHolder _
+	ErrorCodeNotHolder ErrorCode = 3002
+	// ErrorCodeNoAuthority _
+	ErrorCodeNoAuthority ErrorCode = 3003
+
+	// not found (4xxx)
+
+	// ErrorCodeNotIssuedToken _
+	ErrorCodeNotIssuedToken ErrorCode = 4000
+	// ErrorCodeNotExistedAccount _
+	ErrorCodeNotExistedAccount ErrorCode = 4001
+	// ErrorCodeNotExistedHolder _
+	ErrorCodeNotExistedHolder ErrorCode = 4002
+	// ErrorCodeNotExistedPay _
+	ErrorCodeNotExistedPay ErrorCode = 4003
+	// ErrorCodeNotExistedFee _
+	ErrorCodeNotExistedFee ErrorCode = 4004
+	// ErrorCodeNotExistedPendingBalance _
+	ErrorCodeNotExistedPendingBalance ErrorCode = 4005
+	// ErrorCodeNotInitLastPrunedFeeID _
+	ErrorCodeNotInitLastPrunedFeeID ErrorCode = 4006
+	// ErrorCodeNotExistedWrap _
+	ErrorCodeNotExistedWrap ErrorCode = 4007
+	// ErrorCodeNotExistedBalanceLog _
+	ErrorCodeNotExistedBalanceLog ErrorCode = 4008
+	// ErrorCodeNotExistedHTLC _
+	ErrorCodeNotExistedHTLC ErrorCode = 4009
+	// ErrorCodeNotExistedDexOrder _
+	ErrorCodeNotExistedDexOrder ErrorCode = 4010
+	// ErrorCodeNotExistedPool _
+	ErrorCodeNotExistedPool ErrorCode = 4011
+	// ErrorCodeNotExistedConversion _
+	ErrorCodeNotExistedConversion ErrorCode = 4012
+	// ErrorCodeNotExistedDistribution _
+	ErrorCodeNotExistedDistribution ErrorCode = 4013
+	// ErrorCodeNotExistedUnwrap _
+	ErrorCodeNotExistedUnwrap ErrorCode = 4014
+	// ErrorCodeNotExistedFeeSponsorship _
+	ErrorCodeNotExistedFeeSponsorship ErrorCode = 4015
 )
 
+// errorCatalog is the map of error code and its name and response status
+var errorCatalog = map[ErrorCode]struct {
+	name   string
+	status int32
+}{
+	ErrorCodeInternal:                 {"INTERNAL", StatusInternalServerError},
+	ErrorCodeUnknownFunction:          {"UNKNOWN_FUNCTION", StatusBadRequest},
+	ErrorCodeInvalidParameter:         {"INVALID_PARAMETER", StatusBadRequest},
+	ErrorCodeInvalidAmount:            {"INVALID_AMOUNT", StatusBadRequest},
+	ErrorCodeInvalidAccountAddr:       {"INVALID_ACCOUNT_ADDRESS", StatusBadRequest},
+	ErrorCodeInvalidTokenCode:         {"INVALID_TOKEN_CODE", StatusBadRequest},
+	ErrorCodeNotEnoughBalance:         {"NOT_ENOUGH_BALANCE", StatusBadRequest},
+	ErrorCodeSuspendedAccount:         {"SUSPENDED_ACCOUNT", StatusBadRequest},
+	ErrorCodeTooManySigners:           {"TOO_MANY_SIGNERS", StatusBadRequest},
+	ErrorCodeHolderLimit:              {"HOLDER_LIMIT", StatusBadRequest},
+	ErrorCodeExistedAccount:           {"EXISTED_ACCOUNT", StatusBadRequest},
+	ErrorCodeExistedHolder:            {"EXISTED_HOLDER", StatusBadRequest},
+	ErrorCodeAlreadyIssuedToken:       {"ALREADY_ISSUED_TOKEN", StatusBadRequest},
+	ErrorCodeSupply:                   {"SUPPLY", StatusBadRequest},
+	ErrorCodeInvalidState:             {"INVALID_STATE", StatusBadRequest},
+	ErrorCodeDuplicateWrapComplete:    {"DUPLICATE_WRAP_COMPLETE", StatusBadRequest},
+	ErrorCodeDuplicateUnwrapComplete:  {"DUPLICATE_UNWRAP_COMPLETE", StatusBadRequest},
+	ErrorCodeExistedPool:              {"EXISTED_POOL", StatusBadRequest},
+	ErrorCodeSlippage:                 {"SLIPPAGE", StatusBadRequest},
+	ErrorCodeBridgeLimit:              {"BRIDGE_LIMIT", StatusBadRequest},
+	ErrorCodeUnauthenticated:          {"UNAUTHENTICATED", StatusUnauthorized},
+	ErrorCodeInvalidAccess:            {"INVALID_ACCESS", StatusForbidden},
+	ErrorCodeNotHolder:                {"NOT_HOLDER", StatusForbidden},
+	ErrorCodeNoAuthority:              {"NO_AUTHORITY", StatusForbidden},
+	ErrorCodeNotIssuedToken:           {"NOT_ISSUED_TOKEN", StatusNotFound},
+	ErrorCodeNotExistedAccount:        {"NOT_EXISTED_ACCOUNT", StatusNotFound},
+	ErrorCodeNotExistedHolder:         {"NOT_EXISTED_HOLDER", StatusNotFound},
+	ErrorCodeNotExistedPay:            {"NOT_EXISTED_PAY", StatusNotFound},
+	ErrorCodeNotExistedFee:            {"NOT_EXISTED_FEE", StatusNotFound},
+	ErrorCodeNotExistedPendingBalance: {"NOT_EXISTED_PENDING_BALANCE", StatusNotFound},
+	ErrorCodeNotInitLastPrunedFeeID:   {"NOT_INIT_LAST_PRUNED_FEE_ID", StatusNotFound},
+	ErrorCodeNotExistedWrap:           {"NOT_EXISTED_WRAP", StatusNotFound},
+	ErrorCodeNotExistedBalanceLog:     {"NOT_EXISTED_BALANCE_LOG", StatusNotFound},
+	ErrorCodeNotExistedHTLC:           {"NOT_EXISTED_HTLC", StatusNotFound},
+	ErrorCodeNotExistedDexOrder:       {"NOT_EXISTED_DEX_ORDER", StatusNotFound},
+	ErrorCodeNotExistedPool:           {"NOT_EXISTED_POOL", StatusNotFound},
+	ErrorCodeNotExistedConversion:     {"NOT_EXISTED_CONVERSION", StatusNotFound},
+	ErrorCodeNotExistedDistribution:   {"NOT_EXISTED_DISTRIBUTION", StatusNotFound},
+	ErrorCodeNotExistedUnwrap:         {"NOT_EXISTED_UNWRAP", StatusNotFound},
+	ErrorCodeNotExistedFeeSponsorship: {"NOT_EXISTED_FEE_SPONSORSHIP", StatusNotFound},
+}
+
+// Name returns the string code of the error code
+func (c ErrorCode) Name() string {
+	if e, ok := errorCatalog[c]; ok {
+		return e.name
+	}
+	return errorCatalog[ErrorCodeInternal].name
+}
+
+// Status returns the response status of the error code
+func (c ErrorCode) Status() int32 {
+	if e, ok := errorCatalog[c]; ok {
+		return e.status
+	}
+	return StatusInternalServerError
+}
+
+// ErrorResponse is the JSON error envelope of the response message
+type ErrorResponse struct {
+	Code    ErrorCode `json:"code"`
+	Name    string    `json:"name"`
+	Message string    `json:"message"`
+}
+
+// NewErrorResponse _
+func NewErrorResponse(code ErrorCode, msg string) *ErrorResponse {
+	return &ErrorResponse{
+		Code:    code,
+		Name:    code.Name(),
+		Message: msg,
+	}
+}
+
+// GetErrorCode returns the code of the error (or its cause). If the error has no code, it returns ErrorCodeInternal.
+func GetErrorCode(err error) ErrorCode {
+	if ce, ok := errors.Cause(err).(CodedError); ok {
+		return ce.Code()
+	}
+	return ErrorCodeInternal
+}
+
 // ResponsibleError is the interface used to distinguish responsible errors
 type ResponsibleError interface {
 	IsReponsible() bool
@@ -19,6 +219,12 @@ func (e ResponsibleErrorImpl) IsReponsible() bool {
 	return true
 }
 
+// CodedError is the interface of the error which has the error code
+type CodedError interface {
+	error
+	Code() ErrorCode
+}
+
 // NotIssuedTokenError _
 type NotIssuedTokenError struct {
 	ResponsibleErrorImpl
@@ -30,6 +236,11 @@ func (e NotIssuedTokenError) Error() string {
 	return fmt.Sprintf("the token [%s] is not issued", e.code)
 }
 
+// Code implements CodedError interface
+func (e NotIssuedTokenError) Code() ErrorCode {
+	return ErrorCodeNotIssuedToken
+}
+
 // NotInitLastPrunedFeeIDError is an error there is no LastPrunedFeeID state in the world state.
 type NotInitLastPrunedFeeIDError struct {
 	ResponsibleErrorImpl
@@ -40,6 +251,11 @@ func (e NotInitLastPrunedFeeIDError) Error() string {
 	return fmt.Sprintf("the last pruned fee id of the token [%s] is not initialized", e.tokenCode)
 }
 
+// Code implements CodedError interface
+func (e NotInitLastPrunedFeeIDError) Code() ErrorCode {
+	return ErrorCodeNotInitLastPrunedFeeID
+}
+
 // InvalidAccessError _
 type InvalidAccessError struct {
 	ResponsibleErrorImpl
@@ -50,6 +266,11 @@ func (e InvalidAccessError) Error() string {
 	return "invalid access"
 }
 
+// Code implements CodedError interface
+func (e InvalidAccessError) Code() ErrorCode {
+	return ErrorCodeInvalidAccess
+}
+
 // SupplyError _
 type SupplyError struct {
 	ResponsibleErrorImpl
@@ -61,6 +282,11 @@ func (e SupplyError) Error() string {
 	return e.reason
 }
 
+// Code implements CodedError interface
+func (e SupplyError) Code() ErrorCode {
+	return ErrorCodeSupply
+}
+
 // InvalidAccountAddrError _
 type InvalidAccountAddrError struct {
 	ResponsibleErrorImpl
@@ -75,6 +301,26 @@ func (e InvalidAccountAddrError) Error() string {
 	return "invalid account address"
 }
 
+// Code implements CodedError interface
+func (e InvalidAccountAddrError) Code() ErrorCode {
+	return ErrorCodeInvalidAccountAddr
+}
+
+// InvalidTokenCodeError _
+type InvalidTokenCodeError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e InvalidTokenCodeError) Error() string {
+	return "token code must be 3~6 length alphanum"
+}
+
+// Code implements CodedError interface
+func (e InvalidTokenCodeError) Code() ErrorCode {
+	return ErrorCodeInvalidTokenCode
+}
+
 // ExistedAccountError _
 type ExistedAccountError struct {
 	ResponsibleErrorImpl
@@ -86,6 +332,62 @@ func (e ExistedAccountError) Error() string {
 	return fmt.Sprintf("the account [%s] already exists", e.addr)
 }
 
+// Code implements CodedError interface
+func (e ExistedAccountError) Code() ErrorCode {
+	return ErrorCodeExistedAccount
+}
+
+// ExistedPoolError _
+type ExistedPoolError struct {
+	ResponsibleErrorImpl
+	id string
+}
+
+// Error implements error interface
+func (e ExistedPoolError) Error() string {
+	return fmt.Sprintf("the pool [%s] already exists", e.id)
+}
+
+// Code implements CodedError interface
+func (e ExistedPoolError) Code() ErrorCode {
+	return ErrorCodeExistedPool
+}
+
+// SlippageError _
+type SlippageError struct {
+	ResponsibleErrorImpl
+	reason string
+}
+
+// Error implements error interface
+func (e SlippageError) Error() string {
+	return "slippage limit exceeded: " + e.reason
+}
+
+// Code implements CodedError interface
+func (e SlippageError) Code() ErrorCode {
+	return ErrorCodeSlippage
+}
+
+// BridgeLimitError occurs when the wrap/unwrap amount exceeds the limits of the wrap policy.
+type BridgeLimitError struct {
+	ResponsibleErrorImpl
+	reason string
+}
+
+// Error implements error interface
+func (e BridgeLimitError) Error() string {
+	if len(e.reason) > 0 {
+		return "bridge limit exceeded: " + e.reason
+	}
+	return "bridge limit exceeded"
+}
+
+// Code implements CodedError interface
+func (e BridgeLimitError) Code() ErrorCode {
+	return ErrorCodeBridgeLimit
+}
+
 // NotExistedAccountError _
 type NotExistedAccountError struct {
 	ResponsibleErrorImpl
@@ -100,6 +402,11 @@ func (e NotExistedAccountError) Error() string {
 	return "the account does not exist"
 }
 
+// Code implements CodedError interface
+func (e NotExistedAccountError) Code() ErrorCode {
+	return ErrorCodeNotExistedAccount
+}
+
 // NotExistedPayError _
 type NotExistedPayError struct {
 	ResponsibleErrorImpl
@@ -114,6 +421,11 @@ func (e NotExistedPayError) Error() string {
 	return "the pay does not exist"
 }
 
+// Code implements CodedError interface
+func (e NotExistedPayError) Code() ErrorCode {
+	return ErrorCodeNotExistedPay
+}
+
 // NotExistedFeeError occurs when GetFeeState() got invalid fee id
 type NotExistedFeeError struct {
 	ResponsibleErrorImpl
@@ -128,6 +440,161 @@ func (e NotExistedFeeError) Error() string {
 	return "the fee does not exist"
 }
 
+// Code implements CodedError interface
+func (e NotExistedFeeError) Code() ErrorCode {
+	return ErrorCodeNotExistedFee
+}
+
+// NotExistedPendingBalanceError _
+type NotExistedPendingBalanceError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedPendingBalanceError) Error() string {
+	return "the pending balance is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedPendingBalanceError) Code() ErrorCode {
+	return ErrorCodeNotExistedPendingBalance
+}
+
+// NotExistedBalanceLogError _
+type NotExistedBalanceLogError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedBalanceLogError) Error() string {
+	return "the balance log is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedBalanceLogError) Code() ErrorCode {
+	return ErrorCodeNotExistedBalanceLog
+}
+
+// NotExistedHTLCError _
+type NotExistedHTLCError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedHTLCError) Error() string {
+	return "the htlc is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedHTLCError) Code() ErrorCode {
+	return ErrorCodeNotExistedHTLC
+}
+
+// NotExistedConversionError _
+type NotExistedConversionError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedConversionError) Error() string {
+	return "the conversion is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedConversionError) Code() ErrorCode {
+	return ErrorCodeNotExistedConversion
+}
+
+// NotExistedDistributionError _
+type NotExistedDistributionError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedDistributionError) Error() string {
+	return "the distribution is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedDistributionError) Code() ErrorCode {
+	return ErrorCodeNotExistedDistribution
+}
+
+// NotExistedDexOrderError _
+type NotExistedDexOrderError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedDexOrderError) Error() string {
+	return "the dex order is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedDexOrderError) Code() ErrorCode {
+	return ErrorCodeNotExistedDexOrder
+}
+
+// NotExistedPoolError _
+type NotExistedPoolError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedPoolError) Error() string {
+	return "the pool is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedPoolError) Code() ErrorCode {
+	return ErrorCodeNotExistedPool
+}
+
+// NotExistedWrapError _
+type NotExistedWrapError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedWrapError) Error() string {
+	return "wrap is not exist"
+}
+
+// Code implements CodedError interface
+func (e NotExistedWrapError) Code() ErrorCode {
+	return ErrorCodeNotExistedWrap
+}
+
+// NotExistedUnwrapError _
+type NotExistedUnwrapError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedUnwrapError) Error() string {
+	return "the unwrap is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedUnwrapError) Code() ErrorCode {
+	return ErrorCodeNotExistedUnwrap
+}
+
+// NotExistedFeeSponsorshipError _
+type NotExistedFeeSponsorshipError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotExistedFeeSponsorshipError) Error() string {
+	return "the fee sponsorship is not exists"
+}
+
+// Code implements CodedError interface
+func (e NotExistedFeeSponsorshipError) Code() ErrorCode {
+	return ErrorCodeNotExistedFeeSponsorship
+}
+
 // DuplicateWrapCompleteError occurs when wrap is already completed
 type DuplicateWrapCompleteError struct {
 	ResponsibleErrorImpl
@@ -138,6 +605,11 @@ func (e DuplicateWrapCompleteError) Error() string {
 	return "already completed wrap"
 }
 
+// Code implements CodedError interface
+func (e DuplicateWrapCompleteError) Code() ErrorCode {
+	return ErrorCodeDuplicateWrapComplete
+}
+
 // DuplicateUnwrapCompleteError occurs when unwrap is already completed
 type DuplicateUnwrapCompleteError struct {
 	ResponsibleErrorImpl
@@ -147,3 +619,144 @@ type DuplicateUnwrapCompleteError struct {
 func (e DuplicateUnwrapCompleteError) Error() string {
 	return "already completed unwrap"
 }
+
+// Code implements CodedError interface
+func (e DuplicateUnwrapCompleteError) Code() ErrorCode {
+	return ErrorCodeDuplicateUnwrapComplete
+}
+
+// InvalidParameterError _
+type InvalidParameterError struct {
+	ResponsibleErrorImpl
+	reason string
+}
+
+// Error implements error interface
+func (e InvalidParameterError) Error() string {
+	if len(e.reason) > 0 {
+		return e.reason
+	}
+	return "invalid parameter"
+}
+
+// Code implements CodedError interface
+func (e InvalidParameterError) Code() ErrorCode {
+	return ErrorCodeInvalidParameter
+}
+
+// InvalidAmountError _
+type InvalidAmountError struct {
+	ResponsibleErrorImpl
+	reason string
+}
+
+// Error implements error interface
+func (e InvalidAmountError) Error() string {
+	if len(e.reason) > 0 {
+		return e.reason
+	}
+	return "invalid amount"
+}
+
+// Code implements CodedError interface
+func (e InvalidAmountError) Code() ErrorCode {
+	return ErrorCodeInvalidAmount
+}
+
+// InvalidStateError occurs when the state does not allow the request. (ex, already suspended)
+type InvalidStateError struct {
+	ResponsibleErrorImpl
+	reason string
+}
+
+// Error implements error interface
+func (e InvalidStateError) Error() string {
+	if len(e.reason) > 0 {
+		return e.reason
+	}
+	return "invalid state"
+}
+
+// Code implements CodedError interface
+func (e InvalidStateError) Code() ErrorCode {
+	return ErrorCodeInvalidState
+}
+
+// NotEnoughBalanceError _
+type NotEnoughBalanceError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotEnoughBalanceError) Error() string {
+	return "not enough balance"
+}
+
+// Code implements CodedError interface
+func (e NotEnoughBalanceError) Code() ErrorCode {
+	return ErrorCodeNotEnoughBalance
+}
+
+// NotHolderError occurs when the invoker is not a holder of the account
+type NotHolderError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NotHolderError) Error() string {
+	return "invoker is not holder"
+}
+
+// Code implements CodedError interface
+func (e NotHolderError) Code() ErrorCode {
+	return ErrorCodeNotHolder
+}
+
+// NoAuthorityError occurs when the invoker has no authority of the function
+type NoAuthorityError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e NoAuthorityError) Error() string {
+	return "no authority"
+}
+
+// Code implements CodedError interface
+func (e NoAuthorityError) Code() ErrorCode {
+	return ErrorCodeNoAuthority
+}
+
+// SuspendedAccountError _
+type SuspendedAccountError struct {
+	ResponsibleErrorImpl
+	role string // sender, receiver, wrap ...
+}
+
+// Error implements error interface
+func (e SuspendedAccountError) Error() string {
+	if len(e.role) > 0 {
+		return fmt.Sprintf("the %s account is suspended", e.role)
+	}
+	return "the account is suspended"
+}
+
+// Code implements CodedError interface
+func (e SuspendedAccountError) Code() ErrorCode {
+	return ErrorCodeSuspendedAccount
+}
+
+// TooManySignersError _
+type TooManySignersError struct {
+	ResponsibleErrorImpl
+}
+
+// Error implements error interface
+func (e TooManySignersError) Error() string {
+	return "too many signers"
+}
+
+// Code implements CodedError interface
+func (e TooManySignersError) Code() ErrorCode {
+	return ErrorCodeTooManySigners
+}
@@ -0,0 +1,291 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// ExtChain validates and normalizes the address and the tx id of the external chain. (see WrapPolicy.ExtChain)
+type ExtChain interface {
+	NormalizeAddress(addr string) (string, error)
+	NormalizeTxID(txid string) (string, error)
+}
+
+// extChains is the map of the chain name (lower case) and its validator.
+// The unknown chains (including the legacy policies) are EVM chains.
+var extChains = map[string]ExtChain{
+	"bitcoin":         &bitcoinChain{hrp: "bc", versions: []byte{0x00, 0x05}},
+	"btc":             &bitcoinChain{hrp: "bc", versions: []byte{0x00, 0x05}},
+	"bitcoin-testnet": &bitcoinChain{hrp: "tb", versions: []byte{0x6f, 0xc4}},
+	"litecoin":        &bitcoinChain{hrp: "ltc", versions: []byte{0x30, 0x32, 0x05}},
+	"ltc":             &bitcoinChain{hrp: "ltc", versions: []byte{0x30, 0x32, 0x05}},
+	"dogecoin":        &bitcoinChain{versions: []byte{0x1e, 0x16}},
+	"doge":            &bitcoinChain{versions: []byte{0x1e, 0x16}},
+	"tron":            &tronChain{},
+	"trx":             &tronChain{},
+	"solana":          &solanaChain{},
+	"sol":             &solanaChain{},
+}
+
+// GetExtChain returns the validator of the chain.
+func GetExtChain(chain string) ExtChain {
+	if c, ok := extChains[strings.ToLower(chain)]; ok {
+		return c
+	}
+	return &evmChain{}
+}
+
+// evmChain : 0x-prefixed addresses with EIP-55 checksums and 32 bytes hex tx hashes
+type evmChain struct{}
+
+func (c *evmChain) NormalizeAddress(addr string) (string, error) {
+	if err := validateEOA(addr); err != nil {
+		return "", err
+	}
+	return strings.ToLower(addr), nil
+}
+
+func (c *evmChain) NormalizeTxID(txid string) (string, error) {
+	txid = strings.ToLower(txid)
+	if err := validateExtTxID(txid); err != nil {
+		return "", err
+	}
+	return txid, nil
+}
+
+// bitcoinChain : base58check (P2PKH, P2SH) and bech32/bech32m (segwit) addresses
+type bitcoinChain struct {
+	hrp      string // segwit human readable part. empty if the chain has no segwit.
+	versions []byte // base58check version bytes
+}
+
+func (c *bitcoinChain) NormalizeAddress(addr string) (string, error) {
+	if len(c.hrp) > 0 && strings.HasPrefix(strings.ToLower(addr), c.hrp+"1") {
+		return normalizeSegwitAddress(c.hrp, addr)
+	}
+	payload, err := base58CheckDecode(addr)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) != 21 || bytes.IndexByte(c.versions, payload[0]) < 0 {
+		return "", errors.New("address format error")
+	}
+	return addr, nil // base58 is case sensitive
+}
+
+func (c *bitcoinChain) NormalizeTxID(txid string) (string, error) {
+	return normalizeHexTxID(txid)
+}
+
+// tronChain : base58check T-addresses (0x41 version byte)
+type tronChain struct{}
+
+func (c *tronChain) NormalizeAddress(addr string) (string, error) {
+	payload, err := base58CheckDecode(addr)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) != 21 || payload[0] != 0x41 {
+		return "", errors.New("address format error")
+	}
+	return addr, nil
+}
+
+func (c *tronChain) NormalizeTxID(txid string) (string, error) {
+	return normalizeHexTxID(txid)
+}
+
+// solanaChain : base58 ed25519 public keys and base58 signatures
+type solanaChain struct{}
+
+func (c *solanaChain) NormalizeAddress(addr string) (string, error) {
+	key, err := base58Decode(addr)
+	if err != nil {
+		return "", err
+	}
+	if len(key) != 32 {
+		return "", errors.New("address format error")
+	}
+	return addr, nil
+}
+
+func (c *solanaChain) NormalizeTxID(txid string) (string, error) {
+	sig, err := base58Decode(txid)
+	if err != nil {
+		return "", errors.New("txid format error")
+	}
+	if len(sig) != 64 {
+		return "", errors.New("txid format error")
+	}
+	return txid, nil
+}
+
+var isValidHexTxIDFormat = regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString
+
+// normalizeHexTxID normalizes 32 bytes hex tx id without the prefix. (bitcoin, tron)
+func normalizeHexTxID(txid string) (string, error) {
+	txid = strings.ToLower(txid)
+	if !isValidHexTxIDFormat(txid) {
+		return "", errors.New("txid format error")
+	}
+	return txid, nil
+}
+
+// base58
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Decode decodes the base58 (bitcoin alphabet) string. The leading '1's are zero bytes.
+func base58Decode(s string) ([]byte, error) {
+	if len(s) == 0 {
+		return nil, errors.New("address format error")
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, r := range s {
+		i := strings.IndexRune(base58Alphabet, r)
+		if i < 0 {
+			return nil, errors.New("address format error")
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// base58CheckDecode decodes the base58check string and verifies the checksum. (double SHA-256)
+// It returns the payload including the version byte.
+func base58CheckDecode(s string) ([]byte, error) {
+	data, err := base58Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 5 {
+		return nil, errors.New("address format error")
+	}
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	h := sha256.Sum256(payload)
+	h = sha256.Sum256(h[:])
+	if !bytes.Equal(h[:4], checksum) {
+		return nil, errors.New("address checksum error")
+	}
+	return payload, nil
+}
+
+// bech32 (BIP-173) and bech32m (BIP-350)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	values := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	return values
+}
+
+// bech32Decode decodes the bech32/bech32m string and verifies the checksum.
+// It returns the lower cased hrp, the 5-bit data without the checksum and the checksum constant.
+func bech32Decode(s string) (string, []byte, uint32, error) {
+	if len(s) > 90 || (strings.ToLower(s) != s && strings.ToUpper(s) != s) { // mixed case
+		return "", nil, 0, errors.New("address format error")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, 0, errors.New("address format error")
+	}
+	hrp := s[:pos]
+	data := make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, 0, errors.New("address format error")
+		}
+		data = append(data, byte(v))
+	}
+	c := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if c != bech32Const && c != bech32mConst {
+		return "", nil, 0, errors.New("address checksum error")
+	}
+	return hrp, data[:len(data)-6], c, nil
+}
+
+// convertBits regroups the bits (5-bit groups to 8-bit bytes) without padding.
+func convertBits(data []byte, from, to uint) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	out := []byte{}
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if bits >= from || (acc<<(to-bits))&maxv != 0 {
+		return nil, errors.New("address format error")
+	}
+	return out, nil
+}
+
+// normalizeSegwitAddress validates the segwit address and returns it in lower case.
+func normalizeSegwitAddress(hrp, addr string) (string, error) {
+	dhrp, data, c, err := bech32Decode(addr)
+	if err != nil {
+		return "", err
+	}
+	if dhrp != hrp || len(data) < 1 || data[0] > 16 {
+		return "", errors.New("address format error")
+	}
+	program, err := convertBits(data[1:], 5, 8)
+	if err != nil {
+		return "", err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return "", errors.New("address format error")
+	}
+	if data[0] == 0 { // v0 : P2WPKH, P2WSH with bech32
+		if (len(program) != 20 && len(program) != 32) || c != bech32Const {
+			return "", errors.New("address format error")
+		}
+	} else if c != bech32mConst { // v1+ : bech32m
+		return "", errors.New("address format error")
+	}
+	return strings.ToLower(addr), nil
+}
@@ -0,0 +1,108 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"testing"
+)
+
+var extAddresses = []struct {
+	chain      string
+	addr       string
+	normalized string // empty if the address is invalid
+}{
+	// base58check
+	{"bitcoin", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"}, // P2PKH
+	{"bitcoin", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"}, // P2SH
+	{"bitcoin", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", ""},                                   // checksum
+	{"bitcoin", "1a1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", ""},                                   // case sensitive
+	{"bitcoin", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfN0", ""},                                   // not in the alphabet
+	{"bitcoin", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", ""},                                   // testnet version
+	{"bitcoin-testnet", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"},
+	{"litecoin", "LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1", "LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1"},
+	{"doge", "DH5yaieqoZN36fDVciNyRueRGvGLR3mr7L", "DH5yaieqoZN36fDVciNyRueRGvGLR3mr7L"},     // P2PKH
+	{"dogecoin", "9rSHsR8xxKEkKW8Tbv3SGBdiwnQGWZ4bdM", "9rSHsR8xxKEkKW8Tbv3SGBdiwnQGWZ4bdM"}, // P2SH
+	{"doge", "DH5yaieqoZN36fDVciNyRueRGvGLR3mr7M", ""},                                       // checksum
+	{"doge", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", ""},                                       // bitcoin version
+	{"doge", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", ""},                               // no segwit
+	// bech32 (BIP-173) and bech32m (BIP-350)
+	{"bitcoin", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+	{"bitcoin-testnet", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+	{"bitcoin", "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y"},
+	{"bitcoin", "BC1SW50QGDZ25J", "bc1sw50qgdz25j"},
+	{"bitcoin", "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs"},
+	{"bitcoin-testnet", "tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy"},
+	{"bitcoin-testnet", "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c"},
+	{"bitcoin", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+	{"bitcoin", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", ""},                             // checksum
+	{"bitcoin", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", ""},         // v1 with bech32
+	{"bitcoin", "BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL", ""},         // v16 with bech32
+	{"bitcoin", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", ""},                             // v0 with bech32m
+	{"bitcoin-testnet", "tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", ""}, // v0 with bech32m
+	{"bitcoin", "bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", ""},         // not in the charset
+	{"bitcoin", "BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", ""},         // witness version 17
+	{"bitcoin", "bc1pw5dgrnzv", ""}, // program length 1
+	{"bitcoin", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", ""}, // program length 41
+	{"bitcoin-testnet", "tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", ""},       // mixed case
+	{"bitcoin", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", ""},               // testnet hrp
+	{"bitcoin-testnet", "tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", ""},       // unknown hrp
+	// tron
+	{"tron", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"},
+	{"trx", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", ""},  // checksum
+	{"tron", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", ""}, // bitcoin version
+	// solana (no checksum)
+	{"solana", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"},
+	{"sol", "11111111111111111111111111111111", "11111111111111111111111111111111"},
+	{"solana", "14wBqpZM9xaSheZzJSMawUKKwhdpChKbZ5eu5ky4Vigw", ""}, // 33 bytes
+	{"solana", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5D0", ""},  // not in the alphabet
+	// evm (unknown chains)
+	{"ethereum", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+	{"", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", ""}, // EIP-55 checksum
+}
+
+func TestExtChainNormalizeAddress(t *testing.T) {
+	for _, c := range extAddresses {
+		normalized, err := GetExtChain(c.chain).NormalizeAddress(c.addr)
+		if len(c.normalized) == 0 {
+			if err == nil {
+				t.Errorf("%s: NormalizeAddress(%s) = %s, want error", c.chain, c.addr, normalized)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NormalizeAddress(%s) failed: %s", c.chain, c.addr, err)
+		} else if normalized != c.normalized {
+			t.Errorf("%s: NormalizeAddress(%s) = %s, want %s", c.chain, c.addr, normalized, c.normalized)
+		}
+	}
+}
+
+var extTxIDs = []struct {
+	chain      string
+	txid       string
+	normalized string // empty if the tx id is invalid
+}{
+	{"bitcoin", "4A5E1E4BAAB89F3A32518A88C31BC87F618F76673E2CC77AB2127B7AFDEDA33B", "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+	{"bitcoin", "0x4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", ""}, // prefix
+	{"tron", "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda3", ""},        // 31 bytes
+	{"solana", "1GMkH3brNXiNNs1tiFZHu4yZSRrzJwxi5wB9bHFtMinfCXNnR1adh8Vo8NTheK4evneedH4qmvjeqcBBNAefgS", "1GMkH3brNXiNNs1tiFZHu4yZSRrzJwxi5wB9bHFtMinfCXNnR1adh8Vo8NTheK4evneedH4qmvjeqcBBNAefgS"},
+	{"solana", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", ""}, // 32 bytes
+	{"", "0x4A5E1E4BAAB89F3A32518A88C31BC87F618F76673E2CC77AB2127B7AFDEDA33B", "0x4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+}
+
+func TestExtChainNormalizeTxID(t *testing.T) {
+	for _, c := range extTxIDs {
+		normalized, err := GetExtChain(c.chain).NormalizeTxID(c.txid)
+		if len(c.normalized) == 0 {
+			if err == nil {
+				t.Errorf("%s: NormalizeTxID(%s) = %s, want error", c.chain, c.txid, normalized)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NormalizeTxID(%s) failed: %s", c.chain, c.txid, err)
+		} else if normalized != c.normalized {
+			t.Errorf("%s: NormalizeTxID(%s) = %s, want %s", c.chain, c.txid, normalized, c.normalized)
+		}
+	}
+}
@@ -20,10 +20,112 @@ type Fee struct {
 	CreatedTime *txtime.Time `json:"created_time"`
 }
 
+// FeeBucket accumulates the fees of the account in a time bucket. (see SumBucketSize)
+// An account has only one fee in a tx, and its balance is put in the same tx.
+type FeeBucket struct {
+	DOCTYPEID string `json:"@fee_bucket"` // token code
+	Account   string `json:"account"`
+	Start     int64  `json:"start"` // unix seconds
+	Sum       Amount `json:"sum"`
+	Count     int    `json:"count"`
+	FirstID   string `json:"first_id"`
+	LastID    string `json:"last_id"`
+}
+
+// Add accumulates the fee.
+func (b *FeeBucket) Add(fee *Fee) {
+	b.Sum.Add(&fee.Amount)
+	b.Count++
+	if len(b.FirstID) == 0 || fee.FeeID < b.FirstID {
+		b.FirstID = fee.FeeID
+	}
+	if fee.FeeID > b.LastID {
+		b.LastID = fee.FeeID
+	}
+}
+
+// merge accumulates the other bucket of the same start.
+func (b *FeeBucket) merge(o *FeeBucket) {
+	b.Sum.Add(&o.Sum)
+	b.Count += o.Count
+	if o.FirstID < b.FirstID {
+		b.FirstID = o.FirstID
+	}
+	if o.LastID > b.LastID {
+		b.LastID = o.LastID
+	}
+}
+
 // FeePolicy _
 type FeePolicy struct {
 	TargetAddress string             `json:"target_address"`
 	Rates         map[string]FeeRate `json:"rates"`
+	Beneficiaries []FeeBeneficiary   `json:"beneficiaries,omitempty"` // receivers of the pruned fees. empty is all to the target address.
+}
+
+// FeeBeneficiariesMax _
+const FeeBeneficiariesMax = 10
+
+// FeeBeneficiary is a receiver of the pruned fees.
+type FeeBeneficiary struct {
+	Address string `json:"address"`
+	Share   int64  `json:"share"` // ratio to the total shares of the beneficiaries
+}
+
+// FeeShare is the amount of the pruned fees distributed to the beneficiary.
+type FeeShare struct {
+	Address string  `json:"address"`
+	Amount  *Amount `json:"amount"`
+}
+
+// ParseFeeBeneficiaries parses fee beneficiaries format string. "address=share;address=share..."
+func ParseFeeBeneficiaries(s string) ([]FeeBeneficiary, error) {
+	beneficiaries := []FeeBeneficiary{}
+	for _, b := range strings.Split(s, ";") {
+		if b = strings.TrimSpace(b); len(b) == 0 {
+			continue
+		}
+		kv := strings.Split(b, "=")
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, errors.New("failed to parse fee beneficiary")
+		}
+		share, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil || share <= 0 {
+			return nil, errors.New("fee beneficiary share must be positive integer")
+		}
+		for _, o := range beneficiaries {
+			if o.Address == kv[0] {
+				return nil, errors.New("duplicated fee beneficiary")
+			}
+		}
+		beneficiaries = append(beneficiaries, FeeBeneficiary{Address: kv[0], Share: share})
+	}
+	if len(beneficiaries) > FeeBeneficiariesMax {
+		return nil, errors.Errorf("too many fee beneficiaries, max %d", FeeBeneficiariesMax)
+	}
+	return beneficiaries, nil
+}
+
+// Distribute divides the amount by the shares of the beneficiaries. (if there is no beneficiary, all to the target address)
+// Each share is rounded down, and the remainder goes to the first beneficiary.
+func (p *FeePolicy) Distribute(amount *Amount) []*FeeShare {
+	if len(p.Beneficiaries) == 0 {
+		return []*FeeShare{{Address: p.TargetAddress, Amount: amount.Copy()}}
+	}
+	total := big.NewInt(0)
+	for _, b := range p.Beneficiaries {
+		total.Add(total, big.NewInt(b.Share))
+	}
+	shares := make([]*FeeShare, len(p.Beneficiaries))
+	remainder := amount.Copy()
+	for i, b := range p.Beneficiaries {
+		v := new(big.Int).Mul(&amount.Int, big.NewInt(b.Share))
+		v.Quo(v, total)
+		shares[i] = &FeeShare{Address: b.Address, Amount: NewAmountWithBigInt(v)}
+		remainder.Add(shares[i].Amount.Copy().Neg())
+	}
+	shares[0].Amount.Add(remainder)
+	return shares
 }
 
 // isValidFn returns true if given fn is defined.
@@ -31,6 +133,10 @@ func isValidFn(fn string) bool {
 	switch fn {
 	case "transfer":
 		fallthrough
+	case "dex/maker":
+		fallthrough
+	case "dex/taker":
+		fallthrough
 	case "pay": // All valid fee rate type case should fallthrough here, the last one.
 		return true
 	}
@@ -45,7 +151,7 @@ func ParseFeePolicy(s string) (policy *FeePolicy, err error) {
 	for _, f := range fees {
 		kv := strings.Split(f, "=")
 		if len(kv) > 1 {
-			// We limit fn(= kv[0]) to one of "transfer" or "pay".
+			// We limit fn(= kv[0]) to one of "transfer", "pay", "dex/maker" or "dex/taker".
 			if valid := isValidFn(kv[0]); !valid {
 				return nil, errors.New("invalid fee rate type")
 			}
@@ -55,15 +161,23 @@ func ParseFeePolicy(s string) (policy *FeePolicy, err error) {
 				return nil, errors.New("failed to parse rate")
 			}
 			max := int64(0)
-			if len(rm) > 1 {
+			if len(rm) > 1 && len(rm[1]) > 0 {
 				max, err = strconv.ParseInt(rm[1], 10, 64)
 				if err != nil {
 					return nil, errors.New("failed to parse max fee amount")
 				}
 			}
+			bearer := FeeBearerDefault
+			if len(rm) > 2 {
+				// the sponsor is selected per transaction only
+				if bearer, err = ParseFeeBearer(rm[2]); err != nil || bearer == FeeBearerSponsor {
+					return nil, errors.New("invalid fee bearer of the rate")
+				}
+			}
 			rates[kv[0]] = FeeRate{
 				Rate:      rate,
 				MaxAmount: max,
+				Bearer:    bearer,
 			}
 		}
 	}
@@ -75,8 +189,68 @@ func ParseFeePolicy(s string) (policy *FeePolicy, err error) {
 
 // FeeRate _
 type FeeRate struct {
-	Rate      string `json:"rate"`       // numeric string of positive decimal fraction
-	MaxAmount int64  `json:"max_amount"` // 0 is unlimit
+	Rate      string    `json:"rate"`             // numeric string of positive decimal fraction
+	MaxAmount int64     `json:"max_amount"`       // 0 is unlimit
+	Bearer    FeeBearer `json:"bearer,omitempty"` // default bearer of the fn (see FeePolicy.GetBearer)
+}
+
+// FeeBearer is the account which bears the fee.
+type FeeBearer int8
+
+const (
+	// FeeBearerDefault the default bearer of the fn. (transfer: sender, pay: receiver)
+	FeeBearerDefault FeeBearer = iota
+	// FeeBearerSender the fee is charged to the sender on top of the amount
+	FeeBearerSender
+	// FeeBearerReceiver the fee is deducted from the amount which the receiver receives
+	FeeBearerReceiver
+	// FeeBearerSponsor the fee is charged to the sponsor account (see FeeSponsorship)
+	FeeBearerSponsor
+)
+
+var feeBearerNames = []string{"default", "sender", "receiver", "sponsor"}
+
+// ParseFeeBearer parses the bearer name (or the number). Empty string is the default.
+func ParseFeeBearer(s string) (FeeBearer, error) {
+	if len(s) == 0 {
+		return FeeBearerDefault, nil
+	}
+	s = strings.ToLower(s)
+	for i, name := range feeBearerNames {
+		if s == name || s == strconv.Itoa(i) {
+			return FeeBearer(i), nil
+		}
+	}
+	return 0, errors.New("invalid fee bearer")
+}
+
+// String _
+func (b FeeBearer) String() string {
+	if b < 0 || int(b) >= len(feeBearerNames) {
+		return strconv.Itoa(int(b))
+	}
+	return feeBearerNames[b]
+}
+
+// GetBearer returns the bearer of the fn fee. If the rate has no bearer, it returns the default bearer of the fn.
+// The policy can be nil.
+func (p *FeePolicy) GetBearer(fn string) FeeBearer {
+	if p != nil {
+		if rate, ok := p.Rates[fn]; ok && rate.Bearer != FeeBearerDefault {
+			return rate.Bearer
+		}
+	}
+	if fn == "pay" { // the fee of the pay is deducted from the merchant when the pays are pruned
+		return FeeBearerReceiver
+	}
+	return FeeBearerSender
+}
+
+// FeeSponsorship is the authorization of the sponsor account to bear the fees of the account.
+type FeeSponsorship struct {
+	DOCTYPEID   string       `json:"@fee_sponsorship"` // sponsor address
+	Account     string       `json:"account"`          // sponsored account address
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
 }
 
 // FeeSum stands for amount&state of accumulated fee from Start to End
@@ -87,3 +261,29 @@ type FeeSum struct {
 	End     string  `json:"end_id"`
 	HasMore bool    `json:"has_more"`
 }
+
+// add accumulates the fees from firstID to lastID, which are after the End.
+func (s *FeeSum) add(firstID, lastID string, sum *Amount, count int) {
+	if len(s.Start) == 0 {
+		s.Start = firstID
+	}
+	s.End = lastID
+	s.Sum.Add(sum)
+	s.Count += count
+}
+
+// FeePruneResult is the response payload of fee/prune.
+type FeePruneResult struct {
+	*FeeSum
+	Shares      []*FeeShare   `json:"shares"`
+	BalanceLogs []*BalanceLog `json:"balance_logs"` // the logs of the beneficiaries (zero shares are skipped)
+}
+
+// FeePrunePreview is the response payload of fee/prune/preview. (what fee/prune would prune)
+type FeePrunePreview struct {
+	*FeeSum
+	Address   string       `json:"address"`    // target address of the fee policy
+	StartTime *txtime.Time `json:"start_time"` // created time of the last pruned fee (exclusive)
+	EndTime   *txtime.Time `json:"end_time"`
+	Shares    []*FeeShare  `json:"shares,omitempty"`
+}
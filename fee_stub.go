@@ -37,6 +37,12 @@ func (fb *FeeStub) CreateKey(id string) string {
 // CreateFee creates new fee utxo of given amount and puts the state.
 // If give amount is zero, it puts nothing and returns nil.
 func (fb *FeeStub) CreateFee(addr string, amount Amount) (*Fee, error) {
+	return fb.CreateFeeWithSeq(addr, amount, 0)
+}
+
+// CreateFeeWithSeq creates new fee utxo like CreateFee.
+// The sequence distinguishes the fees in a transaction, because the fee ID is based on the tx time and id.
+func (fb *FeeStub) CreateFeeWithSeq(addr string, amount Amount, seq int) (*Fee, error) {
 	if amount.Sign() == 0 {
 		return nil, nil
 	}
@@ -54,6 +60,9 @@ func (fb *FeeStub) CreateFee(addr string, amount Amount) (*Fee, error) {
 		Amount:      amount,
 		CreatedTime: ts,
 	}
+	if seq > 0 {
+		fee.FeeID = fmt.Sprintf("%s_%d", fee.FeeID, seq)
+	}
 	err = fb.PutFee(fee)
 	if nil != err {
 		return nil, errors.Wrap(err, "failed to create fee")
@@ -62,7 +71,16 @@ func (fb *FeeStub) CreateFee(addr string, amount Amount) (*Fee, error) {
 	return fee, nil
 }
 
-// PutFee _
+// CreateBucketKey _ (if account is empty, it returns the range key of the buckets)
+func (fb *FeeStub) CreateBucketKey(tokenCode string, start int64, account string) string {
+	key := "FEEB_" + tokenCode + "_" + sumBucketKeyTime(start)
+	if account != "" {
+		key += "_" + account
+	}
+	return key
+}
+
+// PutFee puts the new fee and adds it to the fee bucket.
 func (fb *FeeStub) PutFee(fee *Fee) error {
 	data, err := json.Marshal(fee)
 	if nil != err {
@@ -72,6 +90,33 @@ func (fb *FeeStub) PutFee(fee *Fee) error {
 	if nil != err {
 		return errors.Wrap(err, "failed to put the fee state")
 	}
+	return fb.putFeeBucket(fee)
+}
+
+// putFeeBucket adds the fee to the bucket of the account.
+func (fb *FeeStub) putFeeBucket(fee *Fee) error {
+	start := sumBucketStart(fee.CreatedTime)
+	key := fb.CreateBucketKey(fee.DOCTYPEID, start, fee.Account)
+	data, err := fb.stub.GetState(key)
+	if nil != err {
+		return errors.Wrap(err, "failed to get the fee bucket state")
+	}
+	bucket := &FeeBucket{}
+	if nil == data {
+		if err = NewSumBucketStub(fb.stub).EnsureOrigin(fee.DOCTYPEID, fee.CreatedTime); nil != err {
+			return err
+		}
+		bucket = &FeeBucket{DOCTYPEID: fee.DOCTYPEID, Account: fee.Account, Start: start}
+	} else if err = json.Unmarshal(data, bucket); nil != err {
+		return errors.Wrap(err, "failed to unmarshal the fee bucket")
+	}
+	bucket.Add(fee)
+	if data, err = json.Marshal(bucket); nil != err {
+		return errors.Wrap(err, "failed to marshal the fee bucket")
+	}
+	if err = fb.stub.PutState(key, data); nil != err {
+		return errors.Wrap(err, "failed to put the fee bucket state")
+	}
 	return nil
 }
 
@@ -98,52 +143,151 @@ func (fb *FeeStub) GetQueryFees(tokenCode, bookmark string, fetchSize int, stime
 }
 
 // GetFeeSumByTime returns FeeSum from stime to etime.
+// The fees in the complete buckets are summed by the buckets, and the others are summed by the query. (see FeeBucket)
 func (fb *FeeStub) GetFeeSumByTime(tokenCode string, stime, etime *txtime.Time) (*FeeSum, error) {
+	origin, err := NewSumBucketStub(fb.stub).GetOrigin(tokenCode)
+	if nil != err {
+		return nil, err
+	}
+
+	feeSum := &FeeSum{Sum: ZeroAmount(), HasMore: false}
+	bstart, bend := sumBucketRange(origin, stime, etime)
+	if bstart >= bend { // no complete bucket
+		if _, err = fb.sumFeesByQuery(feeSum, tokenCode, stime, etime, FeePruneSize); nil != err {
+			return nil, err
+		}
+		return feeSum, nil
+	}
+
+	headEnd, tailStart := sumBucketBoundaries(bstart, bend)
+	cnt, err := fb.sumFeesByQuery(feeSum, tokenCode, stime, headEnd, FeePruneSize)
+	if nil != err {
+		return nil, err
+	}
+	if !feeSum.HasMore {
+		if err = fb.sumFeeBuckets(feeSum, tokenCode, bstart, bend); nil != err {
+			return nil, err
+		}
+	}
+	if !feeSum.HasMore {
+		if _, err = fb.sumFeesByQuery(feeSum, tokenCode, tailStart, etime, FeePruneSize-cnt); nil != err {
+			return nil, err
+		}
+	}
+	return feeSum, nil
+}
+
+// sumFeesByQuery adds the fees from stime to etime to the sum, and returns the number of the added fees.
+// If there are more fees than the limit, it sets HasMore.
+// A transaction can create several fees of the same created time (see FeeStub.CreateFeeWithSeq),
+// and the next prune starts after the created time of the last fee. So it never stops inside the fees of the same time,
+// and it can add a few more fees than the limit.
+func (fb *FeeStub) sumFeesByQuery(feeSum *FeeSum, tokenCode string, stime, etime *txtime.Time, limit int) (int, error) {
 	query := CreateQueryPruneFee(tokenCode, stime, etime)
 	iter, err := fb.stub.GetQueryResult(query)
 	if nil != err {
-		return nil, err
+		return 0, err
 	}
 	defer iter.Close()
 
-	feeSum := &FeeSum{HasMore: false}
-	fee := &Fee{}
 	cnt := 0
-	sum := ZeroAmount()
-
+	var lastTime *txtime.Time // created time of the last added fee
 	for iter.HasNext() {
-		cnt++
 		kv, err := iter.Next()
 		if nil != err {
-			return nil, err
-		}
-		err = json.Unmarshal(kv.Value, fee)
-		if nil != err {
-			return nil, err
+			return 0, err
 		}
-		if 1 == cnt {
-			feeSum.Start = fee.FeeID
+		fee := &Fee{}
+		if err = json.Unmarshal(kv.Value, fee); nil != err {
+			return 0, err
 		}
-		if cnt > FeePruneSize {
+		if cnt >= limit && (lastTime == nil || fee.CreatedTime.Cmp(lastTime) != 0) {
 			feeSum.HasMore = true
-			cnt--
 			break
 		}
-		sum = sum.Add(&fee.Amount)
-		feeSum.End = fee.FeeID
+		cnt++
+		lastTime = fee.CreatedTime
+		feeSum.add(fee.FeeID, fee.FeeID, &fee.Amount, 1)
 	}
-	feeSum.Count = cnt
-	feeSum.Sum = sum
-	return feeSum, nil
+	return cnt, nil
 }
 
-// CalcFee returns calculated fee amount from transfer/pay amount
+// sumFeeBuckets adds the buckets [bstart, bend) to the sum.
+// If there are more buckets than SumBucketsPruneSize, it stops at the bucket boundary and sets HasMore.
+func (fb *FeeStub) sumFeeBuckets(feeSum *FeeSum, tokenCode string, bstart, bend int64) error {
+	iter, err := fb.stub.GetStateByRange(fb.CreateBucketKey(tokenCode, bstart, ""), fb.CreateBucketKey(tokenCode, bend, ""))
+	if nil != err {
+		return errors.Wrap(err, "failed to get the fee buckets")
+	}
+	defer iter.Close()
+
+	read := 0
+	var group *FeeBucket // merged buckets of the same start
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if nil != err {
+			return err
+		}
+		b := &FeeBucket{}
+		if err = json.Unmarshal(kv.Value, b); nil != err {
+			return errors.Wrap(err, "failed to unmarshal the fee bucket")
+		}
+		if group != nil && group.Start != b.Start {
+			feeSum.add(group.FirstID, group.LastID, &group.Sum, group.Count)
+			group = nil
+			if read >= SumBucketsPruneSize {
+				feeSum.HasMore = true
+				return nil
+			}
+		}
+		read++
+		if group == nil {
+			group = b
+		} else {
+			group.merge(b)
+		}
+	}
+	if group != nil {
+		feeSum.add(group.FirstID, group.LastID, &group.Sum, group.Count)
+	}
+	return nil
+}
+
+// CalcFee returns calculated fee amount from transfer/pay amount. The payer is the bearer of the fee.
 func (fb *FeeStub) CalcFee(payer *Address, fn string, amount Amount) (*Amount, error) {
 	token, err := NewTokenStub(fb.stub).GetToken(payer.Code)
 	if err != nil {
 		return nil, err
 	}
+	return calcFee(token, payer, fn, amount), nil
+}
 
+// CalcBearerFee resolves the bearer of the fee and returns the fee amount calculated for the bearer.
+// If the bearer is FeeBearerDefault, the bearer of the fee rate is used. (see FeePolicy.GetBearer)
+// The sponsor is used only if the bearer is FeeBearerSponsor.
+func (fb *FeeStub) CalcBearerFee(sender, receiver, sponsor *Address, fn string, amount Amount, bearer FeeBearer) (*Amount, FeeBearer, error) {
+	token, err := NewTokenStub(fb.stub).GetToken(receiver.Code)
+	if err != nil {
+		return nil, bearer, err
+	}
+	if bearer == FeeBearerDefault {
+		bearer = token.FeePolicy.GetBearer(fn)
+	}
+	payer := sender
+	switch bearer {
+	case FeeBearerReceiver:
+		payer = receiver
+	case FeeBearerSponsor:
+		if sponsor == nil {
+			return nil, bearer, InvalidParameterError{reason: "sponsor is required"}
+		}
+		payer = sponsor
+	}
+	return calcFee(token, payer, fn, amount), bearer, nil
+}
+
+// calcFee returns the fee amount of the payer by the fee policy of the token.
+func calcFee(token *Token, payer *Address, fn string, amount Amount) *Amount {
 	if token.FeePolicy != nil {
 		logger.Debug(token.FeePolicy)
 		feeRate, ok := token.FeePolicy.Rates[fn]
@@ -156,18 +300,59 @@ func (fb *FeeStub) CalcFee(payer *Address, fn string, amount Amount) (*Amount, e
 				// feeAmount = amount * rate
 				feeAmount := amount.Copy().MulRat(feeRateRat)
 				if feeAmount.Sign() < 0 { // fee must be zero or positive
-					return ZeroAmount(), nil
+					return ZeroAmount()
 				}
 				if feeRate.MaxAmount > 0 { // fee limit
 					maxAmount := NewAmountWithBigInt(big.NewInt(feeRate.MaxAmount))
 					if feeAmount.Cmp(maxAmount) > 0 { // feeAmount is gt.
-						return maxAmount, nil
+						return maxAmount
 					}
 				}
-				return feeAmount, nil
+				return feeAmount
 			}
 		} // else no such fn
 	} // else policy does't exist
 
-	return ZeroAmount(), nil
+	return ZeroAmount()
+}
+
+// CreateSponsorshipKey _
+func (fb *FeeStub) CreateSponsorshipKey(sponsor, account string) string {
+	return "FSPN_" + sponsor + "_" + account
+}
+
+// GetSponsorship _
+func (fb *FeeStub) GetSponsorship(sponsor, account string) (*FeeSponsorship, error) {
+	data, err := fb.stub.GetState(fb.CreateSponsorshipKey(sponsor, account))
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the fee sponsorship state")
+	}
+	if nil == data {
+		return nil, NotExistedFeeSponsorshipError{}
+	}
+	sponsorship := &FeeSponsorship{}
+	if err = json.Unmarshal(data, sponsorship); nil != err {
+		return nil, errors.Wrap(err, "failed to unmarshal the fee sponsorship")
+	}
+	return sponsorship, nil
+}
+
+// PutSponsorship _
+func (fb *FeeStub) PutSponsorship(sponsorship *FeeSponsorship) error {
+	data, err := json.Marshal(sponsorship)
+	if nil != err {
+		return errors.Wrap(err, "failed to marshal the fee sponsorship")
+	}
+	if err = fb.stub.PutState(fb.CreateSponsorshipKey(sponsorship.DOCTYPEID, sponsorship.Account), data); nil != err {
+		return errors.Wrap(err, "failed to put the fee sponsorship state")
+	}
+	return nil
+}
+
+// DeleteSponsorship _
+func (fb *FeeStub) DeleteSponsorship(sponsorship *FeeSponsorship) error {
+	if err := fb.stub.DelState(fb.CreateSponsorshipKey(sponsorship.DOCTYPEID, sponsorship.Account)); nil != err {
+		return errors.Wrap(err, "failed to delete the fee sponsorship state")
+	}
+	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/hyperledger/fabric/protos/peer"
 	"github.com/key-inside/kiesnet-ccpkg/kid"
 	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
 )
 
 // Get fee list of token
@@ -24,12 +25,12 @@ import (
 // params[4] : optional. end time (timestamp represented by in64 seconds)
 func feeList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if nil != err {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// token
@@ -42,7 +43,7 @@ func feeList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	// authentication
 	_, err = kid.GetID(stub, false)
 	if nil != err {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	bookmark := ""
@@ -55,14 +56,14 @@ func feeList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 		if len(params) > 2 {
 			fetchSize, err = strconv.Atoi(params[2])
 			if nil != err {
-				return shim.Error("invalid fetch size")
+				return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
 			}
 			// start time
 			if len(params) > 3 {
 				if len(params[3]) > 0 {
 					seconds, err := strconv.ParseInt(params[3], 10, 64)
 					if nil != err {
-						return shim.Error("invalid start time: need seconds since 1970")
+						return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
 					}
 					stime = txtime.Unix(seconds, 0)
 				}
@@ -71,11 +72,11 @@ func feeList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 					if len(params[4]) > 0 {
 						seconds, err := strconv.ParseInt(params[4], 10, 64)
 						if nil != err {
-							return shim.Error("invalid end time: need seconds since 1970")
+							return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
 						}
 						etime = txtime.Unix(seconds, 0)
 						if nil != stime && stime.Cmp(etime) >= 0 {
-							return shim.Error("invalid time parameters")
+							return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
 						}
 					}
 				}
@@ -98,20 +99,20 @@ func feeList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 }
 
 // prune the fees from last fee time to end_time.
-// if end_time is not provided, prune to 10 mins lesser than current time(if ten_minutes_flag is set to true).
-// Only holder of FeePolicy.TargetAddress is able to prune.
+// if end_time is not provided, prune to the prune window lesser than current time(if safety window flag is set to true). (see Token.PruneWindow)
+// Only holder of FeePolicy.TargetAddress is able to prune. The fees are distributed to the beneficiaries of the fee policy. (see FeePolicy.Distribute)
 // ISSUE : Shoud this be in token_tx.go? And should route name be token/fee/prune?
 // params[0] : token code
-// params[1] : 10 minutes limit flag. if the value is true, 10 minutes check is activated.
+// params[1] : safety window flag. if the value is true, the fees in the prune window of the token are not pruned.
 // params[2] : optional. end time
 func feePrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 2 {
-		return shim.Error("incorrect number of parameters. expecting 2+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if nil != err {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// token
@@ -124,7 +125,7 @@ func feePrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if nil != err {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// If Token.FeePolicy is nil, that means there is no fee utxo.
@@ -141,131 +142,375 @@ func feePrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 		return shim.Success(data)
 	}
 
-	// If Token.FeePolicy is not nil, Token.FeePolicy.TargetAddress is never empty.
-	// ISSUE : We MUST validate target address before(tokenUpdate)
-	addr, _ := ParseAddress(token.FeePolicy.TargetAddress) // err is nil
-	ab := NewAccountStub(stub, code)
-	account, err := ab.GetAccount(addr)
-	if nil != err {
-		return responseError(err, "failed to get the target account")
+	fp, err := getValidatedFeePruneParameters(stub, kid, token, params)
+	if err != nil {
+		return responseError(err, "")
 	}
-	if !account.HasHolder(kid) { // authority
-		return shim.Error("no authority")
+	lastPrunedFeeID, stime, etime, ts := fp.lastPrunedFeeID, fp.stime, fp.etime, fp.ts
+	lb := NewLastPrunedFeeIDStub(stub)
+	if fp.migration {
+		//TODO: unused code block after v1.2.5 version.
+		// this last_pruned_fee_id field will be never used after migration. delete it.
+		token.LastPrunedFeeID = ""
+		err = tb.PutToken(token)
+		if err != nil {
+			return responseError(err, "failed to update token state of removing last_pruned_fee_id")
+		}
 	}
-	// ISSUE : What if target account is suspended?
-
-	stime := txtime.Unix(0, 0)
 
-	ts, err := txtime.GetTime(stub)
+	// calculate fee sum
+	fb := NewFeeStub(stub)
+	feeSum, err := fb.GetFeeSumByTime(code, stime, etime)
 	if nil != err {
-		return responseError(err, "failed to get the timestamp")
+		return responseError(err, "failed to get fees to prune")
 	}
 
-	lb := NewLastPrunedFeeIDStub(stub)
-	lastPrunedFeeID, err := lb.GetLastPrunedFeeID(code)
-	if nil != err {
-		//TODO: unused code block from line 167 to line 180 after v1.2.5 version.
-		if _, ok := err.(NotInitLastPrunedFeeIDError); ok {
-			// migration
-			lastPrunedFeeID = &LastPrunedFeeID{
-				DOCTYPEID: code,
-				FeeID:     token.LastPrunedFeeID,
+	if feeSum.Count > 0 {
+		lastPrunedFeeID.FeeID = feeSum.End
+		lastPrunedFeeID.UpdatedTime = ts
+		err = lb.PutLastPrunedFeeID(lastPrunedFeeID)
+		if nil != err {
+			return responseError(err, "failed to update the last pruned fee id")
+		}
+
+		// distribute to the beneficiaries
+		bb := NewBalanceStub(stub)
+		res := &FeePruneResult{FeeSum: feeSum, BalanceLogs: []*BalanceLog{}}
+		res.Shares = token.FeePolicy.Distribute(feeSum.Sum)
+		for _, share := range res.Shares {
+			if share.Amount.Sign() == 0 {
+				continue
 			}
-			// this last_pruned_fee_id field will be never used after migration. delete it.
-			token.LastPrunedFeeID = ""
-			err = tb.PutToken(token)
-			if err != nil {
-				return responseError(err, "failed to update token state of removing last_pruned_fee_id")
+			bal, err := bb.GetBalance(share.Address)
+			if nil != err {
+				return responseError(err, "failed to get the fee beneficiary balance")
 			}
-		} else {
-			return responseError(err, "failed to get the last pruned fee id")
+			bal.Amount.Add(share.Amount)
+			bal.UpdatedTime = ts
+			err = bb.PutBalance(bal)
+			if nil != err {
+				return responseError(err, "failed to update the fee beneficiary balance")
+			}
+
+			// balance log
+			pruneLog := NewBalancePruneFeeLog(bal, *share.Amount, feeSum.Start, feeSum.End)
+			pruneLog.CreatedTime = ts
+			err = bb.PutBalanceLog(pruneLog)
+			if nil != err {
+				return responseError(err, "failed to save balance log")
+			}
+			res.BalanceLogs = append(res.BalanceLogs, pruneLog)
 		}
-	}
 
-	if len(lastPrunedFeeID.FeeID) > 0 {
-		//TODO check fee id parsing logic
-		s, err := strconv.ParseInt(lastPrunedFeeID.FeeID[0:10], 10, 64)
+		data, err := json.Marshal(res)
 		if nil != err {
-			return responseError(err, "failed to get seconds from timestamp")
+			return responseError(err, "failed to marshal the fee prune result")
 		}
-		//TODO check fee id parsing logic
-		n, err := strconv.ParseInt(lastPrunedFeeID.FeeID[10:19], 10, 64)
-		if nil != err {
-			return responseError(err, "failed to get nanoseconds from timestamp")
+		return shim.Success(data)
+	}
+
+	//if there is no fee to prune
+	//XXX The error message is parsed to throw Exception at java sdk.
+	// DO NOT EDIT IT.
+	// DO NOT USE THIS MESSAGE ELSEWHERE.
+	return shim.Error("found no record to prune.")
+
+}
+
+// Preview the range of fee/prune without writing any state.
+// params[0] : token code
+// params[1] : safety window flag (see fee/prune)
+// params[2] : optional. end time
+func feePrunePreview(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if nil != err {
+		return responseError(err, "")
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if nil != err {
+		return responseError(err, "failed to get the token")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if nil != err {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	preview := &FeePrunePreview{FeeSum: &FeeSum{Sum: ZeroAmount()}}
+	if token.FeePolicy != nil { // If Token.FeePolicy is nil, that means there is no fee utxo.
+		fp, err := getValidatedFeePruneParameters(stub, kid, token, params)
+		if err != nil {
+			return responseError(err, "")
+		}
+		if preview.FeeSum, err = NewFeeStub(stub).GetFeeSumByTime(code, fp.stime, fp.etime); nil != err {
+			return responseError(err, "failed to get fees to prune")
+		}
+		preview.Address = fp.account.GetID()
+		preview.StartTime = fp.stime
+		preview.EndTime = fp.etime
+		if preview.Count > 0 {
+			preview.Shares = token.FeePolicy.Distribute(preview.Sum)
 		}
-		stime = txtime.Unix(s, n)
 	}
 
-	var etime *txtime.Time
-	if len(params) > 2 {
-		seconds, err := strconv.ParseInt(params[2], 10, 64)
+	data, err := json.Marshal(preview)
+	if nil != err {
+		return responseError(err, "failed to marshal the fee prune preview")
+	}
+	return shim.Success(data)
+}
+
+// Authorize the sponsor account to bear the fees of the account. (see FeeBearerSponsor)
+// Only holder of the sponsor account is able to authorize.
+// params[0] : sponsor address
+// params[1] : sponsored account address
+func feeSponsor(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if nil != err {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	sp, err := getValidatedFeeSponsorshipParameters(stub, kid, params)
+	if nil != err {
+		return responseError(err, "")
+	}
+
+	fb := NewFeeStub(stub)
+	sponsorship, err := fb.GetSponsorship(sp.sponsor.GetID(), sp.account.String())
+	if nil != err {
+		if _, ok := err.(NotExistedFeeSponsorshipError); !ok {
+			return responseError(err, "failed to get the fee sponsorship")
+		}
+		ts, err := txtime.GetTime(stub)
 		if nil != err {
-			return responseError(err, "failed to parse the end time")
+			return responseError(err, "failed to get the timestamp")
 		}
-		etime = txtime.Unix(seconds, 0)
-	} else {
-		etime = ts
+		sponsorship = &FeeSponsorship{
+			DOCTYPEID:   sp.sponsor.GetID(),
+			Account:     sp.account.String(),
+			CreatedTime: ts,
+		}
+		if err = fb.PutSponsorship(sponsorship); nil != err {
+			return responseError(err, "failed to put the fee sponsorship")
+		}
+	} // else already authorized
+
+	data, err := json.Marshal(sponsorship)
+	if nil != err {
+		return responseError(err, "failed to marshal the fee sponsorship")
 	}
+	return shim.Success(data)
+}
 
-	safely, err := strconv.ParseBool(params[1])
+// Revoke the fee sponsorship.
+// Only holder of the sponsor account is able to revoke.
+// params[0] : sponsor address
+// params[1] : sponsored account address
+func feeSponsorRevoke(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	// authentication
+	kid, err := kid.GetID(stub, true)
 	if nil != err {
-		return shim.Error("invalid boolean flag")
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
-	if safely {
-		// safe time is current transaction time minus 10 minutes. this is to prevent missing pay(s) because of the time differences(+/- 5min) on different servers/devices
-		safeTime := txtime.New(ts.Add(-6e+11))
-		if nil == etime || etime.Cmp(safeTime) > 0 {
-			etime = safeTime
-		}
+	sp, err := getValidatedFeeSponsorshipParameters(stub, kid, params)
+	if nil != err {
+		return responseError(err, "")
 	}
 
-	// calculate fee sum
 	fb := NewFeeStub(stub)
-	feeSum, err := fb.GetFeeSumByTime(code, stime, etime)
+	sponsorship, err := fb.GetSponsorship(sp.sponsor.GetID(), sp.account.String())
 	if nil != err {
-		return responseError(err, "failed to get fees to prune")
+		return responseError(err, "failed to get the fee sponsorship")
+	}
+	if err = fb.DeleteSponsorship(sponsorship); nil != err {
+		return responseError(err, "failed to revoke the fee sponsorship")
 	}
 
-	bb := NewBalanceStub(stub)
-	bal, err := bb.GetBalance(account.GetID())
+	data, err := json.Marshal(sponsorship)
 	if nil != err {
-		return responseError(err, "failed to get the target account balance")
+		return responseError(err, "failed to marshal the fee sponsorship")
 	}
+	return shim.Success(data)
+}
 
-	if feeSum.Count > 0 {
-		bal.Amount.Add(feeSum.Sum)
-		bal.UpdatedTime = ts
-		err = bb.PutBalance(bal)
-		if nil != err {
-			return responseError(err, "failed to update genesis account balance")
+// params[0] : sponsor address
+// params[1] : sponsored account address
+func feeSponsorGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if nil != err {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	sponsorship, err := NewFeeStub(stub).GetSponsorship(params[0], params[1])
+	if nil != err {
+		return responseError(err, "failed to get the fee sponsorship")
+	}
+
+	data, err := json.Marshal(sponsorship)
+	if nil != err {
+		return responseError(err, "failed to marshal the fee sponsorship")
+	}
+	return shim.Success(data)
+}
+
+// helpers
+
+// feeSponsorshipParameters is the validated parameters of fee/sponsor and fee/sponsor/revoke.
+type feeSponsorshipParameters struct {
+	sponsor AccountInterface
+	account *Address
+}
+
+// getValidatedFeeSponsorshipParameters validates the sponsor and the sponsored account. The invoker must be a holder of the sponsor account.
+func getValidatedFeeSponsorshipParameters(stub shim.ChaincodeStubInterface, kid string, params []string) (*feeSponsorshipParameters, error) {
+	if len(params) < 2 {
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 2"}
+	}
+	spAddr, err := ParseAddress(params[0])
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to parse the sponsor's account address")
+	}
+	addr, err := ParseAddress(params[1])
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to parse the sponsored account address")
+	}
+	if spAddr.Code != addr.Code { // not same token
+		return nil, InvalidParameterError{reason: "different token accounts"}
+	}
+	if spAddr.Equal(addr) {
+		return nil, InvalidParameterError{reason: "can't sponsor self"}
+	}
+
+	ab := NewAccountStub(stub, spAddr.Code)
+	sponsor, err := ab.GetAccount(spAddr)
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the sponsor account")
+	}
+	if !sponsor.HasHolder(kid) {
+		return nil, NotHolderError{}
+	}
+	if _, err = ab.GetAccountState(addr.String()); nil != err {
+		return nil, errors.Wrap(err, "failed to get the sponsored account")
+	}
+
+	return &feeSponsorshipParameters{sponsor: sponsor, account: addr}, nil
+}
+
+// getValidatedFeeSponsor validates the sponsorship of the sender's fee and returns the sponsor address and balance.
+// The receiver can't be the sponsor. (the receiver bearer is for it)
+func getValidatedFeeSponsor(stub shim.ChaincodeStubInterface, sponsor string, sAddr, rAddr *Address) (*Address, *Balance, error) {
+	if len(sponsor) == 0 {
+		return nil, nil, InvalidParameterError{reason: "sponsor is required"}
+	}
+	spAddr, err := ParseAddress(sponsor)
+	if nil != err {
+		return nil, nil, errors.Wrap(err, "failed to parse the sponsor's account address")
+	}
+	if spAddr.Code != sAddr.Code { // not same token
+		return nil, nil, InvalidParameterError{reason: "different token accounts"}
+	}
+	if spAddr.Equal(sAddr) || spAddr.Equal(rAddr) {
+		return nil, nil, InvalidParameterError{reason: "the sender or the receiver can't be the sponsor"}
+	}
+	if _, err = NewFeeStub(stub).GetSponsorship(spAddr.String(), sAddr.String()); nil != err {
+		return nil, nil, err
+	}
+	account, err := NewAccountStub(stub, spAddr.Code).GetAccount(spAddr)
+	if nil != err {
+		return nil, nil, errors.Wrap(err, "failed to get the sponsor account")
+	}
+	if account.IsSuspended() {
+		return nil, nil, SuspendedAccountError{role: "sponsor"}
+	}
+	bal, err := NewBalanceStub(stub).GetBalance(account.GetID())
+	if nil != err {
+		return nil, nil, errors.Wrap(err, "failed to get the sponsor's balance")
+	}
+	return spAddr, bal, nil
+}
+
+// feePruneParameters is the validated parameters of fee/prune. (see getValidatedFeePruneParameters)
+type feePruneParameters struct {
+	account         AccountInterface
+	lastPrunedFeeID *LastPrunedFeeID
+	migration       bool         // the last pruned fee id is migrated from the token state
+	stime           *txtime.Time // created time of the last pruned fee
+	etime           *txtime.Time
+	ts              *txtime.Time
+}
+
+// getValidatedFeePruneParameters validates the fee/prune parameters of the token which has the fee policy without writing any state.
+// It is shared by fee/prune and fee/prune/preview.
+func getValidatedFeePruneParameters(stub shim.ChaincodeStubInterface, kid string, token *Token, params []string) (*feePruneParameters, error) {
+	// If Token.FeePolicy is not nil, Token.FeePolicy.TargetAddress is never empty.
+	// ISSUE : We MUST validate target address before(tokenUpdate)
+	addr, _ := ParseAddress(token.FeePolicy.TargetAddress) // err is nil
+	account, err := NewAccountStub(stub, token.DOCTYPEID).GetAccount(addr)
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the target account")
+	}
+	if !account.HasHolder(kid) { // authority
+		return nil, NoAuthorityError{}
+	}
+	// ISSUE : What if target account is suspended?
+
+	fp := &feePruneParameters{account: account, stime: txtime.Unix(0, 0)}
+
+	if fp.ts, err = txtime.GetTime(stub); nil != err {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	fp.lastPrunedFeeID, err = NewLastPrunedFeeIDStub(stub).GetLastPrunedFeeID(token.DOCTYPEID)
+	if nil != err {
+		//TODO: unused code block after v1.2.5 version.
+		if _, ok := err.(NotInitLastPrunedFeeIDError); !ok {
+			return nil, errors.Wrap(err, "failed to get the last pruned fee id")
 		}
-		lastPrunedFeeID.FeeID = feeSum.End
-		lastPrunedFeeID.UpdatedTime = ts
-		err = lb.PutLastPrunedFeeID(lastPrunedFeeID)
-		if nil != err {
-			return responseError(err, "failed to update the last pruned fee id")
+		// migration
+		fp.lastPrunedFeeID = &LastPrunedFeeID{
+			DOCTYPEID: token.DOCTYPEID,
+			FeeID:     token.LastPrunedFeeID,
 		}
+		fp.migration = true
+	}
 
-		// balance log
-		pruneLog := NewBalancePruneFeeLog(bal, *feeSum.Sum, feeSum.Start, feeSum.End)
-		pruneLog.CreatedTime = ts
-		err = bb.PutBalanceLog(pruneLog)
-		if nil != err {
-			return responseError(err, "failed to save balance log")
+	if len(fp.lastPrunedFeeID.FeeID) > 0 {
+		if fp.stime, err = parseUTXOIDTime(fp.lastPrunedFeeID.FeeID); err != nil {
+			return nil, errors.Wrap(err, "failed to get the time of the last pruned fee")
 		}
+	}
 
-		data, err := json.Marshal(feeSum)
+	fp.etime = fp.ts
+	if len(params) > 2 {
+		seconds, err := strconv.ParseInt(params[2], 10, 64)
 		if nil != err {
-			return responseError(err, "failed to marshal the fee prune result")
+			return nil, InvalidParameterError{reason: "failed to parse the end time"}
 		}
-		return shim.Success(data)
+		fp.etime = txtime.Unix(seconds, 0)
 	}
 
-	//if there is no fee to prune
-	//XXX The error message is parsed to throw Exception at java sdk.
-	// DO NOT EDIT IT.
-	// DO NOT USE THIS MESSAGE ELSEWHERE.
-	return shim.Error("found no record to prune.")
+	safely, err := strconv.ParseBool(params[1])
+	if nil != err {
+		return nil, InvalidParameterError{reason: "invalid boolean flag"}
+	}
+	if safely {
+		if safeTime := token.GetPruneSafeTime(fp.ts); fp.etime.Cmp(safeTime) > 0 {
+			fp.etime = safeTime
+		}
+	}
 
+	return fp, nil
 }
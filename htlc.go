@@ -0,0 +1,98 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"golang.org/x/crypto/sha3"
+)
+
+// HTLCStatus _
+type HTLCStatus int8
+
+const (
+	// HTLCStatusLocked _
+	HTLCStatusLocked HTLCStatus = iota
+	// HTLCStatusClaimed _
+	HTLCStatusClaimed
+	// HTLCStatusRefunded _
+	HTLCStatusRefunded
+)
+
+// HTLC hash algorithms
+const (
+	HTLCHashSHA256    = "sha256"
+	HTLCHashKeccak256 = "keccak256" // ethereum compatible
+)
+
+// HTLC is the hash time-locked contract.
+// The amount is escrowed in the pending balance of the same ID until it is claimed or refunded.
+type HTLC struct {
+	DOCTYPEID   string       `json:"@htlc"` // lock tx id (= pending balance id)
+	Sender      string       `json:"sender"`
+	Receiver    string       `json:"receiver"`
+	Amount      Amount       `json:"amount"`
+	Fee         Amount       `json:"fee"`       // transfer fee, returned when refunded
+	HashAlgo    string       `json:"hash_algo"` // HTLCHashSHA256 | HTLCHashKeccak256
+	Hashlock    string       `json:"hashlock"`  // hex (32 bytes)
+	Timelock    *txtime.Time `json:"timelock"`
+	Preimage    string       `json:"preimage,omitempty"` // hex, revealed by the claim
+	Status      HTLCStatus   `json:"status"`
+	Memo        string       `json:"memo,omitempty"`
+	OrderID     string       `json:"order_id,omitempty"`
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+	UpdatedTime *txtime.Time `json:"updated_time,omitempty"`
+}
+
+// GetID implements Identifiable
+func (h *HTLC) GetID() string {
+	return h.DOCTYPEID
+}
+
+// NormalizeHashlock validates the hash algorithm and the hashlock, and returns them in lower case.
+func NormalizeHashlock(algo, hashlock string) (string, string, error) {
+	algo = strings.ToLower(algo)
+	if algo != HTLCHashSHA256 && algo != HTLCHashKeccak256 {
+		return "", "", InvalidParameterError{reason: "invalid hash algorithm. expecting 'sha256' or 'keccak256'"}
+	}
+	hashlock = strings.ToLower(strings.TrimPrefix(hashlock, "0x"))
+	if h, err := hex.DecodeString(hashlock); err != nil || len(h) != 32 {
+		return "", "", InvalidParameterError{reason: "invalid hashlock. expecting 32 bytes hex"}
+	}
+	return algo, hashlock, nil
+}
+
+// VerifyPreimage returns the normalized preimage if its hash is the hashlock.
+func (h *HTLC) VerifyPreimage(preimage string) (string, error) {
+	preimage = strings.ToLower(strings.TrimPrefix(preimage, "0x"))
+	data, err := hex.DecodeString(preimage)
+	if err != nil || len(data) == 0 {
+		return "", InvalidParameterError{reason: "invalid preimage. expecting hex"}
+	}
+	var sum []byte
+	switch h.HashAlgo {
+	case HTLCHashKeccak256:
+		kh := sha3.NewLegacyKeccak256()
+		kh.Write(data)
+		sum = kh.Sum(nil)
+	default:
+		s := sha256.Sum256(data)
+		sum = s[:]
+	}
+	hashlock, _ := hex.DecodeString(h.Hashlock) // validated
+	if !bytes.Equal(sum, hashlock) {
+		return "", InvalidParameterError{reason: "preimage does not match the hashlock"}
+	}
+	return preimage, nil
+}
+
+// HTLCResult is response payload of htlc/lock, htlc/claim and htlc/refund.
+type HTLCResult struct {
+	HTLC       *HTLC       `json:"htlc"`
+	BalanceLog *BalanceLog `json:"balance_log"`
+}
@@ -0,0 +1,199 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// HTLCStub _
+type HTLCStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewHTLCStub _
+func NewHTLCStub(stub shim.ChaincodeStubInterface) *HTLCStub {
+	return &HTLCStub{stub}
+}
+
+// CreateKey _
+func (hb *HTLCStub) CreateKey(id string) string {
+	return "HTLC_" + id
+}
+
+// GetHTLC _
+func (hb *HTLCStub) GetHTLC(id string) (*HTLC, error) {
+	data, err := hb.stub.GetState(hb.CreateKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the htlc state")
+	}
+	if data == nil {
+		return nil, NotExistedHTLCError{}
+	}
+	htlc := &HTLC{}
+	if err = json.Unmarshal(data, htlc); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the htlc")
+	}
+	return htlc, nil
+}
+
+// PutHTLC _
+func (hb *HTLCStub) PutHTLC(htlc *HTLC) error {
+	data, err := json.Marshal(htlc)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the htlc")
+	}
+	if err = hb.stub.PutState(hb.CreateKey(htlc.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the htlc state")
+	}
+	return nil
+}
+
+// Lock escrows (amount + fee) of the sender to the pending balance and creates the htlc.
+// It does not validate the parameters!
+func (hb *HTLCStub) Lock(sender *Balance, receiver string, amount, fee Amount, algo, hashlock string, timelock *txtime.Time, memo, orderID string) (*HTLC, *BalanceLog, error) {
+	ts, err := txtime.GetTime(hb.stub)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	htlc := &HTLC{
+		DOCTYPEID:   hb.stub.GetTxID(),
+		Sender:      sender.DOCTYPEID,
+		Receiver:    receiver,
+		Amount:      amount,
+		Fee:         fee,
+		HashAlgo:    algo,
+		Hashlock:    hashlock,
+		Timelock:    timelock,
+		Status:      HTLCStatusLocked,
+		Memo:        memo,
+		OrderID:     orderID,
+		CreatedTime: ts,
+		UpdatedTime: ts,
+	}
+	if err = hb.PutHTLC(htlc); err != nil {
+		return nil, nil, err
+	}
+
+	// pending balance (escrow)
+	bb := NewBalanceStub(hb.stub)
+	pb := &PendingBalance{
+		DOCTYPEID:   htlc.DOCTYPEID,
+		Type:        PendingBalanceTypeHTLC,
+		Account:     sender.DOCTYPEID,
+		RID:         receiver,
+		Amount:      amount,
+		Fee:         fee.Copy(),
+		Memo:        memo,
+		OrderID:     orderID,
+		CreatedTime: ts,
+		PendingTime: timelock,
+	}
+	if err = bb.PutPendingBalance(pb); err != nil {
+		return nil, nil, err
+	}
+
+	// applied = (amount + fee)
+	applied := amount.Copy().Add(&fee)
+	sender.Amount.Add(applied.Neg()) // -applied
+	sender.UpdatedTime = ts
+	if err = bb.PutBalance(sender); err != nil {
+		return nil, nil, err
+	}
+	log := NewBalanceHTLCLockLog(sender, htlc)
+	log.CreatedTime = ts
+	if err = bb.PutBalanceLog(log); err != nil {
+		return nil, nil, err
+	}
+
+	return htlc, log, nil
+}
+
+// Claim releases the escrowed amount to the receiver with the preimage, and the fee is charged.
+// It does not validate the preimage and the timelock!
+func (hb *HTLCStub) Claim(htlc *HTLC, receiver *Balance, preimage string) (*BalanceLog, error) {
+	ts, err := txtime.GetTime(hb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	bb := NewBalanceStub(hb.stub)
+	pb, err := bb.GetPendingBalance(htlc.DOCTYPEID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver.Amount.Add(&htlc.Amount) // deposit
+	receiver.UpdatedTime = ts
+	if err = bb.PutBalance(receiver); err != nil {
+		return nil, err
+	}
+	log := NewBalanceHTLCClaimLog(receiver, htlc)
+	log.CreatedTime = ts
+	if err = bb.PutBalanceLog(log); err != nil {
+		return nil, err
+	}
+
+	// fee
+	if _, err := NewFeeStub(hb.stub).CreateFee(htlc.Sender, htlc.Fee); err != nil {
+		return nil, err
+	}
+
+	// remove pending balance
+	if err = bb.DeletePendingBalance(pb); err != nil {
+		return nil, err
+	}
+
+	htlc.Status = HTLCStatusClaimed
+	htlc.Preimage = preimage
+	htlc.UpdatedTime = ts
+	if err = hb.PutHTLC(htlc); err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
+
+// Refund returns the escrowed (amount + fee) to the sender.
+// It does not validate the timelock!
+func (hb *HTLCStub) Refund(htlc *HTLC, sender *Balance) (*BalanceLog, error) {
+	ts, err := txtime.GetTime(hb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	bb := NewBalanceStub(hb.stub)
+	pb, err := bb.GetPendingBalance(htlc.DOCTYPEID)
+	if err != nil {
+		return nil, err
+	}
+
+	sender.Amount.Add(htlc.Amount.Copy().Add(&htlc.Fee))
+	sender.UpdatedTime = ts
+	if err = bb.PutBalance(sender); err != nil {
+		return nil, err
+	}
+	log := NewBalanceHTLCRefundLog(sender, htlc)
+	log.CreatedTime = ts
+	if err = bb.PutBalanceLog(log); err != nil {
+		return nil, err
+	}
+
+	// remove pending balance
+	if err = bb.DeletePendingBalance(pb); err != nil {
+		return nil, err
+	}
+
+	htlc.Status = HTLCStatusRefunded
+	htlc.UpdatedTime = ts
+	if err = hb.PutHTLC(htlc); err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
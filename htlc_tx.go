@@ -0,0 +1,297 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/key-inside/kiesnet-ccpkg/kid"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// params[0] : sender address (empty string = personal account)
+// params[1] : receiver address
+// params[2] : amount (big int string | decimal-formatted string)
+// params[3] : hash algorithm ("sha256" | "keccak256")
+// params[4] : hashlock (32 bytes hex)
+// params[5] : timelock (time represented by int64 seconds)
+// params[6] : optional. memo (see MemoMaxLength)
+// params[7] : optional. order id
+func htlcLock(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 6 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 6+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	// hashlock
+	algo, hashlock, err := NormalizeHashlock(params[3], params[4])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// timelock
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+	seconds, err := strconv.ParseInt(params[5], 10, 64)
+	if err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid timelock: need seconds since 1970")
+	}
+	timelock := txtime.Unix(seconds, 0)
+	if timelock.Cmp(ts) <= 0 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid timelock: must be future")
+	}
+
+	// addresses
+	rAddr, err := ParseAddress(params[1])
+	if err != nil {
+		return responseError(err, "failed to parse the receiver's account address")
+	}
+	var sAddr *Address
+	if len(params[0]) > 0 {
+		sAddr, err = ParseAddress(params[0])
+		if err != nil {
+			return responseError(err, "failed to parse the sender's account address")
+		}
+		if rAddr.Code != sAddr.Code { // not same token
+			return responseErrorCode(ErrorCodeInvalidParameter, "different token accounts")
+		}
+	} else {
+		sAddr = NewAddress(rAddr.Code, AccountTypePersonal, kid)
+	}
+	if sAddr.Equal(rAddr) {
+		return responseErrorCode(ErrorCodeInvalidParameter, "can't lock to self")
+	}
+
+	// amount
+	amount, err := NewTokenStub(stub).ParseAmount(rAddr.Code, params[2])
+	if err != nil {
+		return responseError(err, "")
+	}
+	if amount.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+	}
+
+	ab := NewAccountStub(stub, rAddr.Code)
+
+	// sender
+	sender, err := ab.GetAccount(sAddr)
+	if err != nil {
+		return responseError(err, "failed to get the sender account")
+	}
+	if !sender.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
+	}
+	if sender.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the sender account is suspended")
+	}
+
+	// receiver
+	receiver, err := ab.GetAccount(rAddr)
+	if err != nil {
+		return responseError(err, "failed to get the receiver account")
+	}
+	if receiver.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the receiver account is suspended")
+	}
+
+	// sender balance
+	bb := NewBalanceStub(stub)
+	sBal, err := bb.GetBalance(sender.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the sender's balance")
+	}
+
+	// fee (the same as transfer)
+	fee, err := NewFeeStub(stub).CalcFee(sAddr, "transfer", *amount)
+	if err != nil {
+		return responseError(err, "failed to get the fee amount")
+	}
+	if sBal.Amount.Cmp(amount.Copy().Add(fee)) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	// options
+	memo := ""
+	orderID := ""
+	if len(params) > 6 {
+		memo = params[6]
+		if len(memo) > MemoMaxLength { // length limit
+			memo = memo[:MemoMaxLength]
+		}
+		if len(params) > 7 {
+			orderID = params[7]
+		}
+	}
+
+	htlc, log, err := NewHTLCStub(stub).Lock(sBal, receiver.GetID(), *amount, *fee, algo, hashlock, timelock, memo, orderID)
+	if err != nil {
+		return responseError(err, "failed to lock the htlc")
+	}
+
+	return responseHTLCResult(htlc, log)
+}
+
+// Anyone can claim, the amount is always released to the receiver. (e.g. relayer)
+// params[0] : htlc id
+// params[1] : preimage (hex)
+func htlcClaim(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	hb := NewHTLCStub(stub)
+	htlc, err := hb.GetHTLC(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the htlc")
+	}
+	if htlc.Status != HTLCStatusLocked {
+		return responseErrorCode(ErrorCodeInvalidState, "the htlc is not locked")
+	}
+	if htlc.Timelock.Cmp(ts) <= 0 {
+		return responseErrorCode(ErrorCodeInvalidState, "the htlc is expired")
+	}
+	preimage, err := htlc.VerifyPreimage(params[1])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// receiver
+	rAddr, _ := ParseAddress(htlc.Receiver) // err is nil
+	receiver, err := NewAccountStub(stub, rAddr.Code).GetAccount(rAddr)
+	if err != nil {
+		return responseError(err, "failed to get the receiver account")
+	}
+	if receiver.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the receiver account is suspended")
+	}
+	rBal, err := NewBalanceStub(stub).GetBalance(receiver.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the receiver's balance")
+	}
+
+	log, err := hb.Claim(htlc, rBal, preimage)
+	if err != nil {
+		return responseError(err, "failed to claim the htlc")
+	}
+
+	// the preimage is revealed to the relayers
+	data, err := json.Marshal(htlc)
+	if err != nil {
+		return responseError(err, "failed to marshal the htlc")
+	}
+	if err = stub.SetEvent("htlc/claim", data); err != nil {
+		return responseError(err, "failed to set the event")
+	}
+
+	return responseHTLCResult(htlc, log)
+}
+
+// params[0] : htlc id
+func htlcRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	hb := NewHTLCStub(stub)
+	htlc, err := hb.GetHTLC(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the htlc")
+	}
+	if htlc.Status != HTLCStatusLocked {
+		return responseErrorCode(ErrorCodeInvalidState, "the htlc is not locked")
+	}
+	if htlc.Timelock.Cmp(ts) > 0 {
+		return responseErrorCode(ErrorCodeInvalidState, "too early to refund")
+	}
+
+	// sender
+	sAddr, _ := ParseAddress(htlc.Sender) // err is nil
+	sender, err := NewAccountStub(stub, sAddr.Code).GetAccount(sAddr)
+	if err != nil {
+		return responseError(err, "failed to get the sender account")
+	}
+	if !sender.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
+	}
+	if sender.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the sender account is suspended")
+	}
+	sBal, err := NewBalanceStub(stub).GetBalance(sender.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the sender's balance")
+	}
+
+	log, err := hb.Refund(htlc, sBal)
+	if err != nil {
+		return responseError(err, "failed to refund the htlc")
+	}
+
+	return responseHTLCResult(htlc, log)
+}
+
+// params[0] : htlc id
+func htlcGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	htlc, err := NewHTLCStub(stub).GetHTLC(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the htlc")
+	}
+
+	data, err := json.Marshal(htlc)
+	if err != nil {
+		return responseError(err, "failed to marshal the htlc")
+	}
+
+	return shim.Success(data)
+}
+
+// helpers
+
+func responseHTLCResult(htlc *HTLC, log *BalanceLog) peer.Response {
+	data, err := json.Marshal(&HTLCResult{HTLC: htlc, BalanceLog: log})
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+	return shim.Success(data)
+}
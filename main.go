@@ -3,8 +3,11 @@
 package main
 
 import (
+	"encoding/json"
+
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
 )
 
 var logger = shim.NewLogger("kiesnet-token")
@@ -22,9 +25,21 @@ func (cc *Chaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
 func (cc *Chaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 	fn, params := stub.GetFunctionAndParameters()
 	if txFn := routes[fn]; txFn != nil {
-		return txFn(stub, params)
+		// named arguments -> positional params
+		params, err := ParseArgs(fn, params)
+		if err != nil {
+			return responseError(err, "")
+		}
+		res := txFn(stub, params)
+		// decimal amount format (optional, the payload is returned as it is if it can't be rendered)
+		if res.Status == shim.OK && len(res.Payload) > 0 && isDecimalAmountFormat(stub) {
+			if payload, err := renderDecimalAmounts(stub, res.Payload); err == nil {
+				res.Payload = payload
+			}
+		}
+		return res
 	}
-	return shim.Error("unknown function: [" + fn + "]")
+	return responseErrorCode(ErrorCodeUnknownFunction, "unknown function: ["+fn+"]")
 }
 
 // TxFunc _
@@ -39,19 +54,56 @@ var routes = map[string]TxFunc{
 	"account/list":             accountList,
 	"account/suspend":          accountSuspend,
 	"account/unsuspend":        accountUnsuspend,
+	"balance/at":               balanceAt,
+	"balance/history":          balanceHistory,
 	"balance/logs":             balanceLogs,
+	"balance/logs/verify":      balanceLogsVerify,
 	"balance/pending/get":      balancePendingGet,
 	"balance/pending/list":     balancePendingList,
 	"balance/pending/withdraw": balancePendingWithdraw,
+	"balance/statement":        balanceStatement,
+	"bridge/compact":           bridgeCompact,
+	"bridge/status":            bridgeStatus,
 	"contract/execute":         contractExecute,
 	"contract/cancel":          contractCancel,
+	"conversion/get":           conversionGet,
+	"conversion/logs":          conversionLogs,
+	"conversion/register":      conversionRegister,
+	"conversion/update":        conversionUpdate,
+	"convert":                  convert,
+	"dex/book":                 dexBook,
+	"dex/order/cancel":         dexOrderCancel,
+	"dex/order/get":            dexOrderGet,
+	"dex/order/place":          dexOrderPlace,
+	"dex/trades":               dexTrades,
+	"distribution/claim":       distributionClaim,
+	"distribution/close":       distributionClose,
+	"distribution/create":      distributionCreate,
+	"distribution/get":         distributionGet,
 	"fee/list":                 feeList,
 	"fee/prune":                feePrune,
+	"fee/prune/preview":        feePrunePreview,
+	"fee/sponsor":              feeSponsor,
+	"fee/sponsor/get":          feeSponsorGet,
+	"fee/sponsor/revoke":       feeSponsorRevoke,
+	"htlc/claim":               htlcClaim,
+	"htlc/get":                 htlcGet,
+	"htlc/lock":                htlcLock,
+	"htlc/refund":              htlcRefund,
 	"pay":                      pay,
 	"pay/get":                  payGet,
 	"pay/prune":                payPrune,
+	"pay/prune/preview":        payPrunePreview,
 	"pay/list":                 payList,
 	"pay/refund":               payRefund,
+	"pool/add":                 poolAdd,
+	"pool/create":              poolCreate,
+	"pool/get":                 poolGet,
+	"pool/remove":              poolRemove,
+	"pool/share/get":           poolShareGet,
+	"pool/swap":                poolSwap,
+	"quote":                    quote,
+	"token/audit":              tokenAudit,
 	"token/burn":               tokenBurn,
 	"token/create":             tokenCreate,
 	"token/get":                tokenGet,
@@ -59,9 +111,19 @@ var routes = map[string]TxFunc{
 	"token/update":             tokenUpdate,
 	"transfer":                 transfer,
 	"transfer/get":             transferGet,
+	"transfer/multi":           transferMulti,
 	"wrap":                     wrap,
 	"wrap/complete":            wrapComplete,
+	"wrap/complete/batch":      wrapCompleteBatch,
+	"wrap/get":                 wrapGet,
+	"wrap/list":                wrapList,
+	"wrap/migrate":             wrapMigrate,
+	"wrap/refund":              wrapRefund,
 	"unwrap":                   unwrap,
+	"unwrap/attest":            unwrapAttest,
+	"unwrap/attestation/get":   unwrapAttestationGet,
+	"unwrap/batch":             unwrapBatch,
+	"unwrap/get":               unwrapGet,
 	"ver":                      ver,
 }
 
@@ -69,19 +131,39 @@ func ver(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	return shim.Success([]byte("Kiesnet Token v1.4.1 created by Key Inside Co., Ltd."))
 }
 
+// responseError returns the error response with the JSON error envelope. (see ErrorResponse)
+// The error code is taken from 'err' if it (or its cause) is CodedError, otherwise it is ErrorCodeInternal.
 // If 'err' is ResponsibleError, it will add err's message to the 'msg'.
+// If 'msg' is empty, err's message is used.
 func responseError(err error, msg string) peer.Response {
+	code := ErrorCodeInternal
 	if nil != err {
 		logger.Debug(err.Error())
-		if _, ok := err.(ResponsibleError); ok {
+		code = GetErrorCode(err)
+		if _, ok := errors.Cause(err).(ResponsibleError); ok {
 			if len(msg) > 0 {
 				msg = msg + "|" + err.Error()
 			} else {
 				msg = err.Error()
 			}
+		} else if len(msg) == 0 {
+			msg = err.Error()
 		}
 	}
-	return shim.Error(msg)
+	return responseErrorCode(code, msg)
+}
+
+// responseErrorCode returns the error response of the code with the JSON error envelope.
+// The status of the response is decided by the code. (see errorCatalog)
+func responseErrorCode(code ErrorCode, msg string) peer.Response {
+	data, err := json.Marshal(NewErrorResponse(code, msg))
+	if err != nil { // never here
+		return shim.Error(msg)
+	}
+	return peer.Response{
+		Status:  code.Status(),
+		Message: string(data),
+	}
 }
 
 func main() {
@@ -45,6 +45,65 @@ type PaySum struct {
 	HasMore bool    `json:"has_more"`
 }
 
+// PayBucket accumulates the pays of the merchant from the related account in a time bucket. (see SumBucketSize)
+// The related account's balance is put in the same tx, so the bucket doesn't make a new conflict of the pays.
+type PayBucket struct {
+	DOCTYPEID string `json:"@pay_bucket"` // merchant address
+	RID       string `json:"rid"`
+	Start     int64  `json:"start"` // unix seconds
+	Sum       Amount `json:"sum"`
+	Fee       Amount `json:"fee"`
+	Count     int    `json:"count"`
+	FirstID   string `json:"first_id"`
+	LastID    string `json:"last_id"`
+}
+
+// Add accumulates the pay.
+func (b *PayBucket) Add(pay *Pay) {
+	b.Sum.Add(&pay.Amount)
+	b.Fee.Add(&pay.Fee)
+	b.Count++
+	if len(b.FirstID) == 0 || pay.PayID < b.FirstID {
+		b.FirstID = pay.PayID
+	}
+	if pay.PayID > b.LastID {
+		b.LastID = pay.PayID
+	}
+}
+
+// merge accumulates the other bucket of the same start.
+func (b *PayBucket) merge(o *PayBucket) {
+	b.Sum.Add(&o.Sum)
+	b.Fee.Add(&o.Fee)
+	b.Count += o.Count
+	if o.FirstID < b.FirstID {
+		b.FirstID = o.FirstID
+	}
+	if o.LastID > b.LastID {
+		b.LastID = o.LastID
+	}
+}
+
+// add accumulates the pays from firstID to lastID, which are after the End.
+func (s *PaySum) add(firstID, lastID string, sum, fee *Amount, count int) {
+	if len(s.Start) == 0 {
+		s.Start = firstID
+	}
+	s.End = lastID
+	s.Sum.Add(sum)
+	s.Fee.Add(fee)
+	s.Count += count
+}
+
+// PayPrunePreview is the response payload of pay/prune/preview. (what pay/prune would prune)
+type PayPrunePreview struct {
+	*PaySum
+	Address   string       `json:"address"`
+	StartTime *txtime.Time `json:"start_time"` // created time of the last pruned pay (exclusive)
+	EndTime   *txtime.Time `json:"end_time"`
+	Applied   *Amount      `json:"applied"` // sum - fee, the balance change
+}
+
 // PayResult _
 type PayResult struct {
 	Pay        *Pay        `json:"pay"`
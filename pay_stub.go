@@ -72,7 +72,7 @@ func (pb *PayStub) GetPayByOrderID(orderID string) (*Pay, error) {
 	defer iter.Close()
 
 	if !iter.HasNext() {
-		return nil, errors.New("cannot find pay of the order id")
+		return nil, NotExistedPayError{}
 	}
 	kv, err := iter.Next()
 	if nil != err {
@@ -86,7 +86,16 @@ func (pb *PayStub) GetPayByOrderID(orderID string) (*Pay, error) {
 	return pay, nil
 }
 
-// PutPay _
+// CreateBucketKey _ (if rid is empty, it returns the range key of the buckets)
+func (pb *PayStub) CreateBucketKey(id string, start int64, rid string) string {
+	key := "PAYB_" + id + "_" + sumBucketKeyTime(start)
+	if rid != "" {
+		key += "_" + rid
+	}
+	return key
+}
+
+// PutPay puts the new pay and adds it to the pay bucket.
 func (pb *PayStub) PutPay(pay *Pay) error {
 	data, err := json.Marshal(pay)
 	if err != nil {
@@ -95,6 +104,33 @@ func (pb *PayStub) PutPay(pay *Pay) error {
 	if err = pb.stub.PutState(pb.CreateKey(pay.PayID), data); err != nil {
 		return errors.Wrap(err, "failed to put the balance state")
 	}
+	return pb.putPayBucket(pay)
+}
+
+// putPayBucket adds the pay to the bucket of the merchant and the related account.
+func (pb *PayStub) putPayBucket(pay *Pay) error {
+	start := sumBucketStart(pay.CreatedTime)
+	key := pb.CreateBucketKey(pay.DOCTYPEID, start, pay.RID)
+	data, err := pb.stub.GetState(key)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the pay bucket state")
+	}
+	bucket := &PayBucket{}
+	if data == nil {
+		if err = NewSumBucketStub(pb.stub).EnsureOrigin(pay.DOCTYPEID, pay.CreatedTime); err != nil {
+			return err
+		}
+		bucket = &PayBucket{DOCTYPEID: pay.DOCTYPEID, RID: pay.RID, Start: start}
+	} else if err = json.Unmarshal(data, bucket); err != nil {
+		return errors.Wrap(err, "failed to unmarshal the pay bucket")
+	}
+	bucket.Add(pay)
+	if data, err = json.Marshal(bucket); err != nil {
+		return errors.Wrap(err, "failed to marshal the pay bucket")
+	}
+	if err = pb.stub.PutState(key, data); err != nil {
+		return errors.Wrap(err, "failed to put the pay bucket state")
+	}
 	return nil
 }
 
@@ -111,31 +147,67 @@ func (pb *PayStub) PutParentPay(key string, pay *Pay) error {
 }
 
 // Pay _
-func (pb *PayStub) Pay(sender *Balance, receiver string, amount, fee Amount, orderID, memo string) (*PayResult, error) {
+// If the receiver(merchant) bears the fee, the fee is kept in the pay and deducted when the pays are pruned.
+// Otherwise, the fee is charged to the sender or the sponsor now. (the sponsor is used only if the bearer is FeeBearerSponsor)
+func (pb *PayStub) Pay(sender *Balance, receiver string, sponsor *Balance, amount, fee Amount, bearer FeeBearer, orderID, memo string) (*PayResult, error) {
 	ts, err := txtime.GetTime(pb.stub)
 	if nil != err {
 		return nil, errors.Wrap(err, "failed to get the timestamp")
 	}
+	payFee := *ZeroAmount()
+	if bearer == FeeBearerReceiver {
+		payFee = fee
+	}
 	payid := fmt.Sprintf("%d%s", ts.UnixNano(), pb.stub.GetTxID())
-	pay := NewPay(receiver, payid, amount, fee, sender.GetID(), "", orderID, memo, ts)
+	pay := NewPay(receiver, payid, amount, payFee, sender.GetID(), "", orderID, memo, ts)
 	if err = pb.PutPay(pay); nil != err {
 		return nil, errors.Wrap(err, "failed to put new pay")
 	}
 
+	bb := NewBalanceStub(pb.stub)
+
 	amount.Neg()
 	sender.Amount.Add(&amount)
+	if bearer == FeeBearerSender {
+		sender.Amount.Add(fee.Copy().Neg()) // fee
+	}
 	sender.UpdatedTime = ts
-	if err = NewBalanceStub(pb.stub).PutBalance(sender); nil != err {
+	if err = bb.PutBalance(sender); nil != err {
 		return nil, errors.Wrap(err, "failed to update sender balance")
 	}
 
 	var sbl *BalanceLog
 	sbl = NewBalancePayLog(sender, pay)
 	sbl.CreatedTime = ts
-	if err = NewBalanceStub(pb.stub).PutBalanceLog(sbl); err != nil {
+	if bearer == FeeBearerSender && fee.Sign() > 0 {
+		sbl.Fee = &fee
+	}
+	if err = bb.PutBalanceLog(sbl); err != nil {
 		return nil, errors.Wrap(err, "failed to update sender balance log")
 	}
 
+	if bearer == FeeBearerReceiver {
+		return NewPayResult(pay, sbl), nil
+	}
+
+	payer := sender
+	if bearer == FeeBearerSponsor && fee.Sign() > 0 {
+		payer = sponsor
+		sponsor.Amount.Add(fee.Copy().Neg())
+		sponsor.UpdatedTime = ts
+		if err = bb.PutBalance(sponsor); nil != err {
+			return nil, errors.Wrap(err, "failed to update sponsor balance")
+		}
+		fbl := NewBalanceFeeSponsorLog(sponsor, sender.GetID(), fee, memo, orderID)
+		fbl.CreatedTime = ts
+		if err = bb.PutBalanceLog(fbl); err != nil {
+			return nil, errors.Wrap(err, "failed to update sponsor balance log")
+		}
+	}
+	if _, err = NewFeeStub(pb.stub).CreateFee(payer.GetID(), fee); err != nil {
+		return nil, err
+	}
+
 	return NewPayResult(pay, sbl), nil
 }
 
@@ -177,50 +249,109 @@ func (pb *PayStub) Refund(sender, receiver *Balance, amount, fee Amount, memo, o
 }
 
 // GetPaySumByTime _{end sum next}
+// The pays in the complete buckets are summed by the buckets, and the others are summed by the query. (see PayBucket)
 func (pb *PayStub) GetPaySumByTime(id string, stime, etime *txtime.Time) (*PaySum, error) {
+	origin, err := NewSumBucketStub(pb.stub).GetOrigin(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &PaySum{Sum: ZeroAmount(), Fee: ZeroAmount(), HasMore: false}
+	bstart, bend := sumBucketRange(origin, stime, etime)
+	if bstart >= bend { // no complete bucket
+		if _, err = pb.sumPaysByQuery(cs, id, stime, etime, PaysPruneSize); err != nil {
+			return nil, err
+		}
+		return cs, nil
+	}
+
+	headEnd, tailStart := sumBucketBoundaries(bstart, bend)
+	cnt, err := pb.sumPaysByQuery(cs, id, stime, headEnd, PaysPruneSize)
+	if err != nil {
+		return nil, err
+	}
+	if !cs.HasMore {
+		if err = pb.sumPayBuckets(cs, id, bstart, bend); err != nil {
+			return nil, err
+		}
+	}
+	if !cs.HasMore {
+		if _, err = pb.sumPaysByQuery(cs, id, tailStart, etime, PaysPruneSize-cnt); err != nil {
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+// sumPaysByQuery adds the pays from stime to etime to the sum, and returns the number of the added pays.
+// If there are more pays than the limit, it sets HasMore.
+func (pb *PayStub) sumPaysByQuery(cs *PaySum, id string, stime, etime *txtime.Time, limit int) (int, error) {
 	query := CreateQueryPrunePays(id, stime, etime)
 	iter, err := pb.stub.GetQueryResult(query)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer iter.Close()
 
-	cs := &PaySum{HasMore: false}
-	c := &Pay{}
 	cnt := 0 //record counter
-	sum, _ := NewAmount("0")
-	fee, _ := NewAmount("0")
-
 	for iter.HasNext() {
-		cnt++
 		kv, err := iter.Next()
 		if nil != err {
-			return nil, err
+			return 0, err
 		}
-
-		err = json.Unmarshal(kv.Value, c)
-		if err != nil {
-			return nil, err
-		}
-
-		if 1 == cnt {
-			cs.Start = c.PayID
+		c := &Pay{}
+		if err = json.Unmarshal(kv.Value, c); err != nil {
+			return 0, err
 		}
-
-		if cnt > PaysPruneSize {
+		if cnt >= limit {
 			cs.HasMore = true
-			cnt--
 			break
 		}
-		sum = sum.Add(&c.Amount)
-		fee = fee.Add(&c.Fee)
-		cs.End = c.PayID
+		cnt++
+		cs.add(c.PayID, c.PayID, &c.Amount, &c.Fee, 1)
 	}
-	cs.Count = cnt
-	cs.Sum = sum
-	cs.Fee = fee
+	return cnt, nil
+}
 
-	return cs, nil
+// sumPayBuckets adds the buckets [bstart, bend) to the sum.
+// If there are more buckets than SumBucketsPruneSize, it stops at the bucket boundary and sets HasMore.
+func (pb *PayStub) sumPayBuckets(cs *PaySum, id string, bstart, bend int64) error {
+	iter, err := pb.stub.GetStateByRange(pb.CreateBucketKey(id, bstart, ""), pb.CreateBucketKey(id, bend, ""))
+	if err != nil {
+		return errors.Wrap(err, "failed to get the pay buckets")
+	}
+	defer iter.Close()
+
+	read := 0
+	var group *PayBucket // merged buckets of the same start
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		b := &PayBucket{}
+		if err = json.Unmarshal(kv.Value, b); err != nil {
+			return errors.Wrap(err, "failed to unmarshal the pay bucket")
+		}
+		if group != nil && group.Start != b.Start {
+			cs.add(group.FirstID, group.LastID, &group.Sum, &group.Fee, group.Count)
+			group = nil
+			if read >= SumBucketsPruneSize {
+				cs.HasMore = true
+				return nil
+			}
+		}
+		read++
+		if group == nil {
+			group = b
+		} else {
+			group.merge(b)
+		}
+	}
+	if group != nil {
+		cs.add(group.FirstID, group.LastID, &group.Sum, &group.Fee, group.Count)
+	}
+	return nil
 }
 
 // GetPaysByTime _
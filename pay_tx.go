@@ -14,151 +14,57 @@ import (
 	"github.com/key-inside/kiesnet-ccpkg/kid"
 	"github.com/key-inside/kiesnet-ccpkg/stringset"
 	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
 )
 
 // params[0] : sender's address or empty string
 // params[1] : receiver's address
-// params[2] : amount(>0)
+// params[2] : amount(>0) (big int string | decimal-formatted string)
 // params[3] : optional. order id
 // params[4] : optional. memo (see MemoMaxLength)
 // params[5] : optional. expiry (duration represented by int64 seconds, multi-sig only)
+// params[6] : optional. fee bearer ("sender" | "receiver" | "sponsor", empty is the default of the fee policy. multi-sig pay supports only the receiver)
+// params[7] : optional. sponsor address (fee bearer "sponsor" only)
 func pay(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 3 {
-		return shim.Error("incorrect number of parameters. expecting 3+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 3+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if nil != err {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
-	// addresses
-	rAddr, err := ParseAddress(params[1])
-	if err != nil {
-		return responseError(err, "failed to parse the receiver's account address")
-	}
-	var sAddr *Address
-	if len(params[0]) > 0 {
-		sAddr, err = ParseAddress(params[0])
-		if err != nil {
-			return responseError(err, "failed to parse the sender's account address")
-		}
-		if rAddr.Code != sAddr.Code { // not same token
-			return shim.Error("different token accounts")
-		}
-	} else {
-		sAddr = NewAddress(rAddr.Code, AccountTypePersonal, kid)
-	}
-
-	// prevent from paying to self
-	if sAddr.Equal(rAddr) {
-		return shim.Error("can't pay to self")
-	}
-
-	// amount
-	amount, err := NewAmount(params[2])
+	pp, err := getValidatedPayParameters(stub, kid, params)
 	if nil != err {
-		return shim.Error(err.Error())
-	}
-	if amount.Sign() < 1 {
-		return shim.Error("invalid amount. must be greater than 0")
-	}
-
-	ab := NewAccountStub(stub, rAddr.Code)
-
-	// sender account validation
-	sender, err := ab.GetAccount(sAddr)
-	if nil != err {
-		return responseError(err, "failed to get the sender account")
-	}
-	if !sender.HasHolder(kid) {
-		return shim.Error("invoker is not holder")
-	}
-	if sender.IsSuspended() {
-		return shim.Error("the sender account is suspended")
-	}
-
-	// receiver account validation
-	receiver, err := ab.GetAccount(rAddr)
-	if nil != err {
-		return responseError(err, "failed to get the receiver account")
-	}
-	if receiver.IsSuspended() {
-		return shim.Error("the receiver account is suspended")
-	}
-
-	// sender balance
-	bb := NewBalanceStub(stub)
-	sBal, err := bb.GetBalance(sender.GetID())
-	if nil != err {
-		return responseError(err, "failed to get the sender's balance")
-	}
-
-	if sBal.Amount.Cmp(amount) < 0 {
-		return shim.Error("not enough balance")
-	}
-
-	// options
-	orderID := ""
-	memo := ""
-	var expiry int64
-	signers := stringset.New(kid)
-	if a, ok := sender.(*JointAccount); ok {
-		signers.AppendSet(a.Holders)
-	}
-	// order id
-	if len(params) > 3 {
-		orderID = params[3]
-		// memo
-		if len(params) > 4 {
-			if len(params[4]) > MemoMaxLength { // length limit
-				memo = params[4][:MemoMaxLength]
-			} else {
-				memo = params[4]
-			}
-			// expiry time
-			if len(params) > 5 && len(params[5]) > 0 {
-				expiry, err = strconv.ParseInt(params[5], 10, 64)
-				if err != nil {
-					responseError(err, "invalid expiry: need seconds")
-				}
-			}
-		}
+		return responseError(err, "")
 	}
 
 	var log *BalanceLog // log for response
 	payResult := &PayResult{}
-	if signers.Size() > 1 {
-		if signers.Size() > 128 {
-			return shim.Error("too many signers")
-		}
+	if pp.signers.Size() > 1 {
 		// pending balance id
 		pbID := stub.GetTxID()
 		// contract
-		doc := []string{"pay", pbID, sender.GetID(), receiver.GetID(), amount.String(), orderID, memo}
+		doc := []string{"pay", pbID, pp.sender.GetID(), pp.receiver.GetID(), pp.amount.String(), pp.orderID, pp.memo}
 		docb, err := json.Marshal(doc)
 		if err != nil {
 			return responseError(err, "failed to marshal contract document")
 		}
-		con, err := contract.CreateContract(stub, docb, expiry, signers)
+		con, err := contract.CreateContract(stub, docb, pp.expiry, pp.signers)
 		if err != nil {
 			return responseError(err, "failed to create a contract")
 		}
 		// pending balance
 		// Cannot calculate fee amount now.
 		// Fee amount must be calculated when the contract gets all of its approval.
-		log, err = bb.Deposit(pbID, sBal, con, *amount, nil, memo, orderID)
+		log, err = NewBalanceStub(stub).Deposit(pbID, pp.sBal, con, *pp.amount, nil, pp.memo, pp.orderID)
 		if err != nil {
 			return responseError(err, "failed to create the pending balance")
 		}
 	} else {
-		fb := NewFeeStub(stub)
-		feeAmount, err := fb.CalcFee(rAddr, "pay", *amount)
-		if err != nil {
-			return responseError(err, "failed to get the fee amount")
-		}
-		payResult, err = NewPayStub(stub).Pay(sBal, receiver.GetID(), *amount, *feeAmount, orderID, memo)
+		payResult, err = NewPayStub(stub).Pay(pp.sBal, pp.receiver.GetID(), pp.spBal, *pp.amount, *pp.fee, pp.bearer, pp.orderID, pp.memo)
 		if err != nil {
 			return responseError(err, "failed to pay")
 		}
@@ -177,27 +83,18 @@ func pay(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 }
 
 // params[0] : original pay id
-// params[1] : refund amount
+// params[1] : refund amount (big int string | decimal-formatted string)
 // params[2] : optional. memo (see MemoMaxLength)
 // params[3] : optional. order id
 func payRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 2 {
-		return shim.Error("incorrect number of parameters. expecting 2+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if nil != err {
-		return shim.Error(err.Error())
-	}
-
-	// amount
-	amount, err := NewAmount(params[1])
-	if nil != err {
-		return shim.Error(err.Error())
-	}
-	if amount.Sign() < 1 {
-		return shim.Error("invalid amount. must be greater than 0")
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	pb := NewPayStub(stub)
@@ -215,6 +112,15 @@ func payRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the account")
 	}
 
+	// amount (big int string | decimal-formatted string)
+	amount, err := NewTokenStub(stub).ParseAmount(sAddr.Code, params[1])
+	if nil != err {
+		return responseError(err, "")
+	}
+	if amount.Sign() < 1 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+	}
+
 	// receiver's id from the original pay
 	rid := parentPay.RID
 
@@ -225,16 +131,16 @@ func payRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response
 	}
 
 	if rAddr.Code != sAddr.Code {
-		return shim.Error("different token accounts")
+		return responseErrorCode(ErrorCodeInvalidParameter, "different token accounts")
 	}
 
 	if sAddr.Equal(rAddr) {
-		return shim.Error("can't refund to self")
+		return responseErrorCode(ErrorCodeInvalidParameter, "can't refund to self")
 	}
 
 	// refund amount validation
 	if parentPay.Amount.Cmp(parentPay.TotalRefund.Copy().Add(amount)) < 0 {
-		return shim.Error("can't exceed the original pay amount")
+		return responseErrorCode(ErrorCodeInvalidParameter, "can't exceed the original pay amount")
 	}
 
 	ab := NewAccountStub(stub, rAddr.Code)
@@ -245,10 +151,10 @@ func payRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the sender account")
 	}
 	if !sender.HasHolder(kid) {
-		return shim.Error("invoker is not holder")
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
 	}
 	if sender.IsSuspended() {
-		return shim.Error("the sender account is suspended")
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the sender account is suspended")
 	}
 
 	// receiver account validation
@@ -257,7 +163,7 @@ func payRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the receiver account")
 	}
 	if receiver.IsSuspended() {
-		return shim.Error("the receiver account is suspended")
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the receiver account is suspended")
 	}
 
 	// sender balance
@@ -318,93 +224,23 @@ func payRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response
 }
 
 // params[0] : address to prune or token code
-// params[1] : 10 minutes limit flag. if the value is true, 10 minutes check is activated.
+// params[1] : safety window flag. if the value is true, the pays in the prune window of the token are not pruned. (see Token.PruneWindow)
 // params[2] : optional. end time
 func payPrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
-	if len(params) < 2 {
-		return shim.Error("incorrect number of parameters. expecting 2+")
-	}
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if nil != err {
-		return shim.Error(err.Error())
-	}
-
-	var addr *Address
-	code, err := ValidateTokenCode(params[0])
-	if nil == err { // by token code
-		addr = NewAddress(code, AccountTypePersonal, kid)
-	} else { // by address
-		addr, err = ParseAddress(params[0])
-		if nil != err {
-			return responseError(err, "failed to get the account")
-		}
-	}
-	// account validation
-	account, err := NewAccountStub(stub, addr.Code).GetAccount(addr)
-	if nil != err {
-		return responseError(err, "failed to get the account")
-	}
-	if !account.HasHolder(kid) {
-		return shim.Error("invoker is not holder")
-	}
-	if account.IsSuspended() {
-		return shim.Error("the account is suspended")
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
-	bb := NewBalanceStub(stub)
-	bal, err := bb.GetBalance(account.GetID())
-	if nil != err {
-		return responseError(err, "failed to get the balance")
-	}
-	pb := NewPayStub(stub)
-
-	// start time
-	stime := txtime.Unix(0, 0)
-	if 0 < len(bal.LastPrunedPayID) {
-		s, err := strconv.ParseInt(bal.LastPrunedPayID[0:10], 10, 64)
-		if nil != err {
-			return responseError(err, "failed to get seconds from timestamp")
-		}
-		n, err := strconv.ParseInt(bal.LastPrunedPayID[10:19], 10, 64)
-		if nil != err {
-			return responseError(err, "failed to get nanoseconds from timestamp")
-		}
-		stime = txtime.Unix(s, n)
-	}
-
-	ts, err := txtime.GetTime(stub)
-	if nil != err {
-		return responseError(err, "failed to get the timestamp")
-	}
-
-	var etime *txtime.Time
-	// end time
-	if len(params) > 2 {
-		seconds, err := strconv.ParseInt(params[2], 10, 64)
-		if nil != err {
-			return responseError(err, "failed to parse the end time")
-		}
-		etime = txtime.Unix(seconds, 0)
-	} else {
-		etime = ts
-	}
-
-	//boolean validation
-	b, err := strconv.ParseBool(params[1])
+	pp, err := getValidatedPayPruneParameters(stub, kid, params)
 	if err != nil {
-		return shim.Error("wrong first params value. the value must be true or false")
-	}
-
-	if b == true {
-		// safe time is current transaction time minus 10 minutes. this is to prevent missing pay(s) because of the time differences(+/- 5min) on different servers/devices
-		safeTime := txtime.New(ts.Add(-6e+11))
-		if nil == etime || etime.Cmp(safeTime) > 0 {
-			etime = safeTime
-		}
+		return responseError(err, "")
 	}
+	bal, ts := pp.bal, pp.ts
+	bb := NewBalanceStub(stub)
 
-	paySum, err := pb.GetPaySumByTime(account.GetID(), stime, etime)
+	paySum, err := NewPayStub(stub).GetPaySumByTime(bal.GetID(), pp.stime, pp.etime)
 	if nil != err {
 		return responseError(err, "failed to get pay(s) to prune")
 	}
@@ -424,15 +260,15 @@ func payPrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 			return responseError(err, "failed to update balance")
 		}
 
-		if _, err = NewFeeStub(stub).CreateFee(account.GetID(), *paySum.Fee); err != nil {
-			return shim.Error(err.Error())
+		if _, err = NewFeeStub(stub).CreateFee(bal.GetID(), *paySum.Fee); err != nil {
+			return responseError(err, "")
 		}
 
 		// balance log
 		rbl := NewBalancePrunePayLog(bal, *applied, paySum.Start, paySum.End)
 		rbl.CreatedTime = ts
 		if err = bb.PutBalanceLog(rbl); err != nil {
-			return shim.Error(err.Error())
+			return responseError(err, "")
 		}
 
 		data, err := json.Marshal(paySum)
@@ -449,6 +285,41 @@ func payPrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	return shim.Error("found no record to prune.")
 }
 
+// Preview the range of pay/prune without writing any state.
+// params[0] : address to prune or token code
+// params[1] : safety window flag (see pay/prune)
+// params[2] : optional. end time
+func payPrunePreview(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if nil != err {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	pp, err := getValidatedPayPruneParameters(stub, kid, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	paySum, err := NewPayStub(stub).GetPaySumByTime(pp.bal.GetID(), pp.stime, pp.etime)
+	if nil != err {
+		return responseError(err, "failed to get pay(s) to prune")
+	}
+
+	preview := &PayPrunePreview{
+		PaySum:    paySum,
+		Address:   pp.bal.GetID(),
+		StartTime: pp.stime,
+		EndTime:   pp.etime,
+		Applied:   paySum.Sum.Copy().Add(paySum.Fee.Copy().Neg()),
+	}
+	data, err := json.Marshal(preview)
+	if nil != err {
+		return responseError(err, "failed to marshal the pay prune preview")
+	}
+	return shim.Success(data)
+}
+
 // params[0] : token code | account address
 // params[1] : sort order ("asc" or "desc")
 // params[2] : bookmark
@@ -457,13 +328,13 @@ func payPrune(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 // params[5] : end time (time represented by int64 seconds)
 func payList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, false)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	bookmark := ""
@@ -489,7 +360,7 @@ func payList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 					if len(params[4]) > 0 {
 						seconds, err := strconv.ParseInt(params[4], 10, 64)
 						if err != nil {
-							return shim.Error("invalid start time: need seconds since 1970")
+							return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
 						}
 						stime = txtime.Unix(seconds, 0)
 					}
@@ -498,11 +369,11 @@ func payList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 						if len(params[5]) > 0 {
 							seconds, err := strconv.ParseInt(params[5], 10, 64)
 							if err != nil {
-								return shim.Error("invalid end time: need seconds since 1970")
+								return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
 							}
 							etime = txtime.Unix(seconds, 0)
 							if stime != nil && stime.Cmp(etime) >= 0 {
-								return shim.Error("invalid time parameters")
+								return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
 							}
 						}
 					}
@@ -538,13 +409,13 @@ func payList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 // params[1] : optional. order id (vendor specific)
 func payGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1 or 2")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1 or 2")
 	}
 
 	// authentication
 	_, err := kid.GetID(stub, false)
 	if nil != err {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	payID := params[0]
@@ -557,7 +428,7 @@ func payGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	var pay *Pay
 	if "" == payID {
 		if "" == orderID {
-			return shim.Error("invalid parameter")
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid parameter")
 		}
 		// get by order id
 		pay, err = pb.GetPayByOrderID(orderID)
@@ -575,12 +446,260 @@ func payGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	return shim.Success(data)
 }
 
+// helpers
+
+// payPruneParameters is the validated parameters of pay/prune. (see getValidatedPayPruneParameters)
+type payPruneParameters struct {
+	bal   *Balance
+	stime *txtime.Time // created time of the last pruned pay
+	etime *txtime.Time
+	ts    *txtime.Time
+}
+
+// getValidatedPayPruneParameters validates the pay/prune parameters without writing any state.
+// It is shared by pay/prune and pay/prune/preview.
+func getValidatedPayPruneParameters(stub shim.ChaincodeStubInterface, kid string, params []string) (*payPruneParameters, error) {
+	if len(params) < 2 {
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 2+"}
+	}
+
+	var addr *Address
+	code, err := ValidateTokenCode(params[0])
+	if nil == err { // by token code
+		addr = NewAddress(code, AccountTypePersonal, kid)
+	} else { // by address
+		addr, err = ParseAddress(params[0])
+		if nil != err {
+			return nil, errors.Wrap(err, "failed to get the account")
+		}
+	}
+	// account validation
+	account, err := NewAccountStub(stub, addr.Code).GetAccount(addr)
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the account")
+	}
+	if !account.HasHolder(kid) {
+		return nil, NotHolderError{}
+	}
+	if account.IsSuspended() {
+		return nil, SuspendedAccountError{}
+	}
+
+	bal, err := NewBalanceStub(stub).GetBalance(account.GetID())
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the balance")
+	}
+
+	// start time
+	stime := txtime.Unix(0, 0)
+	if 0 < len(bal.LastPrunedPayID) {
+		if stime, err = parseUTXOIDTime(bal.LastPrunedPayID); err != nil {
+			return nil, errors.Wrap(err, "failed to get the time of the last pruned pay")
+		}
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	// end time
+	etime := ts
+	if len(params) > 2 {
+		seconds, err := strconv.ParseInt(params[2], 10, 64)
+		if nil != err {
+			return nil, InvalidParameterError{reason: "failed to parse the end time"}
+		}
+		etime = txtime.Unix(seconds, 0)
+	}
+
+	//boolean validation
+	safely, err := strconv.ParseBool(params[1])
+	if err != nil {
+		return nil, InvalidParameterError{reason: "wrong first params value. the value must be true or false"}
+	}
+	if safely {
+		token, err := NewTokenStub(stub).GetToken(addr.Code)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the token")
+		}
+		if safeTime := token.GetPruneSafeTime(ts); etime.Cmp(safeTime) > 0 {
+			etime = safeTime
+		}
+	}
+
+	return &payPruneParameters{bal: bal, stime: stime, etime: etime, ts: ts}, nil
+}
+
+// payParameters is the validated parameters of pay. (see getValidatedPayParameters)
+type payParameters struct {
+	sender   AccountInterface
+	receiver AccountInterface
+	sBal     *Balance
+	amount   *Amount
+	fee      *Amount   // bearer's fee at this time. multi-sig pay recalculates it on execution.
+	applied  *Amount   // debit of the sender (amount + fee if the sender bears the fee)
+	bearer   FeeBearer // resolved bearer (not default)
+	spBal    *Balance  // sponsor's balance (FeeBearerSponsor only)
+	orderID  string
+	memo     string
+	expiry   int64
+	signers  *stringset.Set
+}
+
+// getValidatedPayParameters validates the pay parameters without writing any state.
+// It is shared by pay and quote.
+func getValidatedPayParameters(stub shim.ChaincodeStubInterface, kid string, params []string) (*payParameters, error) {
+	if len(params) < 3 {
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 3+"}
+	}
+
+	// addresses
+	rAddr, err := ParseAddress(params[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the receiver's account address")
+	}
+	var sAddr *Address
+	if len(params[0]) > 0 {
+		sAddr, err = ParseAddress(params[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the sender's account address")
+		}
+		if rAddr.Code != sAddr.Code { // not same token
+			return nil, InvalidParameterError{reason: "different token accounts"}
+		}
+	} else {
+		sAddr = NewAddress(rAddr.Code, AccountTypePersonal, kid)
+	}
+
+	// prevent from paying to self
+	if sAddr.Equal(rAddr) {
+		return nil, InvalidParameterError{reason: "can't pay to self"}
+	}
+
+	// amount (big int string | decimal-formatted string)
+	amount, err := NewTokenStub(stub).ParseAmount(rAddr.Code, params[2])
+	if nil != err {
+		return nil, err
+	}
+	if amount.Sign() < 1 {
+		return nil, InvalidAmountError{reason: "invalid amount. must be greater than 0"}
+	}
+
+	ab := NewAccountStub(stub, rAddr.Code)
+
+	// sender account validation
+	sender, err := ab.GetAccount(sAddr)
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the sender account")
+	}
+	if !sender.HasHolder(kid) {
+		return nil, NotHolderError{}
+	}
+	if sender.IsSuspended() {
+		return nil, SuspendedAccountError{role: "sender"}
+	}
+
+	// receiver account validation
+	receiver, err := ab.GetAccount(rAddr)
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the receiver account")
+	}
+	if receiver.IsSuspended() {
+		return nil, SuspendedAccountError{role: "receiver"}
+	}
+
+	// sender balance
+	sBal, err := NewBalanceStub(stub).GetBalance(sender.GetID())
+	if nil != err {
+		return nil, errors.Wrap(err, "failed to get the sender's balance")
+	}
+
+	pp := &payParameters{
+		sender:   sender,
+		receiver: receiver,
+		sBal:     sBal,
+		amount:   amount,
+		signers:  stringset.New(kid),
+	}
+	if a, ok := sender.(*JointAccount); ok {
+		pp.signers.AppendSet(a.Holders)
+	}
+
+	// options
+	// order id
+	if len(params) > 3 {
+		pp.orderID = params[3]
+		// memo
+		if len(params) > 4 {
+			if len(params[4]) > MemoMaxLength { // length limit
+				pp.memo = params[4][:MemoMaxLength]
+			} else {
+				pp.memo = params[4]
+			}
+			// expiry time
+			if len(params) > 5 && len(params[5]) > 0 {
+				pp.expiry, err = strconv.ParseInt(params[5], 10, 64)
+				if err != nil {
+					return nil, InvalidParameterError{reason: "invalid expiry: need seconds"}
+				}
+			}
+		}
+	}
+
+	if pp.signers.Size() > 128 {
+		return nil, TooManySignersError{}
+	}
+
+	// fee
+	bearer, sponsor := FeeBearerDefault, ""
+	if len(params) > 6 {
+		if bearer, err = ParseFeeBearer(params[6]); err != nil {
+			return nil, InvalidParameterError{reason: err.Error()}
+		}
+		if len(params) > 7 {
+			sponsor = params[7]
+		}
+	}
+	if pp.signers.Size() > 1 { // the contract calculates the merchant's fee on execution
+		if bearer != FeeBearerDefault && bearer != FeeBearerReceiver {
+			return nil, InvalidParameterError{reason: "multi-sig pay supports only the receiver fee bearer"}
+		}
+		bearer = FeeBearerReceiver
+	}
+	var spAddr *Address
+	if bearer == FeeBearerSponsor {
+		if spAddr, pp.spBal, err = getValidatedFeeSponsor(stub, sponsor, sAddr, rAddr); err != nil {
+			return nil, err
+		}
+	} else if len(sponsor) > 0 {
+		return nil, InvalidParameterError{reason: "sponsor is only for the sponsor fee bearer"}
+	}
+	if pp.fee, pp.bearer, err = NewFeeStub(stub).CalcBearerFee(sAddr, rAddr, spAddr, "pay", *amount, bearer); err != nil {
+		return nil, errors.Wrap(err, "failed to get the fee amount")
+	}
+	pp.applied = amount.Copy()
+	switch pp.bearer {
+	case FeeBearerSender:
+		pp.applied.Add(pp.fee)
+	case FeeBearerSponsor:
+		if pp.spBal.Amount.Cmp(pp.fee) < 0 {
+			return nil, NotEnoughBalanceError{}
+		}
+	} // receiver: deducted when the pays are pruned
+	if sBal.Amount.Cmp(pp.applied) < 0 {
+		return nil, NotEnoughBalanceError{}
+	}
+
+	return pp, nil
+}
+
 // contract callbacks
 
 // doc: ["pay", pending-balance-ID, sender-ID, receiver-ID, amount, order-ID, memo]
 func executePay(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 7 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	// pending balance
@@ -591,14 +710,13 @@ func executePay(stub shim.ChaincodeStubInterface, cid string, doc []interface{})
 	}
 	// validate
 	if pb.Type != PendingBalanceTypeContract || pb.RID != cid {
-		return shim.Error("invalid pending balance")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid pending balance")
 	}
 
 	// sender balance : using response
 	sBal, err := bb.GetBalance(doc[2].(string))
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the sender's balance")
+		return responseError(err, "failed to get the sender's balance")
 	}
 
 	fb := NewFeeStub(stub)
@@ -0,0 +1,177 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// PoolDefaultFeeRate is the default swap fee rate of the pool.
+const PoolDefaultFeeRate = "0.003"
+
+// PoolMinLiquidity is the shares locked forever when the pool is created.
+// It prevents the share price from being inflated by the first provider.
+const PoolMinLiquidity = 1000
+
+// ParsePoolID parses the pool ID ("CODE/CODE") and returns the canonical pool ID (sorted token codes)
+// and the token codes in the given order.
+func ParsePoolID(id string) (string, string, string, error) {
+	first, second, err := ParseDexMarket(id)
+	if err != nil {
+		return "", "", "", InvalidParameterError{reason: "invalid pool id. expecting 'CODE/CODE'"}
+	}
+	return PoolID(first, second), first, second, nil
+}
+
+// PoolID returns the canonical pool ID of the token codes.
+func PoolID(code1, code2 string) string {
+	codes := []string{code1, code2}
+	sort.Strings(codes)
+	return strings.Join(codes, "/")
+}
+
+// ParsePoolFeeRate validates the swap fee rate. (0 <= rate < 1)
+func ParsePoolFeeRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() < 0 || r.Cmp(big.NewRat(1, 1)) >= 0 {
+		return nil, InvalidParameterError{reason: "invalid fee rate. must be 0 <= rate < 1"}
+	}
+	return r, nil
+}
+
+// Pool is the constant-product liquidity pool of two tokens.
+// The reserves are kept in the chaincode-owned accounts (joint accounts without holders).
+type Pool struct {
+	DOCTYPEID   string       `json:"@pool"` // "A/B" (sorted token codes)
+	TokenA      string       `json:"token_a"`
+	TokenB      string       `json:"token_b"`
+	DecimalA    int          `json:"decimal_a"`
+	DecimalB    int          `json:"decimal_b"`
+	AccountA    string       `json:"account_a"` // reserve account of token A
+	AccountB    string       `json:"account_b"` // reserve account of token B
+	ReserveA    Amount       `json:"reserve_a"`
+	ReserveB    Amount       `json:"reserve_b"`
+	TotalShares Amount       `json:"total_shares"`
+	FeeRate     string       `json:"fee_rate"` // swap fee rate, the fee remains in the reserves (for providers)
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+	UpdatedTime *txtime.Time `json:"updated_time,omitempty"`
+}
+
+// GetID implements Identifiable
+func (p *Pool) GetID() string {
+	return p.DOCTYPEID
+}
+
+// Has returns true if the pool has the token.
+func (p *Pool) Has(code string) bool {
+	return p.TokenA == code || p.TokenB == code
+}
+
+// InitialShares returns the shares of the first provider and the locked shares. (sqrt(a*b) - PoolMinLiquidity)
+func (p *Pool) InitialShares(amountA, amountB *Amount) (*Amount, *Amount) {
+	n := new(big.Int).Mul(&amountA.Int, &amountB.Int)
+	total := NewAmountWithBigInt(n.Sqrt(n))
+	locked := NewAmountWithBigInt(big.NewInt(PoolMinLiquidity))
+	return total.Add(locked.Copy().Neg()), locked
+}
+
+// AddAmounts returns the amounts to be added in the pool ratio (not greater than the given amounts) and the shares.
+func (p *Pool) AddAmounts(maxA, maxB *Amount) (*Amount, *Amount, *Amount) {
+	amountA := maxA.Copy()
+	amountB := mulDivAmount(maxA, &p.ReserveB, &p.ReserveA, true)
+	if amountB.Cmp(maxB) > 0 {
+		amountB = maxB.Copy()
+		amountA = mulDivAmount(maxB, &p.ReserveA, &p.ReserveB, true)
+	}
+	sharesA := mulDivAmount(amountA, &p.TotalShares, &p.ReserveA, false)
+	sharesB := mulDivAmount(amountB, &p.TotalShares, &p.ReserveB, false)
+	if sharesA.Cmp(sharesB) < 0 {
+		return amountA, amountB, sharesA
+	}
+	return amountA, amountB, sharesB
+}
+
+// RemoveAmounts returns the amounts of the shares.
+func (p *Pool) RemoveAmounts(shares *Amount) (*Amount, *Amount) {
+	amountA := mulDivAmount(shares, &p.ReserveA, &p.TotalShares, false)
+	amountB := mulDivAmount(shares, &p.ReserveB, &p.TotalShares, false)
+	return amountA, amountB
+}
+
+// SwapAmount returns the output amount and the fee of the input amount.
+// out = (in - fee) * reserveOut / (reserveIn + in - fee)
+func (p *Pool) SwapAmount(codeIn string, amountIn *Amount) (*Amount, *Amount) {
+	reserveIn, reserveOut := &p.ReserveA, &p.ReserveB
+	if codeIn == p.TokenB {
+		reserveIn, reserveOut = reserveOut, reserveIn
+	}
+	rate, _ := ParsePoolFeeRate(p.FeeRate) // validated
+	fee := amountIn.Copy().MulRat(rate)
+	in := amountIn.Copy().Add(fee.Copy().Neg())
+	out := mulDivAmount(in, reserveOut, in.Copy().Add(reserveIn), false)
+	return out, fee
+}
+
+// Prices returns the price of token A in token B and the price of token B in token A. (decimal strings)
+func (p *Pool) Prices() (string, string) {
+	return poolPrice(&p.ReserveB, p.DecimalB, &p.ReserveA, p.DecimalA), poolPrice(&p.ReserveA, p.DecimalA, &p.ReserveB, p.DecimalB)
+}
+
+// PoolShare is the shares of the provider.
+type PoolShare struct {
+	DOCTYPEID   string       `json:"@pool_share"` // pool ID
+	Provider    string       `json:"provider"`    // KID
+	Shares      Amount       `json:"shares"`
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+	UpdatedTime *txtime.Time `json:"updated_time,omitempty"`
+}
+
+// PoolState is the response payload of pool/get.
+type PoolState struct {
+	*Pool
+	PriceA string `json:"price_a"` // price of token A in token B
+	PriceB string `json:"price_b"` // price of token B in token A
+}
+
+// PoolResult is the response payload of pool/create, pool/add, pool/remove and pool/swap.
+type PoolResult struct {
+	Pool        *Pool         `json:"pool"`
+	Share       *PoolShare    `json:"share,omitempty"`
+	AmountIn    *Amount       `json:"amount_in,omitempty"`  // swap only
+	AmountOut   *Amount       `json:"amount_out,omitempty"` // swap only
+	Fee         *Amount       `json:"fee,omitempty"`        // swap only
+	BalanceLogs []*BalanceLog `json:"balance_logs"`
+}
+
+// helpers
+
+// mulDivAmount returns a * b / c.
+func mulDivAmount(a, b, c *Amount, ceil bool) *Amount {
+	n := new(big.Int).Mul(&a.Int, &b.Int)
+	q, m := new(big.Int).QuoRem(n, &c.Int, new(big.Int))
+	if ceil && m.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return NewAmountWithBigInt(q)
+}
+
+// poolPrice returns (num / 10^numDecimal) / (den / 10^denDecimal) as the decimal string.
+func poolPrice(num *Amount, numDecimal int, den *Amount, denDecimal int) string {
+	if den.Sign() == 0 {
+		return "0"
+	}
+	exp := big.NewInt(int64(DexPriceDecimal + denDecimal - numDecimal))
+	scale := new(big.Int).Exp(big.NewInt(10), new(big.Int).Abs(exp), nil)
+	n := new(big.Int).Set(&num.Int)
+	d := new(big.Int).Set(&den.Int)
+	if exp.Sign() >= 0 {
+		n.Mul(n, scale)
+	} else {
+		d.Mul(d, scale)
+	}
+	return NewAmountWithBigInt(n.Quo(n, d)).DecimalString(DexPriceDecimal)
+}
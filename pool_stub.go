@@ -0,0 +1,266 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
+)
+
+// PoolStub _
+type PoolStub struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewPoolStub _
+func NewPoolStub(stub shim.ChaincodeStubInterface) *PoolStub {
+	return &PoolStub{stub}
+}
+
+// CreateKey _
+func (pb *PoolStub) CreateKey(id string) string {
+	return "POOL_" + id
+}
+
+// CreateShareKey _
+func (pb *PoolStub) CreateShareKey(id, kid string) string {
+	return "POOLS_" + id + "_" + kid
+}
+
+// GetPool _
+func (pb *PoolStub) GetPool(id string) (*Pool, error) {
+	data, err := pb.stub.GetState(pb.CreateKey(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the pool state")
+	}
+	if data == nil {
+		return nil, NotExistedPoolError{}
+	}
+	pool := &Pool{}
+	if err = json.Unmarshal(data, pool); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the pool")
+	}
+	return pool, nil
+}
+
+// PutPool _
+func (pb *PoolStub) PutPool(pool *Pool) error {
+	data, err := json.Marshal(pool)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the pool")
+	}
+	if err = pb.stub.PutState(pb.CreateKey(pool.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the pool state")
+	}
+	return nil
+}
+
+// GetReserveAccounts returns the reserve accounts of the token in all pools of the token.
+func (pb *PoolStub) GetReserveAccounts(code string) ([]string, error) {
+	prefix := pb.CreateKey("")
+	iter, err := pb.stub.GetStateByRange(prefix, prefix+string(utf8.MaxRune))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the pool states")
+	}
+	defer iter.Close()
+
+	accounts := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		pool := &Pool{}
+		if err = json.Unmarshal(kv.Value, pool); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal the pool")
+		}
+		if pool.TokenA == code {
+			accounts = append(accounts, pool.AccountA)
+		} else if pool.TokenB == code {
+			accounts = append(accounts, pool.AccountB)
+		}
+	}
+	return accounts, nil
+}
+
+// GetShare returns the shares of the provider. If it doesn't exist, it returns zero shares.
+func (pb *PoolStub) GetShare(id, kid string) (*PoolShare, error) {
+	data, err := pb.stub.GetState(pb.CreateShareKey(id, kid))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the pool share state")
+	}
+	share := &PoolShare{DOCTYPEID: id, Provider: kid}
+	if data != nil {
+		if err = json.Unmarshal(data, share); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal the pool share")
+		}
+	}
+	return share, nil
+}
+
+// PutShare puts the shares of the provider. If the shares are zero, it deletes the state.
+func (pb *PoolStub) PutShare(share *PoolShare) error {
+	key := pb.CreateShareKey(share.DOCTYPEID, share.Provider)
+	if share.Shares.Sign() == 0 {
+		if err := pb.stub.DelState(key); err != nil {
+			return errors.Wrap(err, "failed to delete the pool share state")
+		}
+		return nil
+	}
+	data, err := json.Marshal(share)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the pool share")
+	}
+	if err = pb.stub.PutState(key, data); err != nil {
+		return errors.Wrap(err, "failed to put the pool share state")
+	}
+	return nil
+}
+
+// CreatePool creates the reserve accounts and the pool with the initial liquidity of the provider.
+// It does not validate the parameters!
+func (pb *PoolStub) CreatePool(pool *Pool, provider string, balA, balB *Balance, amountA, amountB *Amount) (*PoolResult, error) {
+	ts, err := txtime.GetTime(pb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	s := newBalanceSettlement(pb.stub)
+	s.cache(balA)
+	s.cache(balB)
+
+	// reserve accounts
+	accountA, resBalA, err := NewAccountStub(pb.stub, pool.TokenA).CreatePoolAccount(pool.DOCTYPEID)
+	if err != nil {
+		return nil, err
+	}
+	accountB, resBalB, err := NewAccountStub(pb.stub, pool.TokenB).CreatePoolAccount(pool.DOCTYPEID)
+	if err != nil {
+		return nil, err
+	}
+	s.cache(resBalA) // not readable in this tx
+	s.cache(resBalB)
+	pool.AccountA = accountA.GetID()
+	pool.AccountB = accountB.GetID()
+	pool.CreatedTime = ts
+
+	shares, locked := pool.InitialShares(amountA, amountB)
+	pool.TotalShares = *locked
+
+	share := &PoolShare{DOCTYPEID: pool.DOCTYPEID, Provider: provider, Shares: *ZeroAmount()}
+	return pb.addLiquidity(s, pool, share, balA, balB, amountA, amountB, shares, ts)
+}
+
+// AddLiquidity moves the amounts from the provider's balances to the reserves and adds the shares.
+// It does not validate the parameters!
+func (pb *PoolStub) AddLiquidity(pool *Pool, share *PoolShare, balA, balB *Balance, amountA, amountB, shares *Amount) (*PoolResult, error) {
+	ts, err := txtime.GetTime(pb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	s := newBalanceSettlement(pb.stub)
+	s.cache(balA)
+	s.cache(balB)
+	return pb.addLiquidity(s, pool, share, balA, balB, amountA, amountB, shares, ts)
+}
+
+func (pb *PoolStub) addLiquidity(s *balanceSettlement, pool *Pool, share *PoolShare, balA, balB *Balance, amountA, amountB, shares *Amount, ts *txtime.Time) (*PoolResult, error) {
+	s.add(balA.DOCTYPEID, amountA.Copy().Neg(), nil)
+	s.add(balB.DOCTYPEID, amountB.Copy().Neg(), nil)
+	s.add(pool.AccountA, amountA, nil)
+	s.add(pool.AccountB, amountB, nil)
+
+	pool.ReserveA.Add(amountA)
+	pool.ReserveB.Add(amountB)
+	pool.TotalShares.Add(shares)
+	share.Shares.Add(shares)
+
+	return pb.settle(s, BalanceLogTypePoolAdd, pool, share, ts)
+}
+
+// RemoveLiquidity moves the amounts from the reserves to the provider's balances and removes the shares.
+// It does not validate the parameters!
+func (pb *PoolStub) RemoveLiquidity(pool *Pool, share *PoolShare, balA, balB *Balance, amountA, amountB, shares *Amount) (*PoolResult, error) {
+	ts, err := txtime.GetTime(pb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	s := newBalanceSettlement(pb.stub)
+	s.cache(balA)
+	s.cache(balB)
+	s.add(pool.AccountA, amountA.Copy().Neg(), nil)
+	s.add(pool.AccountB, amountB.Copy().Neg(), nil)
+	s.add(balA.DOCTYPEID, amountA, nil)
+	s.add(balB.DOCTYPEID, amountB, nil)
+
+	pool.ReserveA.Add(amountA.Copy().Neg())
+	pool.ReserveB.Add(amountB.Copy().Neg())
+	pool.TotalShares.Add(shares.Copy().Neg())
+	share.Shares.Add(shares.Copy().Neg())
+
+	return pb.settle(s, BalanceLogTypePoolRemove, pool, share, ts)
+}
+
+// Swap moves the input amount to the reserve and the output amount from the reserve.
+// The fee remains in the reserve.
+// It does not validate the parameters!
+func (pb *PoolStub) Swap(pool *Pool, balIn *Balance, addrOut, codeIn string, amountIn, amountOut, fee *Amount) (*PoolResult, error) {
+	ts, err := txtime.GetTime(pb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	reserveIn, reserveOut := pool.AccountA, pool.AccountB
+	if codeIn == pool.TokenB {
+		reserveIn, reserveOut = reserveOut, reserveIn
+		pool.ReserveB.Add(amountIn)
+		pool.ReserveA.Add(amountOut.Copy().Neg())
+	} else {
+		pool.ReserveA.Add(amountIn)
+		pool.ReserveB.Add(amountOut.Copy().Neg())
+	}
+
+	s := newBalanceSettlement(pb.stub)
+	s.cache(balIn)
+	s.add(balIn.DOCTYPEID, amountIn.Copy().Neg(), nil)
+	s.add(reserveIn, amountIn, nil)
+	s.add(reserveOut, amountOut.Copy().Neg(), nil)
+	s.add(addrOut, amountOut, nil)
+
+	res, err := pb.settle(s, BalanceLogTypePoolSwap, pool, nil, ts)
+	if err != nil {
+		return nil, err
+	}
+	res.AmountIn = amountIn
+	res.AmountOut = amountOut
+	res.Fee = fee
+	return res, nil
+}
+
+// settle puts the balances, the pool and the share.
+func (pb *PoolStub) settle(s *balanceSettlement, logType BalanceLogType, pool *Pool, share *PoolShare, ts *txtime.Time) (*PoolResult, error) {
+	logs, err := s.apply(logType, pool.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
+	pool.UpdatedTime = ts
+	if err = pb.PutPool(pool); err != nil {
+		return nil, err
+	}
+	if share != nil {
+		if share.CreatedTime == nil {
+			share.CreatedTime = ts
+		}
+		share.UpdatedTime = ts
+		if err = pb.PutShare(share); err != nil {
+			return nil, err
+		}
+	}
+	return &PoolResult{Pool: pool, Share: share, BalanceLogs: logs}, nil
+}
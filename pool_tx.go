@@ -0,0 +1,414 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/key-inside/kiesnet-ccpkg/kid"
+)
+
+// The pool routes use the PAOTs of the invoker, and the shares belong to the invoker(KID).
+
+// params[0] : token code
+// params[1] : token code
+// params[2] : initial liquidity amount of params[0] token (big int string | decimal-formatted string)
+// params[3] : initial liquidity amount of params[1] token (big int string | decimal-formatted string)
+// params[4] : optional. swap fee rate (default "0.003")
+func poolCreate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 4 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 4+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	id, first, second, err := ParsePoolID(params[0] + "/" + params[1])
+	if err != nil {
+		return responseError(err, "")
+	}
+	feeRate := PoolDefaultFeeRate
+	if len(params) > 4 && len(params[4]) > 0 {
+		feeRate = params[4]
+	}
+	if _, err = ParsePoolFeeRate(feeRate); err != nil {
+		return responseError(err, "")
+	}
+
+	pb := NewPoolStub(stub)
+	if _, err = pb.GetPool(id); err == nil {
+		return responseError(ExistedPoolError{id: id}, "")
+	} else if _, ok := err.(NotExistedPoolError); !ok {
+		return responseError(err, "failed to get the pool")
+	}
+
+	// tokens
+	tb := NewTokenStub(stub)
+	pool := &Pool{DOCTYPEID: id, FeeRate: feeRate}
+	amounts := map[string]*Amount{}
+	for i, code := range []string{first, second} {
+		token, err := tb.GetToken(code)
+		if err != nil {
+			return responseError(err, "failed to get the token")
+		}
+		amount, err := token.ParseAmount(params[2+i])
+		if err != nil {
+			return responseError(err, "")
+		}
+		if amount.Sign() <= 0 {
+			return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+		}
+		amounts[code] = amount
+		if len(pool.TokenA) == 0 || code < pool.TokenA {
+			pool.TokenB, pool.DecimalB = pool.TokenA, pool.DecimalA
+			pool.TokenA, pool.DecimalA = code, token.Decimal
+		} else {
+			pool.TokenB, pool.DecimalB = code, token.Decimal
+		}
+	}
+	amountA, amountB := amounts[pool.TokenA], amounts[pool.TokenB]
+	if shares, _ := pool.InitialShares(amountA, amountB); shares.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "too small initial liquidity")
+	}
+
+	balA, balB, err := getPoolProviderBalances(stub, pool, kid)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if balA.Amount.Cmp(amountA) < 0 || balB.Amount.Cmp(amountB) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	res, err := pb.CreatePool(pool, kid, balA, balB, amountA, amountB)
+	if err != nil {
+		return responseError(err, "failed to create the pool")
+	}
+
+	return responsePoolResult(res)
+}
+
+// params[0] : pool id ("CODE/CODE")
+// params[1] : max amount of the first token of params[0] (big int string | decimal-formatted string)
+// params[2] : max amount of the second token of params[0] (big int string | decimal-formatted string)
+// params[3] : optional. min shares (slippage limit)
+func poolAdd(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 3 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 3+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	pb := NewPoolStub(stub)
+	pool, first, err := getPoolByID(stub, params[0])
+	if err != nil {
+		return responseError(err, "failed to get the pool")
+	}
+
+	tb := NewTokenStub(stub)
+	codes := []string{pool.TokenA, pool.TokenB}
+	if first != pool.TokenA { // the given order
+		codes = []string{pool.TokenB, pool.TokenA}
+	}
+	amounts := map[string]*Amount{}
+	for i, code := range codes {
+		amount, err := tb.ParseAmount(code, params[1+i])
+		if err != nil {
+			return responseError(err, "")
+		}
+		if amount.Sign() <= 0 {
+			return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+		}
+		amounts[code] = amount
+	}
+	maxA, maxB := amounts[pool.TokenA], amounts[pool.TokenB]
+
+	amountA, amountB, shares := pool.AddAmounts(maxA, maxB)
+	if shares.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "too small liquidity")
+	}
+	if len(params) > 3 && len(params[3]) > 0 {
+		minShares, err := NewAmount(params[3])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidAmount, "invalid min shares")
+		}
+		if shares.Cmp(minShares) < 0 {
+			return responseError(SlippageError{reason: "shares " + shares.String()}, "")
+		}
+	}
+
+	balA, balB, err := getPoolProviderBalances(stub, pool, kid)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if balA.Amount.Cmp(amountA) < 0 || balB.Amount.Cmp(amountB) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	share, err := pb.GetShare(pool.DOCTYPEID, kid)
+	if err != nil {
+		return responseError(err, "failed to get the pool share")
+	}
+
+	res, err := pb.AddLiquidity(pool, share, balA, balB, amountA, amountB, shares)
+	if err != nil {
+		return responseError(err, "failed to add the liquidity")
+	}
+
+	return responsePoolResult(res)
+}
+
+// params[0] : pool id ("CODE/CODE")
+// params[1] : shares
+// params[2] : optional. min amount of the first token of params[0] (slippage limit)
+// params[3] : optional. min amount of the second token of params[0] (slippage limit)
+func poolRemove(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	pb := NewPoolStub(stub)
+	pool, first, err := getPoolByID(stub, params[0])
+	if err != nil {
+		return responseError(err, "failed to get the pool")
+	}
+
+	shares, err := NewAmount(params[1])
+	if err != nil || shares.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid shares. must be greater than 0")
+	}
+	share, err := pb.GetShare(pool.DOCTYPEID, kid)
+	if err != nil {
+		return responseError(err, "failed to get the pool share")
+	}
+	if share.Shares.Cmp(shares) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough shares")
+	}
+
+	amountA, amountB := pool.RemoveAmounts(shares)
+	if amountA.Sign() == 0 && amountB.Sign() == 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "too small shares")
+	}
+
+	// slippage
+	tb := NewTokenStub(stub)
+	codes := []string{pool.TokenA, pool.TokenB}
+	if first != pool.TokenA {
+		codes = []string{pool.TokenB, pool.TokenA}
+	}
+	for i, code := range codes {
+		if len(params) > 2+i && len(params[2+i]) > 0 {
+			min, err := tb.ParseAmount(code, params[2+i])
+			if err != nil {
+				return responseError(err, "")
+			}
+			amount := amountA
+			if code == pool.TokenB {
+				amount = amountB
+			}
+			if amount.Cmp(min) < 0 {
+				return responseError(SlippageError{reason: code + " amount " + amount.String()}, "")
+			}
+		}
+	}
+
+	balA, balB, err := getPoolProviderBalances(stub, pool, kid)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	res, err := pb.RemoveLiquidity(pool, share, balA, balB, amountA, amountB, shares)
+	if err != nil {
+		return responseError(err, "failed to remove the liquidity")
+	}
+
+	return responsePoolResult(res)
+}
+
+// params[0] : pool id ("CODE/CODE")
+// params[1] : input token code
+// params[2] : input amount (big int string | decimal-formatted string)
+// params[3] : optional. min output amount (slippage limit, big int string | decimal-formatted string)
+func poolSwap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 3 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 3+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	pool, _, err := getPoolByID(stub, params[0])
+	if err != nil {
+		return responseError(err, "failed to get the pool")
+	}
+
+	codeIn, err := ValidateTokenCode(params[1])
+	if err != nil {
+		return responseError(err, "")
+	}
+	if !pool.Has(codeIn) {
+		return responseErrorCode(ErrorCodeInvalidParameter, "the pool doesn't have the token: "+codeIn)
+	}
+	codeOut := pool.TokenB
+	if codeIn == pool.TokenB {
+		codeOut = pool.TokenA
+	}
+
+	tb := NewTokenStub(stub)
+	amountIn, err := tb.ParseAmount(codeIn, params[2])
+	if err != nil {
+		return responseError(err, "")
+	}
+	if amountIn.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount. must be greater than 0")
+	}
+
+	amountOut, fee := pool.SwapAmount(codeIn, amountIn)
+	if amountOut.Sign() <= 0 {
+		return responseErrorCode(ErrorCodeInvalidAmount, "too small amount")
+	}
+	if len(params) > 3 && len(params[3]) > 0 {
+		min, err := tb.ParseAmount(codeOut, params[3])
+		if err != nil {
+			return responseError(err, "")
+		}
+		if amountOut.Cmp(min) < 0 {
+			return responseError(SlippageError{reason: "output amount " + amountOut.String()}, "")
+		}
+	}
+
+	balA, balB, err := getPoolProviderBalances(stub, pool, kid)
+	if err != nil {
+		return responseError(err, "")
+	}
+	balIn, balOut := balA, balB
+	if codeIn == pool.TokenB {
+		balIn, balOut = balB, balA
+	}
+	if balIn.Amount.Cmp(amountIn) < 0 {
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "not enough balance")
+	}
+
+	res, err := NewPoolStub(stub).Swap(pool, balIn, balOut.DOCTYPEID, codeIn, amountIn, amountOut, fee)
+	if err != nil {
+		return responseError(err, "failed to swap")
+	}
+
+	return responsePoolResult(res)
+}
+
+// params[0] : pool id ("CODE/CODE")
+func poolGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	pool, _, err := getPoolByID(stub, params[0])
+	if err != nil {
+		return responseError(err, "failed to get the pool")
+	}
+
+	state := &PoolState{Pool: pool}
+	state.PriceA, state.PriceB = pool.Prices()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return responseError(err, "failed to marshal the pool")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : pool id ("CODE/CODE")
+func poolShareGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	pool, _, err := getPoolByID(stub, params[0])
+	if err != nil {
+		return responseError(err, "failed to get the pool")
+	}
+
+	share, err := NewPoolStub(stub).GetShare(pool.DOCTYPEID, kid)
+	if err != nil {
+		return responseError(err, "failed to get the pool share")
+	}
+
+	data, err := json.Marshal(share)
+	if err != nil {
+		return responseError(err, "failed to marshal the pool share")
+	}
+
+	return shim.Success(data)
+}
+
+// helpers
+
+// getPoolByID returns the pool and the first token code of the given pool ID.
+func getPoolByID(stub shim.ChaincodeStubInterface, id string) (*Pool, string, error) {
+	id, first, _, err := ParsePoolID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	pool, err := NewPoolStub(stub).GetPool(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return pool, first, nil
+}
+
+// getPoolProviderBalances returns the balances of the PAOTs of the pool tokens.
+func getPoolProviderBalances(stub shim.ChaincodeStubInterface, pool *Pool, kid string) (*Balance, *Balance, error) {
+	bals := make([]*Balance, 2)
+	for i, code := range []string{pool.TokenA, pool.TokenB} {
+		addr := NewAddress(code, AccountTypePersonal, kid)
+		account, err := NewAccountStub(stub, code).GetAccount(addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if account.IsSuspended() {
+			return nil, nil, SuspendedAccountError{}
+		}
+		if bals[i], err = NewBalanceStub(stub).GetBalance(account.GetID()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return bals[0], bals[1], nil
+}
+
+func responsePoolResult(res *PoolResult) peer.Response {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+	return shim.Success(data)
+}
@@ -296,3 +296,121 @@ const QueryFeesByCode = `{
 func CreateQueryFeesByCode(tokenCode string) string {
 	return fmt.Sprintf(QueryFeesByCode, tokenCode)
 }
+
+// QueryDexTradesByMarket _
+const QueryDexTradesByMarket = `{
+	"selector":{
+		"@dex_trade":"%s"
+	},
+	"sort":[{"@dex_trade":"desc"},{"created_time":"desc"}],
+	"use_index":["dex","trades"]
+}`
+
+// CreateQueryDexTradesByMarket _
+func CreateQueryDexTradesByMarket(market string) string {
+	return fmt.Sprintf(QueryDexTradesByMarket, market)
+}
+
+// QueryConversionLogsByID _
+const QueryConversionLogsByID = `{
+	"selector":{
+		"@conversion_log":"%s"
+	},
+	"sort":[{"@conversion_log":"desc"},{"created_time":"desc"}],
+	"use_index":["conversion","logs"]
+}`
+
+// CreateQueryConversionLogsByID _
+func CreateQueryConversionLogsByID(id string) string {
+	return fmt.Sprintf(QueryConversionLogsByID, id)
+}
+
+// QueryBalanceLogAtTime _
+const QueryBalanceLogAtTime = `{
+	"selector":{
+		"@balance_log":"%s",
+		"created_time":{"$lte":"%s"}
+	},
+	"sort":[{"@balance_log":"desc"},{"created_time":"desc"}],
+	"use_index":["balance","logs"],
+	"limit":1
+}`
+
+// CreateQueryBalanceLogAtTime _
+func CreateQueryBalanceLogAtTime(id string, t *txtime.Time) string {
+	return fmt.Sprintf(QueryBalanceLogAtTime, id, t.String())
+}
+
+// QuerySupplyLogsAfterTime _
+const QuerySupplyLogsAfterTime = `{
+	"selector":{
+		"@balance_log":"%s",
+		"type":{"$in":[0,1]},
+		"created_time":{"$gt":"%s"}
+	},
+	"sort":[{"@balance_log":"desc"},{"created_time":"desc"}],
+	"use_index":["balance","logs"]
+}`
+
+// CreateQuerySupplyLogsAfterTime _
+func CreateQuerySupplyLogsAfterTime(genesis string, t *txtime.Time) string {
+	return fmt.Sprintf(QuerySupplyLogsAfterTime, genesis, t.String())
+}
+
+// QuerySupplyHistoryAfterTime _
+const QuerySupplyHistoryAfterTime = `{
+	"selector":{
+		"@supply_log":"%s",
+		"created_time":{"$gt":"%s"}
+	},
+	"use_index":["token","supply_logs"]
+}`
+
+// CreateQuerySupplyHistoryAfterTime _
+func CreateQuerySupplyHistoryAfterTime(code string, t *txtime.Time) string {
+	return fmt.Sprintf(QuerySupplyHistoryAfterTime, code, t.String())
+}
+
+// QueryWrapsByToken _
+const QueryWrapsByToken = `{
+	"selector":{
+		"@wrap":{"$exists":true},
+		"token":"%s",
+		%s
+		"$and":[
+			{
+				"created_time":{
+					"$gte":"%s"
+				}
+			},
+			{
+				"created_time":{
+					"$lt":"%s"
+				}
+			}
+		]
+	},
+	"sort":[{"token":"desc"},{"created_time":"desc"}],
+	"use_index":["wrap","list"]
+}`
+
+// CreateQueryWrapsByToken _
+func CreateQueryWrapsByToken(token, extCode, address, status string, stime, etime *txtime.Time) string {
+	filters := ""
+	if extCode != "" {
+		filters += fmt.Sprintf(`"ext_code":"%s",`, extCode)
+	}
+	if address != "" {
+		filters += fmt.Sprintf(`"address":"%s",`, address)
+	}
+	if status != "" {
+		filters += fmt.Sprintf(`"status":%s,`, status)
+	}
+	if nil == stime {
+		stime = txtime.Unix(0, 0)
+	}
+	if nil == etime {
+		etime = txtime.Unix(253402300799, 999999999) // 9999-12-31 23:59:59.999999999
+	}
+	return fmt.Sprintf(QueryWrapsByToken, token, filters, stime.String(), etime.String())
+}
@@ -0,0 +1,19 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+// Quote is the dry-run result of transfer, pay or wrap
+type Quote struct {
+	Fn        string         `json:"fn"`
+	Sender    string         `json:"sender,omitempty"`
+	Receiver  string         `json:"receiver,omitempty"` // account address or external address(wrap)
+	ExtCode   string         `json:"ext_code,omitempty"` // wrap only
+	Amount    *Amount        `json:"amount,omitempty"`
+	Fee       *Amount        `json:"fee,omitempty"`        // pay: charged to the receiver when pruned (receiver bearer)
+	FeeBearer string         `json:"fee_bearer,omitempty"` // "sender" | "receiver" | "sponsor"
+	Total     *Amount        `json:"total,omitempty"`      // total debit of the sender
+	Balance   *Amount        `json:"balance,omitempty"`    // sender's balance after the tx
+	Contract  bool           `json:"contract"`             // true if a multi-sig contract would be created
+	Signers   []string       `json:"signers,omitempty"`    // KIDs of the contract signers
+	Error     *ErrorResponse `json:"error,omitempty"`
+}
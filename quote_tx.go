@@ -0,0 +1,125 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/key-inside/kiesnet-ccpkg/kid"
+)
+
+// Dry-run of transfer, pay and wrap. It runs the same validation without writing any state.
+// A validation failure is not a query failure, it is reported in the 'error' field of the quote.
+// params[0] : function name ("transfer" | "pay" | "wrap")
+// params[1:] : parameters of the function (positional, or a single JSON object of the named arguments)
+func quote(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	fn := params[0]
+	// named arguments of the function -> positional params
+	fnParams, err := ParseArgs(fn, params[1:])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	var q *Quote
+	switch fn {
+	case "transfer":
+		q = quoteTransfer(stub, kid, fnParams)
+	case "pay":
+		q = quotePay(stub, kid, fnParams)
+	case "wrap":
+		q = quoteWrap(stub, kid, fnParams)
+	default:
+		return responseErrorCode(ErrorCodeUnknownFunction, "unknown function: ["+fn+"]")
+	}
+
+	data, err := json.Marshal(q)
+	if err != nil {
+		return responseError(err, "failed to marshal the quote")
+	}
+	return shim.Success(data)
+}
+
+// helpers
+
+func quoteTransfer(stub shim.ChaincodeStubInterface, kid string, params []string) *Quote {
+	q := &Quote{Fn: "transfer"}
+	params, bearer, sponsor, err := getTransferFeeOptions(params)
+	if err != nil {
+		q.Error = NewErrorResponse(GetErrorCode(err), err.Error())
+		return q
+	}
+	tp, err := getValidatedTransferParameters(stub, kid, params, bearer, sponsor)
+	if err != nil {
+		q.Error = NewErrorResponse(GetErrorCode(err), err.Error())
+		return q
+	}
+	// applied = amount + fee (if the sender bears the fee)
+	q.Sender = tp.sender.GetID()
+	q.Receiver = tp.receiver.GetID()
+	q.Amount = tp.amount
+	q.Fee = tp.fee
+	q.FeeBearer = tp.bearer.String()
+	q.Total = tp.applied
+	q.Balance = tp.sBal.Amount.Copy().Add(tp.applied.Copy().Neg())
+	if tp.signers.Size() > 1 {
+		q.Contract = true
+		q.Signers = tp.signers.Strings()
+	}
+	return q
+}
+
+func quotePay(stub shim.ChaincodeStubInterface, kid string, params []string) *Quote {
+	q := &Quote{Fn: "pay"}
+	pp, err := getValidatedPayParameters(stub, kid, params)
+	if err != nil {
+		q.Error = NewErrorResponse(GetErrorCode(err), err.Error())
+		return q
+	}
+	// the fee of pay is charged to the receiver(merchant) by default
+	q.Sender = pp.sender.GetID()
+	q.Receiver = pp.receiver.GetID()
+	q.Amount = pp.amount
+	q.Fee = pp.fee
+	q.FeeBearer = pp.bearer.String()
+	q.Total = pp.applied
+	q.Balance = pp.sBal.Amount.Copy().Add(pp.applied.Copy().Neg())
+	if pp.signers.Size() > 1 {
+		q.Contract = true
+		q.Signers = pp.signers.Strings()
+	}
+	return q
+}
+
+func quoteWrap(stub shim.ChaincodeStubInterface, kid string, params []string) *Quote {
+	q := &Quote{Fn: "wrap"}
+	wp, err := getValidatedWrapParameters(stub, kid, params)
+	if err != nil {
+		q.Error = NewErrorResponse(GetErrorCode(err), err.Error())
+		return q
+	}
+	// the fee of wrap is decided by the bridge on wrap/complete
+	q.Sender = wp.sender.GetID()
+	q.Receiver = wp.extID
+	q.ExtCode = wp.extCode
+	q.Amount = wp.amount
+	q.Fee = ZeroAmount()
+	q.Total = wp.amount.Copy()
+	q.Balance = wp.sBal.Amount.Copy().Add(wp.amount.Copy().Neg())
+	if wp.signers.Size() > 1 {
+		q.Contract = true
+		q.Signers = wp.signers.Strings()
+	}
+	return q
+}
@@ -0,0 +1,118 @@
+// Copyright Key Inside Co., Ltd. 2019 All Rights Reserved.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+)
+
+// balanceSettlement accumulates the balance changes and the fees of the accounts in a transaction,
+// because each account can have only one balance log in a transaction. (the keys are based on the tx time)
+// The fees of the accounts are distinguished by the sequence. (see FeeStub.CreateFeeWithSeq)
+type balanceSettlement struct {
+	stub     shim.ChaincodeStubInterface
+	balances map[string]*Balance
+	diffs    map[string]*Amount
+	fees     map[string]*Amount
+	// options of the balance logs
+	memo       string
+	orderID    string
+	extCode    string
+	creditType *BalanceLogType // if it is set, the log type of the positive diff
+	feeType    *BalanceLogType // if it is set, the log type of the fee only (zero diff)
+}
+
+func newBalanceSettlement(stub shim.ChaincodeStubInterface) *balanceSettlement {
+	return &balanceSettlement{
+		stub:     stub,
+		balances: map[string]*Balance{},
+		diffs:    map[string]*Amount{},
+		fees:     map[string]*Amount{},
+	}
+}
+
+func (s *balanceSettlement) cache(bal *Balance) {
+	s.balances[bal.DOCTYPEID] = bal
+}
+
+// add accumulates the diff and the fee. (fee is nil-able)
+// Like the transfer log, the diff doesn't include the fee. (balance += diff - fee)
+func (s *balanceSettlement) add(addr string, diff *Amount, fee *Amount) {
+	if _, ok := s.diffs[addr]; !ok {
+		s.diffs[addr] = ZeroAmount()
+		s.fees[addr] = ZeroAmount()
+	}
+	s.diffs[addr].Add(diff)
+	if fee != nil {
+		s.fees[addr].Add(fee)
+	}
+}
+
+// apply puts the balances, the balance logs and the fees. (sorted by the address)
+func (s *balanceSettlement) apply(logType BalanceLogType, rid string, ts *txtime.Time) ([]*BalanceLog, error) {
+	addrs := make([]string, 0, len(s.diffs))
+	for addr := range s.diffs {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	bb := NewBalanceStub(s.stub)
+	fb := NewFeeStub(s.stub)
+	logs := []*BalanceLog{}
+	feeSeq := 0
+	for _, addr := range addrs {
+		diff, fee := s.diffs[addr], s.fees[addr]
+		if diff.Sign() == 0 && fee.Sign() == 0 {
+			continue
+		}
+		bal, ok := s.balances[addr]
+		if !ok {
+			var err error
+			if bal, err = bb.GetBalance(addr); err != nil {
+				return nil, err
+			}
+		}
+		bal.Amount.Add(diff)
+		bal.Amount.Add(fee.Copy().Neg())
+		if bal.Amount.Sign() < 0 { // never here
+			return nil, NotEnoughBalanceError{}
+		}
+		bal.UpdatedTime = ts
+		if err := bb.PutBalance(bal); err != nil {
+			return nil, err
+		}
+		log := &BalanceLog{
+			DOCTYPEID:   addr,
+			Type:        logType,
+			RID:         rid,
+			Diff:        *diff,
+			Amount:      bal.Amount,
+			Memo:        s.memo,
+			OrderID:     s.orderID,
+			ExtCode:     s.extCode,
+			CreatedTime: ts,
+		}
+		if s.creditType != nil && diff.Sign() > 0 {
+			log.Type = *s.creditType
+		} else if s.feeType != nil && diff.Sign() == 0 {
+			log.Type = *s.feeType
+		}
+		if fee.Sign() > 0 {
+			log.Fee = fee
+		}
+		if err := bb.PutBalanceLog(log); err != nil {
+			return nil, err
+		}
+		if fee.Sign() > 0 {
+			if _, err := fb.CreateFeeWithSeq(addr, *fee, feeSeq); err != nil {
+				return nil, err
+			}
+			feeSeq++
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
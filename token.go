@@ -5,19 +5,22 @@ package main
 import (
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/key-inside/kiesnet-ccpkg/contract"
 	"github.com/key-inside/kiesnet-ccpkg/txtime"
-	"github.com/pkg/errors"
 )
 
+// PruneWindowDefault is the default safety window (seconds) of pay/prune and fee/prune.
+const PruneWindowDefault = 600
+
 var _validateTokenCode = regexp.MustCompile(`^[A-Z0-9]{3,6}$`).MatchString
 
 // ValidateTokenCode validates a code and returns an uppercased code
 func ValidateTokenCode(code string) (string, error) {
 	code = strings.ToUpper(code)
 	if !_validateTokenCode(code) {
-		return "", errors.New("token code must be 3~6 length alphanum")
+		return "", InvalidTokenCodeError{}
 	}
 	return code, nil
 }
@@ -30,23 +33,52 @@ type Token struct {
 	Supply          Amount                 `json:"supply"`
 	LastPrunedFeeID string                 `json:"last_pruned_fee_id,omitempty"`
 	GenesisAccount  string                 `json:"genesis_account"`
-	FeePolicy       *FeePolicy             `json:"fee_policy,omitempty"`  // FeePolicy is nil if and only if knt fee is never yet imported. Once knt is initiated/upgraded with fee, it wil always exists.
-	WrapBridge      map[string]*WrapPolicy `json:"wrap_bridge,omitempty"` // map of extCode and wrap policy(wrap address, ext chain, fee)
+	FeePolicy       *FeePolicy             `json:"fee_policy,omitempty"`   // FeePolicy is nil if and only if knt fee is never yet imported. Once knt is initiated/upgraded with fee, it wil always exists.
+	WrapBridge      map[string]*WrapPolicy `json:"wrap_bridge,omitempty"`  // map of extCode and wrap policy(wrap address, ext chain, fee)
+	PruneWindow     int64                  `json:"prune_window,omitempty"` // seconds. safety window of pay/prune and fee/prune, 0 (not set) is PruneWindowDefault
 	CreatedTime     *txtime.Time           `json:"created_time,omitempty"`
 	UpdatedTime     *txtime.Time           `json:"updated_time,omitempty"`
 }
 
 func (t *Token) getWrapPolicy(extCode string) (*WrapPolicy, error) {
 	if t.WrapBridge == nil {
-		return nil, errors.New("wrap_bridge is not installed")
+		return nil, InvalidParameterError{reason: "wrap_bridge is not installed"}
 	}
 	policy, ok := t.WrapBridge[strings.ToUpper(extCode)]
 	if !ok {
-		return nil, errors.New("wrap_bridge has no policy of given ext_code")
+		return nil, InvalidParameterError{reason: "wrap_bridge has no policy of given ext_code"}
 	}
 	return policy, nil
 }
 
+// GetPruneSafeTime returns the end time limit of the safe prune. (ts - prune window)
+// The window prevents missing pays(fees) because of the time differences(+/- 5min) on different servers/devices.
+func (t *Token) GetPruneSafeTime(ts *txtime.Time) *txtime.Time {
+	window := t.PruneWindow
+	if window < 1 {
+		window = PruneWindowDefault
+	}
+	return txtime.New(ts.Add(-time.Duration(window) * time.Second))
+}
+
+// GetWrapExpiry returns the wrap expiry (seconds) of given extCode. 0 is no expiry.
+func (t *Token) GetWrapExpiry(extCode string) (int64, error) {
+	policy, err := t.getWrapPolicy(extCode)
+	if err != nil {
+		return 0, err
+	}
+	return policy.Expiry, nil
+}
+
+// GetExtChain returns the validator of the external chain of given extCode.
+func (t *Token) GetExtChain(extCode string) (ExtChain, error) {
+	policy, err := t.getWrapPolicy(extCode)
+	if err != nil {
+		return nil, err
+	}
+	return GetExtChain(policy.ExtChain), nil
+}
+
 // GetWrapAddress returns wrap address of given extCode.
 func (t *Token) GetWrapAddress(extCode string) (*Address, error) {
 	policy, err := t.getWrapPolicy(extCode)
@@ -56,6 +88,32 @@ func (t *Token) GetWrapAddress(extCode string) (*Address, error) {
 	return ParseAddress(policy.WrapAddress)
 }
 
+// ParseAmount parses the amount string of the token.
+// If it is decimal-formatted (see IsDecimalAmount), it is converted with the token's decimal.
+func (t *Token) ParseAmount(val string) (*Amount, error) {
+	var amount *Amount
+	var err error
+	if IsDecimalAmount(val) {
+		amount, err = NewAmountWithDecimal(val, t.Decimal)
+	} else {
+		amount, err = NewAmount(val)
+	}
+	if err != nil {
+		return nil, InvalidAmountError{reason: err.Error()}
+	}
+	return amount, nil
+}
+
+// SupplyLog records the supply change which is not logged on the genesis account. (burn mode conversion)
+// The mint/burn logs of the genesis account and the supply logs are the supply history of the token.
+type SupplyLog struct {
+	DOCTYPEID   string       `json:"@supply_log"` // token code
+	RID         string       `json:"rid"`         // conversion id
+	Diff        Amount       `json:"diff"`
+	Supply      Amount       `json:"supply"`
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
+}
+
 // TokenResult is response payload of token/burn and token/mint.
 type TokenResult struct {
 	Token      *Token             `json:"token,omitempty"`
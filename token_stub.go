@@ -4,6 +4,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/key-inside/kiesnet-ccpkg/stringset"
@@ -27,7 +28,7 @@ func (tb *TokenStub) CreateKey(code string) string {
 }
 
 // CreateToken _
-func (tb *TokenStub) CreateToken(code string, decimal int, maxSupply, supply Amount, feePolicy *FeePolicy, wrapBridge map[string]*WrapPolicy, holders *stringset.Set) (*Token, error) {
+func (tb *TokenStub) CreateToken(code string, decimal int, maxSupply, supply Amount, feePolicy *FeePolicy, wrapBridge map[string]*WrapPolicy, pruneWindow int64, holders *stringset.Set) (*Token, error) {
 	// create genesis account (joint account)
 	ab := NewAccountStub(tb.stub, code)
 	account, balance, err := ab.CreateJointAccount(holders)
@@ -43,6 +44,14 @@ func (tb *TokenStub) CreateToken(code string, decimal int, maxSupply, supply Amo
 		} else {
 			feePolicy.TargetAddress = account.GetID()
 		}
+		for _, b := range feePolicy.Beneficiaries {
+			if bc, _ := ParseCode(b.Address); bc != code {
+				return nil, InvalidParameterError{reason: "fee beneficiary must be an account of the token"}
+			}
+			if _, err := ab.GetAccountState(b.Address); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// wrap
@@ -74,6 +83,7 @@ func (tb *TokenStub) CreateToken(code string, decimal int, maxSupply, supply Amo
 		GenesisAccount: account.GetID(),
 		FeePolicy:      feePolicy,
 		WrapBridge:     wrapBridge,
+		PruneWindow:    pruneWindow,
 		CreatedTime:    ts,
 		UpdatedTime:    ts,
 	}
@@ -98,6 +108,23 @@ func (tb *TokenStub) GetToken(code string) (*Token, error) {
 	return token, nil
 }
 
+// ParseAmount parses the amount string of the token. (see Token.ParseAmount)
+// The token state is read only if the amount is decimal-formatted.
+func (tb *TokenStub) ParseAmount(code, val string) (*Amount, error) {
+	if !IsDecimalAmount(val) {
+		amount, err := NewAmount(val)
+		if err != nil {
+			return nil, InvalidAmountError{reason: err.Error()}
+		}
+		return amount, nil
+	}
+	token, err := tb.GetToken(code)
+	if err != nil {
+		return nil, err
+	}
+	return token.ParseAmount(val)
+}
+
 // GetTokenState _
 func (tb *TokenStub) GetTokenState(code string) ([]byte, error) {
 	data, err := tb.stub.GetState(tb.CreateKey(code))
@@ -122,6 +149,60 @@ func (tb *TokenStub) PutToken(token *Token) error {
 	return nil
 }
 
+// CreateSupplyLogKey _
+func (tb *TokenStub) CreateSupplyLogKey(code string, seq int64) string {
+	return fmt.Sprintf("SLOG_%s_%d", code, seq)
+}
+
+// PutSupplyLog _
+func (tb *TokenStub) PutSupplyLog(log *SupplyLog) error {
+	data, err := json.Marshal(log)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the supply log")
+	}
+	if err = tb.stub.PutState(tb.CreateSupplyLogKey(log.DOCTYPEID, log.CreatedTime.UnixNano()), data); err != nil {
+		return errors.Wrap(err, "failed to put the supply log state")
+	}
+	return nil
+}
+
+// GetSupplyAt returns the supply of the token at the time.
+// (current supply - mint/burn diffs of the genesis account and supply log diffs after the time)
+func (tb *TokenStub) GetSupplyAt(token *Token, t *txtime.Time) (*Amount, error) {
+	supply := token.Supply.Copy()
+	if err := tb.subDiffsAfterTime(supply, CreateQuerySupplyLogsAfterTime(token.GenesisAccount, t)); err != nil {
+		return nil, err
+	}
+	if err := tb.subDiffsAfterTime(supply, CreateQuerySupplyHistoryAfterTime(token.DOCTYPEID, t)); err != nil {
+		return nil, err
+	}
+	return supply, nil
+}
+
+// subDiffsAfterTime subtracts the diffs of the queried logs from the supply.
+func (tb *TokenStub) subDiffsAfterTime(supply *Amount, query string) error {
+	iter, err := tb.stub.GetQueryResult(query)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		log := &struct {
+			Diff Amount `json:"diff"`
+		}{}
+		if err = json.Unmarshal(kv.Value, log); err != nil {
+			return errors.Wrap(err, "failed to unmarshal the supply diff")
+		}
+		supply.Add(log.Diff.Neg())
+	}
+	return nil
+}
+
 // Burn _
 func (tb *TokenStub) Burn(token *Token, bal *Balance, amount Amount) (*Token, *BalanceLog, error) {
 	ts, err := txtime.GetTime(tb.stub)
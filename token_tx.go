@@ -18,15 +18,15 @@ import (
 )
 
 // params[0] : token code
-// params[1] : amount (big int string)
+// params[1] : amount (big int string | decimal-formatted string)
 func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 2 {
-		return shim.Error("incorrect number of parameters. expecting 2")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// token
@@ -39,7 +39,7 @@ func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// genesis account
@@ -50,7 +50,7 @@ func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the genesis account")
 	}
 	if !account.HasHolder(kid) { // authority
-		return shim.Error("no authority")
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
 	}
 
 	// balance
@@ -60,12 +60,12 @@ func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the genesis account balance")
 	}
 	if bal.Amount.Sign() == 0 {
-		return shim.Error("genesis account balance is 0")
+		return responseErrorCode(ErrorCodeNotEnoughBalance, "genesis account balance is 0")
 	}
 
-	_amount, err := NewAmount(params[1]) // validate amount
+	_amount, err := token.ParseAmount(params[1]) // validate amount
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 	// get burnable amount
 	burnable, err := invokeKNT(stub, code, []string{"burn", token.Supply.String(), bal.Amount.String(), _amount.String()})
@@ -74,11 +74,11 @@ func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response
 	}
 	amount, err := NewAmount(string(burnable))
 	if err != nil || amount.Sign() < 0 {
-		return shim.Error("not burnable")
+		return responseErrorCode(ErrorCodeInvalidAmount, "not burnable")
 	}
 
 	if token.Supply.Cmp(amount) < 0 {
-		return shim.Error("amount must be less or equal than total supply")
+		return responseErrorCode(ErrorCodeInvalidAmount, "amount must be less or equal than total supply")
 	}
 
 	jac := account.(*JointAccount)
@@ -87,13 +87,12 @@ func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		doc := []interface{}{"token/burn", code, amount.String()}
 		docb, err := json.Marshal(doc)
 		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to create a contract")
+			return responseError(err, "failed to create a contract")
 		}
 		// ISSUE : should we get and set expiry?
 		con, err := contract.CreateContract(stub, docb, 0, jac.Holders)
 		if err != nil {
-			return shim.Error(err.Error())
+			return responseError(err, "")
 		}
 		payload := &TokenResult{Contract: con}
 		data, err := json.Marshal(payload)
@@ -121,12 +120,12 @@ func tokenBurn(stub shim.ChaincodeStubInterface, params []string) peer.Response
 // params[1:] : co-holders (personal account addresses)
 func tokenCreate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	tb := NewTokenStub(stub)
@@ -135,18 +134,18 @@ func tokenCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 			return responseError(err, "failed to get the token state")
 		}
 	} else {
-		return shim.Error("already issued token : [" + code + "]")
+		return responseErrorCode(ErrorCodeAlreadyIssuedToken, "already issued token : ["+code+"]")
 	}
 
-	decimal, maxSupply, supply, feePolicy, wrap, err := getValidatedTokenMeta(stub, code)
+	decimal, maxSupply, supply, feePolicy, wrap, pruneWindow, err := getValidatedTokenMeta(stub, code)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// co-holders
@@ -170,7 +169,7 @@ func tokenCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 		return invokeContract(stub, doc, holders)
 	}
 
-	token, err := tb.CreateToken(code, decimal, *maxSupply, *supply, feePolicy, wrap, holders)
+	token, err := tb.CreateToken(code, decimal, *maxSupply, *supply, feePolicy, wrap, pruneWindow, holders)
 	if err != nil {
 		return responseError(err, "failed to create the token")
 	}
@@ -182,20 +181,70 @@ func tokenCreate(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 	return shim.Success(data)
 }
 
+// Audit the supply invariant page by page. (supply = balances + pending balances + unpruned pays + unpruned fees)
+// Call it with the cursor of the previous page until it is done.
+// params[0] : token code
+// params[1] : optional. cursor of the previous page (empty = first page)
+// params[2] : optional. fetch size (if < 1 => default size 200, max 1000)
+func tokenAudit(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// authentication
+	if _, err = kid.GetID(stub, false); err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	cursor := NewTokenAuditCursor()
+	fetchSize := 0
+	if len(params) > 1 && len(params[1]) > 0 {
+		if cursor, err = ParseTokenAuditCursor(params[1]); err != nil {
+			return responseError(err, "")
+		}
+	}
+	if len(params) > 2 && len(params[2]) > 0 {
+		if fetchSize, err = strconv.Atoi(params[2]); err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+		}
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if err != nil {
+		return responseError(err, "failed to get the token")
+	}
+
+	res, err := NewAuditStub(stub).Audit(token, cursor, fetchSize)
+	if err != nil {
+		return responseError(err, "failed to audit the token")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the audit")
+	}
+	return shim.Success(data)
+}
+
 // params[0] : token code
 func tokenGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// authentication
 	if _, err = kid.GetID(stub, false); err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	tb := NewTokenStub(stub)
@@ -207,15 +256,15 @@ func tokenGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 }
 
 // params[0] : token code
-// params[1] : amount (big int string)
+// params[1] : amount (big int string | decimal-formatted string)
 func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 2 {
-		return shim.Error("incorrect number of parameters. expecting 2")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// token
@@ -225,13 +274,13 @@ func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the token")
 	}
 	if token.Supply.Cmp(&token.MaxSupply) >= 0 {
-		return shim.Error("max supplied")
+		return responseErrorCode(ErrorCodeSupply, "max supplied")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// genesis account
@@ -242,7 +291,7 @@ func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the genesis account")
 	}
 	if !account.HasHolder(kid) { // authority
-		return shim.Error("no authority")
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
 	}
 
 	// balance
@@ -252,9 +301,9 @@ func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		return responseError(err, "failed to get the genesis account balance")
 	}
 
-	_amount, err := NewAmount(params[1]) // validate amount
+	_amount, err := token.ParseAmount(params[1]) // validate amount
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 	// get mintable amount
 	mintable, err := invokeKNT(stub, code, []string{"mint", token.Supply.String(), bal.Amount.String(), _amount.String()})
@@ -263,7 +312,7 @@ func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response
 	}
 	amount, err := NewAmount(string(mintable))
 	if err != nil || amount.Sign() < 0 {
-		return shim.Error("not mintable")
+		return responseErrorCode(ErrorCodeInvalidAmount, "not mintable")
 	}
 
 	jac := account.(*JointAccount)
@@ -273,13 +322,12 @@ func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response
 		// return invokeContract(stub, doc, jac.Holders)
 		docb, err := json.Marshal(doc)
 		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to create a contract")
+			return responseError(err, "failed to create a contract")
 		}
 		// ISSUE : should we get and set expiry?
 		con, err := contract.CreateContract(stub, docb, 0, jac.Holders)
 		if err != nil {
-			return shim.Error(err.Error())
+			return responseError(err, "")
 		}
 		payload := &TokenResult{Contract: con}
 		data, err := json.Marshal(payload)
@@ -307,19 +355,19 @@ func tokenMint(stub shim.ChaincodeStubInterface, params []string) peer.Response
 // params[0] : token code
 func tokenUpdate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) != 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
 	}
 
 	code, err := ValidateTokenCode(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// authentication
 	// ISSUE: only genesis account holders ?
 	_, err = kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
 	// check issued token
@@ -330,9 +378,9 @@ func tokenUpdate(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 	}
 
 	// get token meta
-	_, _, _, policy, wrapBridge, err := getValidatedTokenMeta(stub, code)
+	_, _, _, policy, wrapBridge, pruneWindow, err := getValidatedTokenMeta(stub, code)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	// Update token state.
@@ -363,6 +411,20 @@ func tokenUpdate(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 				policy.TargetAddress = token.FeePolicy.TargetAddress
 			}
 		}
+		if policy.Beneficiaries == nil { // No beneficiaries input. Do not edit current value.
+			if token.FeePolicy != nil {
+				policy.Beneficiaries = token.FeePolicy.Beneficiaries
+			}
+		} else {
+			for _, b := range policy.Beneficiaries {
+				if bc, _ := ParseCode(b.Address); bc != code {
+					return responseError(InvalidParameterError{reason: "fee beneficiary must be an account of the token"}, "")
+				}
+				if _, err := NewAccountStub(stub, code).GetAccountState(b.Address); err != nil {
+					return responseError(err, "failed to set a fee beneficiary")
+				}
+			}
+		}
 		token.FeePolicy = policy
 		update = true
 	}
@@ -378,6 +440,12 @@ func tokenUpdate(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 		update = true
 	}
 
+	// prune window
+	if token.PruneWindow != pruneWindow {
+		token.PruneWindow = pruneWindow
+		update = true
+	}
+
 	if update {
 		ts, err := txtime.GetTime(stub)
 		if err != nil {
@@ -418,41 +486,46 @@ func invokeKNT(stub shim.ChaincodeStubInterface, code string, params []string) (
 	return nil, errors.New(res.GetMessage())
 }
 
-func getValidatedTokenMeta(stub shim.ChaincodeStubInterface, code string) (int, *Amount, *Amount, *FeePolicy, map[string]*WrapPolicy, error) {
+func getValidatedTokenMeta(stub shim.ChaincodeStubInterface, code string) (int, *Amount, *Amount, *FeePolicy, map[string]*WrapPolicy, int64, error) {
 
 	// get token meta
 	meta, err := invokeKNT(stub, code, []string{"token"})
 	if err != nil {
-		return 0, nil, nil, nil, nil, errors.Wrap(err, "failed to get the token meta")
+		return 0, nil, nil, nil, nil, 0, errors.Wrap(err, "failed to get the token meta")
 	}
 	metaMap := make(map[string]interface{})
 	if err = json.Unmarshal(meta, &metaMap); err != nil {
-		return 0, nil, nil, nil, nil, errors.Wrap(err, "failed to unmarshal the token meta")
+		return 0, nil, nil, nil, nil, 0, errors.Wrap(err, "failed to unmarshal the token meta")
 	}
 
 	// validate meta
 	decimal, err := strconv.Atoi(metaMap["decimal"].(string))
 	if err != nil || decimal < 0 || decimal > 18 {
-		return 0, nil, nil, nil, nil, errors.New("decimal must be integer between 0 and 18")
+		return 0, nil, nil, nil, nil, 0, errors.New("decimal must be integer between 0 and 18")
 	}
 	maxSupply, err := NewAmount(metaMap["max_supply"].(string))
 	if err != nil || maxSupply.Sign() < 0 {
-		return 0, nil, nil, nil, nil, errors.New("max supply must be positive integer")
+		return 0, nil, nil, nil, nil, 0, errors.New("max supply must be positive integer")
 	}
 	supply, err := NewAmount(metaMap["initial_supply"].(string))
 	if err != nil || supply.Sign() < 0 || supply.Cmp(maxSupply) > 0 {
-		return 0, nil, nil, nil, nil, errors.New("initial supply must be positive integer and less(or equal) than max supply")
+		return 0, nil, nil, nil, nil, 0, errors.New("initial supply must be positive integer and less(or equal) than max supply")
 	}
 	fee := metaMap["fee"].(string)
 	var policy *FeePolicy
 	if len(fee) > 0 {
 		policy, err = ParseFeePolicy(fee)
 		if err != nil {
-			return 0, nil, nil, nil, nil, err
+			return 0, nil, nil, nil, nil, 0, err
 		}
 		if metaMap["target_address"] != nil {
 			policy.TargetAddress = metaMap["target_address"].(string)
 		}
+		if b, ok := metaMap["fee_beneficiaries"].(string); ok { // nil beneficiaries if it is not exists
+			if policy.Beneficiaries, err = ParseFeeBeneficiaries(b); err != nil {
+				return 0, nil, nil, nil, nil, 0, err
+			}
+		}
 	}
 
 	//wrapBridge
@@ -460,16 +533,25 @@ func getValidatedTokenMeta(stub shim.ChaincodeStubInterface, code string) (int,
 	if metaMap["wrap_bridge"] != nil {
 		wb, ok := metaMap["wrap_bridge"].(map[string]interface{})
 		if !ok {
-			return 0, nil, nil, nil, nil, errors.New("cannot type casting wrap_bridge to map[string]interface")
+			return 0, nil, nil, nil, nil, 0, errors.New("cannot type casting wrap_bridge to map[string]interface")
 		}
 
 		wrapBridge, err = NewWrapBridge(wb)
 		if err != nil {
-			return 0, nil, nil, nil, nil, err
+			return 0, nil, nil, nil, nil, 0, err
+		}
+	}
+
+	// prune window (optional, seconds. empty is the default)
+	var pruneWindow int64
+	if w, ok := metaMap["prune_window"].(string); ok && len(w) > 0 {
+		pruneWindow, err = strconv.ParseInt(w, 10, 64)
+		if err != nil || pruneWindow < 1 {
+			return 0, nil, nil, nil, nil, 0, errors.New("prune window must be positive integer (seconds) or empty (default)")
 		}
 	}
 
-	return decimal, maxSupply, supply, policy, wrapBridge, nil
+	return decimal, maxSupply, supply, policy, wrapBridge, pruneWindow, nil
 }
 
 // contract callbacks
@@ -477,13 +559,13 @@ func getValidatedTokenMeta(stub shim.ChaincodeStubInterface, code string) (int,
 // doc: ["token/burn", code, amount]
 func executeTokenBurn(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) != 3 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	code := doc[1].(string)
 	amount, err := NewAmount(doc[2].(string))
 	if err != nil {
-		return shim.Error("invalid amount")
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount")
 	}
 
 	// token
@@ -516,7 +598,7 @@ func executeTokenBurn(stub shim.ChaincodeStubInterface, cid string, doc []interf
 // doc: ["token/create", code, [co-holders...]]
 func executeTokenCreate(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) != 3 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	code := doc[1].(string)
@@ -527,12 +609,12 @@ func executeTokenCreate(stub shim.ChaincodeStubInterface, cid string, doc []inte
 			return responseError(err, "failed to get the token state")
 		}
 	} else {
-		return shim.Error("already issued token : [" + code + "]")
+		return responseErrorCode(ErrorCodeAlreadyIssuedToken, "already issued token : ["+code+"]")
 	}
 
-	decimal, maxSupply, supply, feePolicy, wrap, err := getValidatedTokenMeta(stub, code)
+	decimal, maxSupply, supply, feePolicy, wrap, pruneWindow, err := getValidatedTokenMeta(stub, code)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
 
 	kids := doc[2].([]interface{})
@@ -541,7 +623,7 @@ func executeTokenCreate(stub shim.ChaincodeStubInterface, cid string, doc []inte
 		holders.Add(kid.(string))
 	}
 
-	if _, err = tb.CreateToken(code, decimal, *maxSupply, *supply, feePolicy, wrap, holders); err != nil {
+	if _, err = tb.CreateToken(code, decimal, *maxSupply, *supply, feePolicy, wrap, pruneWindow, holders); err != nil {
 		return responseError(err, "failed to create the token")
 	}
 
@@ -551,13 +633,13 @@ func executeTokenCreate(stub shim.ChaincodeStubInterface, cid string, doc []inte
 // doc: ["token/mint", code, amount]
 func executeTokenMint(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) != 3 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	code := doc[1].(string)
 	amount, err := NewAmount(doc[2].(string))
 	if err != nil {
-		return shim.Error("invalid amount")
+		return responseErrorCode(ErrorCodeInvalidAmount, "invalid amount")
 	}
 
 	// token
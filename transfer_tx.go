@@ -4,6 +4,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -12,51 +13,303 @@ import (
 	"github.com/key-inside/kiesnet-ccpkg/kid"
 	"github.com/key-inside/kiesnet-ccpkg/stringset"
 	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
 )
 
 // params[0] : sender address (empty string = personal account)
 // params[1] : receiver address
-// params[2] : amount (big int string)
+// params[2] : amount (big int string | decimal-formatted string)
 // params[3] : memo (see MemoMaxLength)
 // params[4] : order id
 // params[5] : pending time (time represented by int64 seconds)
 // params[6] : expiry (duration represented by int64 seconds, multi-sig only)
-// params[7:] : extra signers (personal account addresses)
+// params[7] : fee bearer ("sender" | "receiver" | "sponsor", empty is the default of the fee policy)
+// params[8] : sponsor address (fee bearer "sponsor" only)
+// params[9:] : extra signers (personal account addresses)
+// The legacy params have the extra signers from params[7]. (see getTransferFeeOptions)
 func transfer(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	if len(params) < 3 {
-		return shim.Error("incorrect number of parameters. expecting 3+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 3+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
-	// amount
-	amount, err := NewAmount(params[2])
+	params, bearer, sponsor, err := getTransferFeeOptions(params)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "")
 	}
-	if amount.Sign() <= 0 {
-		return shim.Error("invalid amount. must be greater than 0")
+	tp, err := getValidatedTransferParameters(stub, kid, params, bearer, sponsor)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	var log *BalanceLog // log for response
+
+	bb := NewBalanceStub(stub)
+	if tp.signers.Size() > 1 { // multi-sig
+		// pending balance id
+		pbID := stub.GetTxID()
+		// contract
+		ptStr := "0"
+		if tp.pendingTime != nil {
+			ptStr = strconv.FormatInt(tp.pendingTime.Unix(), 10)
+		}
+		doc := []string{"transfer", pbID, tp.sender.GetID(), tp.receiver.GetID(), tp.amount.String(), tp.fee.String(), tp.memo, tp.orderID, ptStr}
+		docb, err := json.Marshal(doc)
+		if err != nil {
+			return responseError(err, "failed to create a contract")
+		}
+		con, err := contract.CreateContract(stub, docb, tp.expiry, tp.signers)
+		if err != nil {
+			return responseError(err, "")
+		}
+		// pending balance
+		log, err = bb.Deposit(pbID, tp.sBal, con, *tp.amount, tp.fee, tp.memo, tp.orderID)
+		if err != nil {
+			return responseError(err, "failed to create the pending balance")
+		}
+	} else { // instant sending
+		log, err = bb.Transfer(tp.sBal, tp.rBal, tp.spBal, *tp.amount, *tp.fee, tp.bearer, tp.memo, tp.orderID, tp.pendingTime)
+		if err != nil {
+			return responseError(err, "failed to transfer")
+		}
+	}
+
+	// log is not nil
+	data, err := json.Marshal(log)
+	if err != nil {
+		return responseError(err, "failed to marshal the log")
+	}
+
+	return shim.Success(data)
+}
+
+// TransferMultiMaxLegs is the max number of the legs of transfer/multi.
+const TransferMultiMaxLegs = 20
+
+// TransferLeg is a leg of transfer/multi.
+type TransferLeg struct {
+	Sender    string `json:"sender"` // empty string = personal account
+	Receiver  string `json:"receiver"`
+	Amount    string `json:"amount"`               // big int string | decimal-formatted string
+	FeeBearer string `json:"fee_bearer,omitempty"` // "sender" | "receiver" | "sponsor", empty is the default of the fee policy
+	Sponsor   string `json:"sponsor,omitempty"`    // sponsor address (fee_bearer "sponsor" only)
+}
+
+// TransferMultiResult is the response payload of transfer/multi.
+type TransferMultiResult struct {
+	TransferID  string        `json:"transfer_id"` // tx id, the RID of the balance logs
+	OrderID     string        `json:"order_id,omitempty"`
+	BalanceLogs []*BalanceLog `json:"balance_logs"`
+}
+
+// All legs are committed in one transaction, or nothing is committed.
+// Each account has one balance log of the net change. (send or receive)
+// Multi-sig senders(joint accounts) and pending time are not supported.
+// params[0] : legs (JSON array of TransferLeg, max 20)
+// params[1] : optional. order id (shared by the balance logs)
+// params[2] : optional. memo (see MemoMaxLength)
+func transferMulti(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	legs := []*TransferLeg{}
+	if err = json.Unmarshal([]byte(params[0]), &legs); err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid legs: expecting JSON array of {sender, receiver, amount}")
+	}
+	if len(legs) < 1 || len(legs) > TransferMultiMaxLegs {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid number of legs. expecting 1~20")
+	}
+
+	s := newBalanceSettlement(stub)
+	if len(params) > 1 {
+		s.orderID = params[1]
+		if len(params) > 2 {
+			s.memo = params[2]
+			if len(s.memo) > MemoMaxLength { // length limit
+				s.memo = s.memo[:MemoMaxLength]
+			}
+		}
+	}
+	creditType := BalanceLogTypeReceive
+	s.creditType = &creditType
+	feeType := BalanceLogTypeFeeSponsor
+	s.feeType = &feeType
+
+	// validate every leg like transfer
+	applied := map[string]*Amount{} // sender(or sponsor) => amount + fee of all legs
+	debit := func(bal *Balance, amount *Amount) bool {
+		id := bal.GetID()
+		if _, ok := applied[id]; !ok {
+			applied[id] = ZeroAmount()
+			s.cache(bal)
+		}
+		applied[id].Add(amount)
+		return bal.Amount.Cmp(applied[id]) >= 0
+	}
+	for i, leg := range legs {
+		bearer, err := ParseFeeBearer(leg.FeeBearer)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, fmt.Sprintf("invalid leg[%d]: %s", i, err.Error()))
+		}
+		tp, err := getValidatedTransferParameters(stub, kid, []string{leg.Sender, leg.Receiver, leg.Amount}, bearer, leg.Sponsor)
+		if err != nil {
+			return responseError(err, fmt.Sprintf("invalid leg[%d]", i))
+		}
+		if tp.signers.Size() > 1 {
+			return responseErrorCode(ErrorCodeInvalidParameter, fmt.Sprintf("invalid leg[%d]: multi-sig sender is not supported", i))
+		}
+		sID, rID := tp.sender.GetID(), tp.receiver.GetID()
+		switch tp.bearer {
+		case FeeBearerReceiver:
+			s.add(sID, tp.amount.Copy().Neg(), nil)
+			s.add(rID, tp.amount, tp.fee)
+		case FeeBearerSponsor:
+			if !debit(tp.spBal, tp.fee) {
+				return responseErrorCode(ErrorCodeNotEnoughBalance, fmt.Sprintf("invalid leg[%d]: not enough balance of the sponsor", i))
+			}
+			s.add(sID, tp.amount.Copy().Neg(), nil)
+			s.add(rID, tp.amount, nil)
+			s.add(tp.spBal.GetID(), ZeroAmount(), tp.fee)
+		default:
+			s.add(sID, tp.amount.Copy().Neg(), tp.fee)
+			s.add(rID, tp.amount, nil)
+		}
+		if !debit(tp.sBal, tp.applied) {
+			return responseErrorCode(ErrorCodeNotEnoughBalance, fmt.Sprintf("invalid leg[%d]: not enough balance", i))
+		}
+	}
+	// the balances of the receivers are loaded in apply, if they are not senders.
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+	res := &TransferMultiResult{TransferID: stub.GetTxID(), OrderID: s.orderID}
+	if res.BalanceLogs, err = s.apply(BalanceLogTypeSend, res.TransferID, ts); err != nil {
+		return responseError(err, "failed to transfer")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : order id (vendor specific)
+func transferGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if nil != err {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	orderID := params[0]
+
+	bb := NewBalanceStub(stub)
+	var bl *BalanceLog
+	if "" == orderID {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid parameter")
+	}
+	// get by order id
+	typeStr := "2"
+	bl, err = bb.GetQueryBalaceLogByOrderID(orderID, typeStr)
+	if nil != err {
+		return responseError(err, "failed to get transfer")
+	}
+
+	// balance log is not nil
+	data, err := json.Marshal(bl)
+	if err != nil {
+		return responseError(err, "failed to marshal the log")
+	}
+
+	return shim.Success(data)
+}
+
+// helpers
+
+// getTransferFeeOptions takes the fee bearer(params[7]) and the sponsor(params[8]) out of the transfer params,
+// and returns the params of getValidatedTransferParameters. (extra signers from params[7])
+// If params[7] is not empty and not a fee bearer, the params are legacy. (extra signers from params[7], no fee options)
+func getTransferFeeOptions(params []string) ([]string, FeeBearer, string, error) {
+	if len(params) < 8 {
+		return params, FeeBearerDefault, "", nil
+	}
+	bearer, err := ParseFeeBearer(params[7])
+	if err != nil { // legacy: an extra signer
+		if _, aerr := ParseAddress(params[7]); aerr != nil {
+			return nil, FeeBearerDefault, "", InvalidParameterError{reason: "invalid fee bearer (or extra signer)"}
+		}
+		return params, FeeBearerDefault, "", nil
+	}
+	sponsor := ""
+	if len(params) > 8 {
+		sponsor = params[8]
+	}
+	rest := append([]string{}, params[:7]...)
+	if len(params) > 9 {
+		rest = append(rest, params[9:]...)
+	}
+	return rest, bearer, sponsor, nil
+}
+
+// transferParameters is the validated parameters of transfer. (see getValidatedTransferParameters)
+type transferParameters struct {
+	sender      AccountInterface
+	receiver    AccountInterface
+	sBal        *Balance
+	rBal        *Balance
+	amount      *Amount
+	fee         *Amount   // not nil
+	applied     *Amount   // debit of the sender (amount + fee if the sender bears the fee)
+	bearer      FeeBearer // resolved bearer (not default)
+	spBal       *Balance  // sponsor's balance (FeeBearerSponsor only)
+	memo        string
+	orderID     string
+	pendingTime *txtime.Time
+	expiry      int64
+	signers     *stringset.Set
+}
+
+// getValidatedTransferParameters validates the transfer parameters without writing any state.
+// It is shared by transfer, transfer/multi and quote. The bearer and the sponsor are the fee options. (FeeBearerDefault and empty are the default)
+// The pending time and multi-sig transfers are always borne by the sender.
+func getValidatedTransferParameters(stub shim.ChaincodeStubInterface, kid string, params []string, bearer FeeBearer, sponsor string) (*transferParameters, error) {
+	if len(params) < 3 {
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 3+"}
 	}
 
 	// addresses
 	rAddr, err := ParseAddress(params[1])
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to parse the receiver's account address")
+		return nil, errors.Wrap(err, "failed to parse the receiver's account address")
 	}
 	var sAddr *Address
 	if len(params[0]) > 0 {
 		sAddr, err = ParseAddress(params[0])
 		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to parse the sender's account address")
+			return nil, errors.Wrap(err, "failed to parse the sender's account address")
 		}
 		if rAddr.Code != sAddr.Code { // not same token
-			return shim.Error("different token accounts")
+			return nil, InvalidParameterError{reason: "different token accounts"}
 		}
 	} else {
 		sAddr = NewAddress(rAddr.Code, AccountTypePersonal, kid)
@@ -64,7 +317,16 @@ func transfer(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 
 	// IMPORTANT: assert(sender != receiver)
 	if sAddr.Equal(rAddr) {
-		return shim.Error("can't transfer to self")
+		return nil, InvalidParameterError{reason: "can't transfer to self"}
+	}
+
+	// amount (big int string | decimal-formatted string)
+	amount, err := NewTokenStub(stub).ParseAmount(rAddr.Code, params[2])
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() <= 0 {
+		return nil, InvalidAmountError{reason: "invalid amount. must be greater than 0"}
 	}
 
 	ab := NewAccountStub(stub, rAddr.Code)
@@ -72,92 +334,78 @@ func transfer(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	// sender
 	sender, err := ab.GetAccount(sAddr)
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the sender account")
+		return nil, errors.Wrap(err, "failed to get the sender account")
 	}
 	if !sender.HasHolder(kid) {
-		return shim.Error("invoker is not holder")
+		return nil, NotHolderError{}
 	}
 	if sender.IsSuspended() {
-		return shim.Error("the sender account is suspended")
+		return nil, SuspendedAccountError{role: "sender"}
 	}
 
 	// receiver
 	receiver, err := ab.GetAccount(rAddr)
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the receiver account")
+		return nil, errors.Wrap(err, "failed to get the receiver account")
 	}
 	if receiver.IsSuspended() {
-		return shim.Error("the receiver account is suspended")
+		return nil, SuspendedAccountError{role: "receiver"}
 	}
 
 	// sender balance
 	bb := NewBalanceStub(stub)
 	sBal, err := bb.GetBalance(sender.GetID())
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the sender's balance")
-	}
-
-	fb := NewFeeStub(stub)
-	fee, err := fb.CalcFee(sAddr, "transfer", *amount)
-	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the fee amount")
-	}
-
-	// fee is not nil
-	applied := amount.Copy().Add(fee)
-
-	if sBal.Amount.Cmp(applied) < 0 {
-		return shim.Error("not enough balance")
+		return nil, errors.Wrap(err, "failed to get the sender's balance")
 	}
 
 	// receiver balance
 	rBal, err := bb.GetBalance(receiver.GetID())
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the receiver's balance")
+		return nil, errors.Wrap(err, "failed to get the receiver's balance")
 	}
 
-	// options
-	memo := ""
-	orderID := ""
-	var pendingTime *txtime.Time
-	var expiry int64
-	signers := stringset.New(kid)
+	tp := &transferParameters{
+		sender:   sender,
+		receiver: receiver,
+		sBal:     sBal,
+		rBal:     rBal,
+		amount:   amount,
+		signers:  stringset.New(kid),
+	}
 	if a, ok := sender.(*JointAccount); ok {
-		signers.AppendSet(a.Holders)
+		tp.signers.AppendSet(a.Holders)
 	}
+
+	// options
 	// memo
 	if len(params) > 3 {
 		if len(params[3]) > MemoMaxLength { // length limit
-			memo = params[3][:MemoMaxLength]
+			tp.memo = params[3][:MemoMaxLength]
 		} else {
-			memo = params[3]
+			tp.memo = params[3]
 		}
 		// order id
 		if len(params) > 4 {
-			orderID = params[4]
+			tp.orderID = params[4]
 			// pending time
 			if len(params) > 5 {
 				seconds, err := strconv.ParseInt(params[5], 10, 64)
 				if err != nil {
-					return shim.Error("invalid pending time: need seconds since 1970")
+					return nil, InvalidParameterError{reason: "invalid pending time: need seconds since 1970"}
 				}
 				ts, err := stub.GetTxTimestamp()
 				if err != nil {
-					return shim.Error("failed to get the timestamp")
+					return nil, errors.Wrap(err, "failed to get the timestamp")
 				}
 				if ts.GetSeconds() < seconds { // meaning pending time
-					pendingTime = txtime.Unix(seconds, 0)
+					tp.pendingTime = txtime.Unix(seconds, 0)
 				}
 				// expiry
 				if len(params) > 6 && len(params[6]) > 0 {
-					expiry, err = strconv.ParseInt(params[6], 10, 64)
+					tp.expiry, err = strconv.ParseInt(params[6], 10, 64)
 					if err != nil {
-						return shim.Error("invalid expiry: need seconds")
+						return nil, InvalidParameterError{reason: "invalid expiry: need seconds"}
 					}
 					// extra signers
 					if len(params) > 7 {
@@ -165,9 +413,9 @@ func transfer(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 						for addr := range addrs.Map() {
 							kids, err := ab.GetSignableIDs(addr)
 							if err != nil {
-								return shim.Error(err.Error())
+								return nil, err
 							}
-							signers.AppendSlice(kids)
+							tp.signers.AppendSlice(kids)
 						}
 					}
 				}
@@ -175,87 +423,44 @@ func transfer(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 		}
 	}
 
-	var log *BalanceLog // log for response
-
-	if signers.Size() > 1 { // multi-sig
-		if signers.Size() > 128 {
-			return shim.Error("too many signers")
-		}
-		// pending balance id
-		pbID := stub.GetTxID()
-		// contract
-		ptStr := "0"
-		if pendingTime != nil {
-			ptStr = params[4]
-		}
-		doc := []string{"transfer", pbID, sender.GetID(), receiver.GetID(), amount.String(), fee.String(), memo, orderID, ptStr}
-		docb, err := json.Marshal(doc)
-		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to create a contract")
-		}
-		con, err := contract.CreateContract(stub, docb, expiry, signers)
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		// pending balance
-		log, err = bb.Deposit(pbID, sBal, con, *amount, fee, memo, orderID)
-		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to create the pending balance")
-		}
-	} else { // instant sending
-		log, err = bb.Transfer(sBal, rBal, *amount, *fee, memo, orderID, pendingTime)
-		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to transfer")
-		}
+	if tp.signers.Size() > 128 {
+		return nil, TooManySignersError{}
 	}
 
-	// log is not nil
-	data, err := json.Marshal(log)
-	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to marshal the log")
+	// fee
+	if tp.pendingTime != nil || tp.signers.Size() > 1 {
+		bearer = FeeBearerSender
 	}
-
-	return shim.Success(data)
-}
-
-// params[0] : order id (vendor specific)
-func transferGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
-	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1")
-	}
-
-	// authentication
-	_, err := kid.GetID(stub, false)
-	if nil != err {
-		return shim.Error(err.Error())
+	var spAddr *Address
+	if bearer == FeeBearerSponsor {
+		if spAddr, tp.spBal, err = getValidatedFeeSponsor(stub, sponsor, sAddr, rAddr); err != nil {
+			return nil, err
+		}
+	} else if len(sponsor) > 0 {
+		return nil, InvalidParameterError{reason: "sponsor is only for the sponsor fee bearer"}
 	}
-
-	orderID := params[0]
-
-	bb := NewBalanceStub(stub)
-	var bl *BalanceLog
-	if "" == orderID {
-		return shim.Error("invalid parameter")
+	if tp.fee, tp.bearer, err = NewFeeStub(stub).CalcBearerFee(sAddr, rAddr, spAddr, "transfer", *amount, bearer); err != nil {
+		return nil, errors.Wrap(err, "failed to get the fee amount")
 	}
-	// get by order id
-	typeStr := "2"
-	bl, err = bb.GetQueryBalaceLogByOrderID(orderID, typeStr)
-	if nil != err {
-		return responseError(err, "failed to get transfer")
+	// fee is not nil
+	tp.applied = amount.Copy()
+	switch tp.bearer {
+	case FeeBearerReceiver:
+		if amount.Cmp(tp.fee) < 0 {
+			return nil, InvalidAmountError{reason: "invalid amount. must be greater than or equal to the fee"}
+		}
+	case FeeBearerSponsor:
+		if tp.spBal.Amount.Cmp(tp.fee) < 0 {
+			return nil, NotEnoughBalanceError{}
+		}
+	default:
+		tp.applied.Add(tp.fee)
 	}
-
-	// balance log is not nil
-	data, err := json.Marshal(bl)
-	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to marshal the log")
+	if sBal.Amount.Cmp(tp.applied) < 0 {
+		return nil, NotEnoughBalanceError{}
 	}
 
-	return shim.Success(data)
+	return tp, nil
 }
 
 // contract callbacks
@@ -263,27 +468,25 @@ func transferGet(stub shim.ChaincodeStubInterface, params []string) peer.Respons
 // doc: ["transfer", pending-balance-ID, sender-ID, receiver-ID, amount, fee, memo, order-ID, pending-time]
 func cancelTransfer(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 2 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	// pending balance
 	bb := NewBalanceStub(stub)
 	pb, err := bb.GetPendingBalance(doc[1].(string))
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the pending balance")
+		return responseError(err, "failed to get the pending balance")
 	}
 	// validate
 	if pb.Type != PendingBalanceTypeContract || pb.RID != cid {
-		return shim.Error("invalid pending balance")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid pending balance")
 	}
 
 	// ISSUE: check account ?
 
 	// withdraw
 	if _, err = bb.Withdraw(pb); err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to withdraw")
+		return responseError(err, "failed to withdraw")
 	}
 
 	return shim.Success(nil)
@@ -292,19 +495,18 @@ func cancelTransfer(stub shim.ChaincodeStubInterface, cid string, doc []interfac
 // doc: ["transfer", pending-balance-ID, sender-ID, receiver-ID, amount, fee, memo, order-ID, pending-time]
 func executeTransfer(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 9 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	// pending balance
 	bb := NewBalanceStub(stub)
 	pb, err := bb.GetPendingBalance(doc[1].(string))
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the pending balance")
+		return responseError(err, "failed to get the pending balance")
 	}
 	// validate
 	if pb.Type != PendingBalanceTypeContract || pb.RID != cid {
-		return shim.Error("invalid pending balance")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid pending balance")
 	}
 
 	// ISSUE: check accounts ? (suspended)
@@ -312,15 +514,13 @@ func executeTransfer(stub shim.ChaincodeStubInterface, cid string, doc []interfa
 	// sender balance : using response
 	sBal, err := bb.GetBalance(doc[2].(string))
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the sender's balance")
+		return responseError(err, "failed to get the sender's balance")
 	}
 
 	// receiver balance
 	rBal, err := bb.GetBalance(doc[3].(string))
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the receiver's balance")
+		return responseError(err, "failed to get the receiver's balance")
 	}
 
 	// pending time
@@ -329,15 +529,14 @@ func executeTransfer(stub shim.ChaincodeStubInterface, cid string, doc []interfa
 	if ptStr != "" && ptStr != "0" {
 		seconds, err := strconv.ParseInt(ptStr, 10, 64)
 		if err != nil {
-			return shim.Error("invalid pending time")
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid pending time")
 		}
 		pendingTime = txtime.Unix(seconds, 0)
 	}
 
 	// transfer
 	if err := bb.TransferPendingBalance(pb, sBal, rBal, pendingTime); err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to transfer a pending balance")
+		return responseError(err, "failed to transfer a pending balance")
 	}
 
 	log := struct {
@@ -361,8 +560,7 @@ func executeTransfer(stub shim.ChaincodeStubInterface, cid string, doc []interfa
 	// log is not nil
 	data, err := json.Marshal(&log) // pass log by reference (diff marshal issue)
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to marshal the log")
+		return responseError(err, "failed to marshal the log")
 	}
 
 	return shim.Success(data)
@@ -1,61 +1,269 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/key-inside/kiesnet-ccpkg/stringset"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
 	"golang.org/x/crypto/sha3"
 )
 
 // WrapPolicy _
 type WrapPolicy struct {
-	WrapAddress string `json:"wrap_address"`
-	ExtChain    string `json:"ext_chain"`
+	WrapAddress      string   `json:"wrap_address"`
+	ExtChain         string   `json:"ext_chain"`
+	Expiry           int64    `json:"expiry,omitempty"`            // seconds. the sender can refund the wrap after it. 0 is no expiry.
+	Validators       []string `json:"validators,omitempty"`        // KIDs of the bridge validators which attest unwraps
+	Threshold        int      `json:"threshold,omitempty"`         // number of the attestations to unwrap. 0 is unwrap by the wrap account holder.
+	WrapLimitPerTx   *Amount  `json:"wrap_limit_per_tx,omitempty"` // nil is unlimited
+	WrapLimitDaily   *Amount  `json:"wrap_limit_daily,omitempty"`
+	UnwrapLimitPerTx *Amount  `json:"unwrap_limit_per_tx,omitempty"`
+	UnwrapLimitDaily *Amount  `json:"unwrap_limit_daily,omitempty"`
 }
 
-// NewWrapBridge _
+// NewWrapBridge parses the policies.
+// "wrap_address;ext_chain[;expiry[;threshold;validator_kid,...[;wrap_limit_per_tx;wrap_limit_daily;unwrap_limit_per_tx;unwrap_limit_daily]]]"
+// The optional values can be empty. (no expiry, no validators, unlimited)
 func NewWrapBridge(data map[string]interface{}) (map[string]*WrapPolicy, error) {
 	wb := make(map[string]*WrapPolicy)
 	for extCode, rawPolicy := range data {
 		values := strings.Split(rawPolicy.(string), ";")
-		if len(values) == 2 {
-			wb[strings.ToUpper(extCode)] = &WrapPolicy{
-				WrapAddress: values[0],
-				ExtChain:    values[1],
-			}
-		} else {
+		if len(values) < 2 || len(values) == 4 || (len(values) > 5 && len(values) != 9) {
 			return nil, errors.New("failed to parse wrap bridge")
 		}
+		policy := &WrapPolicy{
+			WrapAddress: values[0],
+			ExtChain:    values[1],
+		}
+		if len(values) > 2 && len(values[2]) > 0 {
+			expiry, err := strconv.ParseInt(values[2], 10, 64)
+			if err != nil || expiry < 0 {
+				return nil, errors.New("failed to parse wrap bridge expiry")
+			}
+			policy.Expiry = expiry
+		}
+		if len(values) > 4 && (len(values[3]) > 0 || len(values[4]) > 0) {
+			threshold, err := strconv.Atoi(values[3])
+			if err != nil {
+				return nil, errors.New("failed to parse wrap bridge threshold")
+			}
+			validators := stringset.New()
+			for _, v := range strings.Split(values[4], ",") {
+				if v = strings.TrimSpace(v); len(v) > 0 {
+					validators.Add(v)
+				}
+			}
+			if threshold < 1 || threshold > validators.Size() {
+				return nil, errors.New("invalid wrap bridge threshold")
+			}
+			policy.Threshold = threshold
+			policy.Validators = validators.Strings()
+			sort.Strings(policy.Validators)
+		}
+		if len(values) > 8 {
+			limits := []**Amount{&policy.WrapLimitPerTx, &policy.WrapLimitDaily, &policy.UnwrapLimitPerTx, &policy.UnwrapLimitDaily}
+			for i, limit := range limits {
+				if v := values[5+i]; len(v) > 0 {
+					amount, err := NewAmount(v)
+					if err != nil || amount.Sign() <= 0 {
+						return nil, errors.New("failed to parse wrap bridge limit")
+					}
+					*limit = amount
+				}
+			}
+		}
+		wb[strings.ToUpper(extCode)] = policy
 	}
 	return wb, nil
 }
 
+// CheckWrapLimit returns BridgeLimitError if the amount exceeds the wrap limits. (used is the wrapped amount of the day)
+func (p *WrapPolicy) CheckWrapLimit(amount, used *Amount) error {
+	return checkBridgeLimit("wrap", p.WrapLimitPerTx, p.WrapLimitDaily, amount, used)
+}
+
+// CheckUnwrapLimit returns BridgeLimitError if the amount exceeds the unwrap limits. (used is the unwrapped amount of the day)
+func (p *WrapPolicy) CheckUnwrapLimit(amount, used *Amount) error {
+	return checkBridgeLimit("unwrap", p.UnwrapLimitPerTx, p.UnwrapLimitDaily, amount, used)
+}
+
+func checkBridgeLimit(fn string, perTx, daily, amount, used *Amount) error {
+	if perTx != nil && amount.Cmp(perTx) > 0 {
+		return BridgeLimitError{reason: fn + " amount exceeds the limit per tx"}
+	}
+	if daily != nil && used != nil && used.Copy().Add(amount).Cmp(daily) > 0 {
+		return BridgeLimitError{reason: fn + " amount exceeds the daily limit"}
+	}
+	return nil
+}
+
+// IsValidator _
+func (p *WrapPolicy) IsValidator(kid string) bool {
+	for _, v := range p.Validators {
+		if v == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// WrapsFetchSize _
+const WrapsFetchSize = 20
+
+// WrapStatus _
+type WrapStatus int8
+
+const (
+	// WrapStatusRequested the amount is sent to the wrap account, waiting for the bridge
+	WrapStatusRequested WrapStatus = iota
+	// WrapStatusCompleted the bridge minted the external token
+	WrapStatusCompleted
+	// WrapStatusImpossible the bridge couldn't mint the external token
+	WrapStatusImpossible
+	// WrapStatusRefunded the amount is returned to the sender
+	WrapStatusRefunded
+)
+
+var wrapStatusNames = []string{"requested", "completed", "impossible", "refunded"}
+
+// ParseWrapStatus parses the status name (or the number).
+func ParseWrapStatus(s string) (WrapStatus, error) {
+	s = strings.ToLower(s)
+	for i, name := range wrapStatusNames {
+		if s == name || s == strconv.Itoa(i) {
+			return WrapStatus(i), nil
+		}
+	}
+	return 0, errors.New("invalid wrap status")
+}
+
 // Wrap _
 type Wrap struct {
-	DOCTYPEID    string `json:"@wrap"` // tx_id
-	Address      string `json:"address"`
-	Amount       Amount `json:"amount"`
-	ExtCode      string `json:"ext_code"`                 // external token code
-	ExtID        string `json:"ext_id"`                   // EOA
-	CompleteTxID string `json:"complete_tx_id,omitempty"` // tx hash (internal or external)
-	Memo         string `json:"memo"`
-	OrderID      string `json:"order_id,omitempty"` // order id. vendor specific unique identifier.
+	DOCTYPEID    string       `json:"@wrap"` // tx_id
+	Token        string       `json:"token,omitempty"`
+	Address      string       `json:"address"`
+	Amount       Amount       `json:"amount"`
+	ExtCode      string       `json:"ext_code"`                 // external token code
+	ExtID        string       `json:"ext_id"`                   // EOA
+	CompleteTxID string       `json:"complete_tx_id,omitempty"` // tx hash (internal or external)
+	Memo         string       `json:"memo"`
+	OrderID      string       `json:"order_id,omitempty"` // order id. vendor specific unique identifier.
+	Status       WrapStatus   `json:"status"`
+	Expiry       *txtime.Time `json:"expiry,omitempty"` // refundable after it, nil if the policy has no expiry
+	Ledger       bool         `json:"ledger,omitempty"` // added to the pending of the bridge ledger when it is requested
+	CreatedTime  *txtime.Time `json:"created_time,omitempty"`
+	UpdatedTime  *txtime.Time `json:"updated_time,omitempty"`
+}
+
+// WrapMigrateFetchSize is the default number of the wrap states that one wrap/migrate reads.
+const WrapMigrateFetchSize = 200
+
+// WrapMigrateResult is the response payload of wrap/migrate.
+type WrapMigrateResult struct {
+	Fetched  int    `json:"fetched"`
+	Migrated int    `json:"migrated"`
+	Next     string `json:"next,omitempty"` // wrap id to continue, empty if done
+}
+
+// normalize fills the token and the status of the legacy wrap.
+func (w *Wrap) normalize() {
+	if len(w.Token) == 0 {
+		w.Token, _ = ParseCode(w.Address)
+	}
+	if w.Status == WrapStatusRequested && len(w.CompleteTxID) > 0 {
+		if w.CompleteTxID == w.DOCTYPEID {
+			w.Status = WrapStatusImpossible
+		} else {
+			w.Status = WrapStatusCompleted
+		}
+	}
 }
 
 // Unwrap _
 type Unwrap struct {
-	DOCTYPEID    string `json:"@unwrap"`                  // external tx_id
-	CompleteTxID string `json:"complete_tx_id,omitempty"` // internal tx hash
+	DOCTYPEID    string       `json:"@unwrap"`                  // external tx_id
+	CompleteTxID string       `json:"complete_tx_id,omitempty"` // internal tx hash
+	Token        string       `json:"token,omitempty"`
+	Address      string       `json:"address,omitempty"` // receiver (the wrap account if it is impossible)
+	Amount       *Amount      `json:"amount,omitempty"`
+	ExtCode      string       `json:"ext_code,omitempty"`
+	ExtID        string       `json:"ext_id,omitempty"` // EOA
+	Impossible   bool         `json:"impossible,omitempty"`
+	CreatedTime  *txtime.Time `json:"created_time,omitempty"`
+}
+
+// UnwrapAttestationStatus _
+type UnwrapAttestationStatus int8
+
+const (
+	// UnwrapAttestationStatusPending the threshold is not met
+	UnwrapAttestationStatusPending UnwrapAttestationStatus = iota
+	// UnwrapAttestationStatusExecuted the unwrap is executed
+	UnwrapAttestationStatusExecuted
+)
+
+// UnwrapAttest is the attestation of a bridge validator.
+type UnwrapAttest struct {
+	Validator   string       `json:"validator"` // KID
+	Receiver    string       `json:"receiver"`  // receiver address (the wrap account if it is impossible)
+	ExtID       string       `json:"ext_id"`
+	Amount      Amount       `json:"amount"`
+	Digest      string       `json:"digest"` // see UnwrapAttestDigest
+	CreatedTime *txtime.Time `json:"created_time,omitempty"`
 }
 
-// NormalizeExtAddress _
-func NormalizeExtAddress(addr string) (string, error) {
-	err := validateEOA(addr)
-	if err != nil {
-		return "", err
+// UnwrapAttestDigest returns the hex encoded SHA-256 hash of the attested unwrap.
+func UnwrapAttestDigest(extTxID, receiver, extID string, amount *Amount) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{extTxID, receiver, extID, amount.String()}, "|")))
+	return hex.EncodeToString(h[:])
+}
+
+// UnwrapAttestation is the attestations of the bridge validators for the external tx.
+// The unwrap is executed when the attestations of the same digest meet the threshold of the wrap policy.
+type UnwrapAttestation struct {
+	DOCTYPEID    string                  `json:"@unwrap_attestation"` // external tx_id
+	Token        string                  `json:"token"`
+	ExtCode      string                  `json:"ext_code"`
+	Threshold    int                     `json:"threshold"` // threshold of the policy at the last attestation
+	Attests      []*UnwrapAttest         `json:"attests"`
+	Conflicted   bool                    `json:"conflicted"` // true if there are attestations of different digests
+	Status       UnwrapAttestationStatus `json:"status"`
+	CompleteTxID string                  `json:"complete_tx_id,omitempty"` // internal tx hash of the unwrap
+	CreatedTime  *txtime.Time            `json:"created_time,omitempty"`
+	UpdatedTime  *txtime.Time            `json:"updated_time,omitempty"`
+}
+
+// UnwrapAttestResult is the response payload of unwrap/attest.
+type UnwrapAttestResult struct {
+	Attestation *UnwrapAttestation `json:"attestation"`
+	BalanceLog  *BalanceLog        `json:"balance_log,omitempty"` // the receiver's log if the unwrap is executed
+}
+
+// GetAttest returns the attestation of the validator. If the validator didn't attest, it returns nil.
+func (ua *UnwrapAttestation) GetAttest(kid string) *UnwrapAttest {
+	for _, a := range ua.Attests {
+		if a.Validator == kid {
+			return a
+		}
 	}
-	return strings.ToLower(addr), nil
+	return nil
+}
+
+// Count returns the number of the attestations of the digest.
+func (ua *UnwrapAttestation) Count(digest string) int {
+	n := 0
+	for _, a := range ua.Attests {
+		if a.Digest == digest {
+			n++
+		}
+	}
+	return n
 }
 
 var isValidEOAFormat = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`).MatchString
@@ -95,16 +303,6 @@ func eip55Checksum(unprefix string) string {
 	return string(mixed)
 }
 
-// NormalizeExtTxID _
-func NormalizeExtTxID(txid string) (string, error) {
-	txid = strings.ToLower(txid)
-	err := validateExtTxID(txid)
-	if err != nil {
-		return "", err
-	}
-	return txid, nil
-}
-
 var isValidExtTxIDFormat = regexp.MustCompile(`^0x[0-9a-f]{64}$`).MatchString
 
 func validateExtTxID(txid string) error {
@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/key-inside/kiesnet-ccpkg/txtime"
@@ -29,6 +31,11 @@ func (wb *WrapStub) CreateUnwrapKey(extTxID string) string {
 	return fmt.Sprintf("UNWRAP_%s", extTxID)
 }
 
+// CreateUnwrapAttestationKey _
+func (wb *WrapStub) CreateUnwrapAttestationKey(extTxID string) string {
+	return fmt.Sprintf("UNWRAPA_%s", extTxID)
+}
+
 // GetWrap _
 func (wb *WrapStub) GetWrap(txid string) (*Wrap, error) {
 	data, err := wb.stub.GetState(wb.CreateWrapKey(txid))
@@ -36,15 +43,126 @@ func (wb *WrapStub) GetWrap(txid string) (*Wrap, error) {
 		return nil, err
 	}
 	if nil == data {
-		return nil, errors.New("wrap is not exist")
+		return nil, NotExistedWrapError{}
 	}
 	wrap := &Wrap{}
 	if err = json.Unmarshal(data, wrap); err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal the wrap")
 	}
+	wrap.normalize()
 	return wrap, nil
 }
 
+// GetQueryWraps _
+func (wb *WrapStub) GetQueryWraps(token, extCode, address, status, bookmark string, fetchSize int, stime, etime *txtime.Time) (*QueryResult, error) {
+	if fetchSize < 1 {
+		fetchSize = WrapsFetchSize
+	}
+	if fetchSize > 200 {
+		fetchSize = 200
+	}
+	query := CreateQueryWrapsByToken(token, extCode, address, status, stime, etime)
+	iter, meta, err := wb.stub.GetQueryResultWithPagination(query, int32(fetchSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	return NewQueryResult(meta, iter)
+}
+
+// GetUnwrap _
+func (wb *WrapStub) GetUnwrap(extTxID string) (*Unwrap, error) {
+	data, err := wb.stub.GetState(wb.CreateUnwrapKey(extTxID))
+	if err != nil {
+		return nil, err
+	}
+	if nil == data {
+		return nil, NotExistedUnwrapError{}
+	}
+	unwrap := &Unwrap{}
+	if err = json.Unmarshal(data, unwrap); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the unwrap")
+	}
+	return unwrap, nil
+}
+
+// Migrate puts the token, the status and the created/updated time of the legacy wraps of the token,
+// so that wrap/list and the wrap list index can find them.
+// It reads the wrap states from the start wrap id (max fetchSize) and skips the other tokens and the migrated wraps.
+// The created time is the first modification of the wrap state, so the history database of the peer must be enabled.
+func (wb *WrapStub) Migrate(code, start string, fetchSize int) (*WrapMigrateResult, error) {
+	if fetchSize < 1 || fetchSize > WrapMigrateFetchSize {
+		fetchSize = WrapMigrateFetchSize
+	}
+	prefix := wb.CreateWrapKey("")
+	iter, err := wb.stub.GetStateByRange(wb.CreateWrapKey(start), prefix+string(utf8.MaxRune))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the wrap states")
+	}
+	defer iter.Close()
+
+	res := &WrapMigrateResult{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if res.Fetched == fetchSize {
+			res.Next = kv.Key[len(prefix):]
+			break
+		}
+		res.Fetched++
+
+		wrap := &Wrap{}
+		if err = json.Unmarshal(kv.Value, wrap); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal the wrap")
+		}
+		if len(wrap.Token) > 0 { // not legacy
+			continue
+		}
+		if c, _ := ParseCode(wrap.Address); c != code {
+			continue
+		}
+		wrap.normalize()
+		if wrap.CreatedTime, wrap.UpdatedTime, err = wb.getWrapTimes(kv.Key); err != nil {
+			return nil, err
+		}
+		if err = wb.PutWrap(wrap); err != nil {
+			return nil, err
+		}
+		res.Migrated++
+	}
+	return res, nil
+}
+
+// getWrapTimes returns the first and the last modification time of the wrap state.
+func (wb *WrapStub) getWrapTimes(key string) (created, updated *txtime.Time, err error) {
+	iter, err := wb.stub.GetHistoryForKey(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get the wrap history")
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		km, err := iter.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		ts := txtime.Unix(km.GetTimestamp().GetSeconds(), int64(km.GetTimestamp().GetNanos()))
+		if created == nil || ts.Cmp(created) < 0 {
+			created = ts
+		}
+		if updated == nil || ts.Cmp(updated) > 0 {
+			updated = ts
+		}
+	}
+	if created == nil { // never here
+		return nil, nil, errors.New("no history of the wrap")
+	}
+	return created, updated, nil
+}
+
 // PutWrap _
 func (wb *WrapStub) PutWrap(wrap *Wrap) error {
 	data, err := json.Marshal(wrap)
@@ -69,6 +187,34 @@ func (wb *WrapStub) PutUnwrap(unwrap *Unwrap) error {
 	return nil
 }
 
+// GetUnwrapAttestation returns the attestations of the external tx. If there is no attestation, it returns nil.
+func (wb *WrapStub) GetUnwrapAttestation(extTxID string) (*UnwrapAttestation, error) {
+	data, err := wb.stub.GetState(wb.CreateUnwrapAttestationKey(extTxID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the unwrap attestation state")
+	}
+	if nil == data {
+		return nil, nil
+	}
+	ua := &UnwrapAttestation{}
+	if err = json.Unmarshal(data, ua); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the unwrap attestation")
+	}
+	return ua, nil
+}
+
+// PutUnwrapAttestation _
+func (wb *WrapStub) PutUnwrapAttestation(ua *UnwrapAttestation) error {
+	data, err := json.Marshal(ua)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the unwrap attestation")
+	}
+	if err = wb.stub.PutState(wb.CreateUnwrapAttestationKey(ua.DOCTYPEID), data); err != nil {
+		return errors.Wrap(err, "failed to put the unwrap attestation state")
+	}
+	return nil
+}
+
 // Wrap _
 func (wb *WrapStub) Wrap(sender *Balance, amount Amount, extCode, extID, memo, orderID string) (*BalanceLog, error) {
 	ts, err := txtime.GetTime(wb.stub)
@@ -77,13 +223,20 @@ func (wb *WrapStub) Wrap(sender *Balance, amount Amount, extCode, extID, memo, o
 	}
 
 	wrap := &Wrap{
-		DOCTYPEID: wb.stub.GetTxID(),
-		Address:   sender.GetID(),
-		Amount:    amount,
-		ExtCode:   extCode,
-		ExtID:     extID,
-		Memo:      memo,
-		OrderID:   orderID,
+		DOCTYPEID:   wb.stub.GetTxID(),
+		Address:     sender.GetID(),
+		Amount:      amount,
+		ExtCode:     extCode,
+		ExtID:       extID,
+		Memo:        memo,
+		OrderID:     orderID,
+		Status:      WrapStatusRequested,
+		CreatedTime: ts,
+		UpdatedTime: ts,
+	}
+	wrap.Token, _ = ParseCode(wrap.Address)
+	if err = wb.requestWrap(wrap, ts); err != nil {
+		return nil, err
 	}
 	if err = wb.PutWrap(wrap); err != nil {
 		return nil, err
@@ -112,6 +265,9 @@ func (wb *WrapStub) WrapComplete(wrap *Wrap, wBal *Balance, fee Amount, extTxID
 	if wrap.CompleteTxID != "" {
 		return nil, DuplicateWrapCompleteError{}
 	}
+	if wrap.Status == WrapStatusRefunded {
+		return nil, InvalidStateError{reason: "the wrap is refunded"}
+	}
 
 	ts, err := txtime.GetTime(wb.stub)
 	if err != nil {
@@ -121,16 +277,14 @@ func (wb *WrapStub) WrapComplete(wrap *Wrap, wBal *Balance, fee Amount, extTxID
 	diff := wrap.Amount.Copy()
 	diff.Add(fee.Copy().Neg())
 	if diff.Sign() <= 0 {
-		return nil, errors.New("wrap amount is less than or equal to fee")
+		return nil, InvalidAmountError{reason: "wrap amount is less than or equal to fee"}
 	}
 
 	// update wrap state
-	if extTxID != "" { // successful wrap
-		wrap.CompleteTxID = extTxID
-	} else { // impossible wrap
-		wrap.CompleteTxID = wrap.DOCTYPEID
+	if err = wb.completeWrap(wrap, extTxID, ts); err != nil {
+		return nil, err
 	}
-	if err = wb.PutWrap(wrap); err != nil {
+	if err = NewBridgeStub(wb.stub).ReleaseWrap(wrap, &fee, ts); err != nil {
 		return nil, err
 	}
 
@@ -156,27 +310,93 @@ func (wb *WrapStub) WrapComplete(wrap *Wrap, wBal *Balance, fee Amount, extTxID
 	return sbl, nil
 }
 
-// Unwrap _
-func (wb *WrapStub) Unwrap(wrapper, receiver *Balance, amount Amount, extCode, extID, extTxID string) (*BalanceLog, error) {
+// completeWrap puts the completed (or impossible if the external tx id is empty) wrap.
+func (wb *WrapStub) completeWrap(wrap *Wrap, extTxID string, ts *txtime.Time) error {
+	if extTxID != "" { // successful wrap
+		wrap.CompleteTxID = extTxID
+		wrap.Status = WrapStatusCompleted
+	} else { // impossible wrap
+		wrap.CompleteTxID = wrap.DOCTYPEID
+		wrap.Status = WrapStatusImpossible
+	}
+	wrap.UpdatedTime = ts
+	return wb.PutWrap(wrap)
+}
+
+// Refund returns the amount of the expired wrap to the sender.
+// The wrap account is not debited, because the amount is credited to it only when the wrap is completed.
+// It does not validate the expiry!
+func (wb *WrapStub) Refund(wrap *Wrap, sBal *Balance) (*BalanceLog, error) {
+	if wrap.Status != WrapStatusRequested {
+		return nil, InvalidStateError{reason: "the wrap is not requested"}
+	}
+
 	ts, err := txtime.GetTime(wb.stub)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get the timestamp")
 	}
 
-	bb := NewBalanceStub(wb.stub)
+	s := newBalanceSettlement(wb.stub)
+	s.memo = wrap.Memo
+	s.orderID = wrap.OrderID
+	s.cache(sBal)
+	s.add(sBal.DOCTYPEID, &wrap.Amount, nil)
+	logs, err := s.apply(BalanceLogTypeWrapRefund, wrap.DOCTYPEID, ts)
+	if err != nil {
+		return nil, err
+	}
 
-	receiver.Amount.Add(&amount)
-	receiver.UpdatedTime = ts
-	if err = bb.PutBalance(receiver); err != nil {
+	wrap.Status = WrapStatusRefunded
+	wrap.UpdatedTime = ts
+	if err = wb.PutWrap(wrap); err != nil {
+		return nil, err
+	}
+	if err = NewBridgeStub(wb.stub).ReleaseWrap(wrap, nil, ts); err != nil {
 		return nil, err
 	}
 
-	rbl := NewBalanceUnwrapLog(receiver, amount, extCode, extID, extTxID)
-	rbl.CreatedTime = ts
-	if err = bb.PutBalanceLog(rbl); err != nil {
+	for _, log := range logs {
+		if log.DOCTYPEID == sBal.DOCTYPEID {
+			return log, nil
+		}
+	}
+	return nil, errors.New("failed to refund the wrap") // never here
+}
+
+// requestWrap sets the expiry of the wrap by the wrap policy, and records the wrap in the bridge ledger.
+// It returns BridgeLimitError if the wrap exceeds the wrap limits of the policy.
+func (wb *WrapStub) requestWrap(wrap *Wrap, ts *txtime.Time) error {
+	token, err := NewTokenStub(wb.stub).GetToken(wrap.Token)
+	if err != nil {
+		return err
+	}
+	policy, err := token.getWrapPolicy(wrap.ExtCode)
+	if err != nil {
+		return err
+	}
+	if policy.Expiry > 0 {
+		wrap.Expiry = txtime.New(ts.Add(time.Duration(policy.Expiry) * time.Second))
+	}
+	if err = NewBridgeStub(wb.stub).RecordWrap(policy, wrap.Token, wrap.ExtCode, &wrap.Amount, ts); err != nil {
+		return err
+	}
+	wrap.Ledger = true
+	return nil
+}
+
+// Unwrap _
+func (wb *WrapStub) Unwrap(wrapper, receiver *Balance, amount Amount, extCode, extID, extTxID string) (*BalanceLog, error) {
+	ts, err := txtime.GetTime(wb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
+	rbl, err := wb.creditUnwrap(receiver, amount, extCode, extID, extTxID, ts)
+	if err != nil {
 		return nil, err
 	}
 
+	bb := NewBalanceStub(wb.stub)
 	amount.Neg()
 	wrapper.Amount.Add(&amount)
 	wrapper.UpdatedTime = ts
@@ -191,6 +411,22 @@ func (wb *WrapStub) Unwrap(wrapper, receiver *Balance, amount Amount, extCode, e
 	return rbl, nil
 }
 
+// creditUnwrap adds the unwrapped amount to the receiver, and puts the unwrap log of the receiver.
+func (wb *WrapStub) creditUnwrap(receiver *Balance, amount Amount, extCode, extID, extTxID string, ts *txtime.Time) (*BalanceLog, error) {
+	bb := NewBalanceStub(wb.stub)
+	receiver.Amount.Add(&amount)
+	receiver.UpdatedTime = ts
+	if err := bb.PutBalance(receiver); err != nil {
+		return nil, err
+	}
+	rbl := NewBalanceUnwrapLog(receiver, amount, extCode, extID, extTxID)
+	rbl.CreatedTime = ts
+	if err := bb.PutBalanceLog(rbl); err != nil {
+		return nil, err
+	}
+	return rbl, nil
+}
+
 // UnwrapImpossible _
 func (wb *WrapStub) UnwrapImpossible(wrapper *Balance, extCode, extID, extTxID string) (*BalanceLog, error) {
 	ts, err := txtime.GetTime(wb.stub)
@@ -209,14 +445,26 @@ func (wb *WrapStub) UnwrapImpossible(wrapper *Balance, extCode, extID, extTxID s
 
 // WrapPendingBalance wrap the sender's pending balance. (multi-sig contract)
 func (wb *WrapStub) WrapPendingBalance(pb *PendingBalance, sender *Balance, extCode, extID, memo, orderID string) (*Wrap, error) {
+	ts, err := txtime.GetTime(wb.stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+
 	wrap := &Wrap{
-		DOCTYPEID: wb.stub.GetTxID(),
-		Address:   sender.GetID(),
-		Amount:    *pb.Amount.Copy(),
-		ExtCode:   extCode,
-		ExtID:     extID,
-		Memo:      memo,
-		OrderID:   orderID,
+		DOCTYPEID:   wb.stub.GetTxID(),
+		Address:     sender.GetID(),
+		Amount:      *pb.Amount.Copy(),
+		ExtCode:     extCode,
+		ExtID:       extID,
+		Memo:        memo,
+		OrderID:     orderID,
+		Status:      WrapStatusRequested,
+		CreatedTime: ts,
+		UpdatedTime: ts,
+	}
+	wrap.Token, _ = ParseCode(wrap.Address)
+	if err = wb.requestWrap(wrap, ts); err != nil {
+		return nil, err
 	}
 	if err := wb.PutWrap(wrap); err != nil {
 		return nil, err
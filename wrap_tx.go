@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -10,12 +11,14 @@ import (
 	"github.com/key-inside/kiesnet-ccpkg/contract"
 	"github.com/key-inside/kiesnet-ccpkg/kid"
 	"github.com/key-inside/kiesnet-ccpkg/stringset"
+	"github.com/key-inside/kiesnet-ccpkg/txtime"
+	"github.com/pkg/errors"
 )
 
 // params[0] : sender address | token code
 // params[1] : external token code(wpci, ...)
 // params[2] : external address(EOA)
-// params[3] : amount (big int string) must bigger than 0
+// params[3] : amount (big int string | decimal-formatted string) must bigger than 0
 // params[4] : memo (see MemoMaxLength)
 // params[5] : order id
 // params[6] : expiry (duration represented by int64 seconds, multi-sig only)
@@ -23,33 +26,937 @@ import (
 func wrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	// param check
 	if len(params) < 4 {
-		return shim.Error("incorrect number of parameters. expecting 4+")
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 4+")
 	}
 
 	// authentication
 	kid, err := kid.GetID(stub, true)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
 	}
 
-	// external code
+	wp, err := getValidatedWrapParameters(stub, kid, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	var log *BalanceLog // log for response
+	if wp.signers.Size() > 1 {
+		// multisig
+		// pending balance id
+		pbID := stub.GetTxID()
+		doc := []string{"wrap", pbID, wp.sender.GetID(), wp.amount.String(), wp.extCode, wp.extID, wp.memo, wp.orderID}
+		docb, err := json.Marshal(doc)
+		if err != nil {
+			return responseError(err, "failed to create a contract")
+		}
+		con, err := contract.CreateContract(stub, docb, wp.expiry, wp.signers)
+		if err != nil {
+			return responseError(err, "")
+		}
+		// pending balance
+		log, err = NewBalanceStub(stub).Deposit(pbID, wp.sBal, con, *wp.amount, nil, wp.memo, wp.orderID)
+		if err != nil {
+			return responseError(err, "failed to create the pending balance")
+		}
+	} else {
+		wb := NewWrapStub(stub)
+		log, err = wb.Wrap(wp.sBal, *wp.amount, wp.extCode, wp.extID, wp.memo, wp.orderID)
+		if err != nil {
+			return responseError(err, "failed to wrap")
+		}
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return responseError(err, "failed to marshal the log")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : wrap key (wrap tx id)
+// params[1] : fee (big int string | decimal-formatted string) must bigger than or equal to 0
+// params[2] : external tx id (if it is nil, it is 'impossible wrap')
+func wrapComplete(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	// param check
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	cp, err := getValidatedWrapCompleteParameters(stub, kid, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	wBal, err := NewBalanceStub(stub).GetBalance(cp.wrapper.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the balance of the wrap account")
+	}
+
+	log, err := NewWrapStub(stub).WrapComplete(cp.wrap, wBal, *cp.fee, cp.extTxID)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return responseError(err, "failed to marshal the log")
+	}
+
+	return shim.Success(data)
+}
+
+// WrapBatchMaxItems is the max number of the items of wrap/complete/batch and unwrap/batch.
+const WrapBatchMaxItems = 50
+
+// WrapCompleteItem is an item of wrap/complete/batch.
+type WrapCompleteItem struct {
+	WrapID  string `json:"wrap_id"`
+	Fee     string `json:"fee"`       // big int string | decimal-formatted string, empty = 0
+	ExtTxID string `json:"ext_tx_id"` // empty = impossible wrap
+}
+
+// UnwrapItem is an item of unwrap/batch.
+type UnwrapItem struct {
+	Receiver string `json:"receiver"` // empty = impossible unwrap
+	ExtID    string `json:"ext_id"`
+	ExtTxID  string `json:"ext_tx_id"`
+	Amount   string `json:"amount"` // big int string | decimal-formatted string
+}
+
+// WrapBatchItemResult is the result of an item of the batch. The failed item is skipped and has the error.
+type WrapBatchItemResult struct {
+	ID    string         `json:"id"` // wrap id | (normalized) external tx id
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// WrapBatchResult is the response payload of wrap/complete/batch and unwrap/batch.
+type WrapBatchResult struct {
+	BatchID     string                 `json:"batch_id"` // tx id, the RID of the balance logs
+	Applied     int                    `json:"applied"`
+	Items       []*WrapBatchItemResult `json:"items"` // in the order of the items
+	BalanceLogs []*BalanceLog          `json:"balance_logs"`
+}
+
+// Complete the wraps in a transaction. Each wrap account has one balance update and one balance log of the net change.
+// The failed items (including the duplicate completions) are reported in the results and don't abort the others.
+// The internal errors (e.g. state read failures) abort the transaction.
+// params[0] : items (JSON array of WrapCompleteItem, max 50)
+func wrapCompleteBatch(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	items := []*WrapCompleteItem{}
+	if err = json.Unmarshal([]byte(params[0]), &items); err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid items: expecting JSON array of {wrap_id, fee, ext_tx_id}")
+	}
+	if len(items) < 1 || len(items) > WrapBatchMaxItems {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid number of items. expecting 1~50")
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	wb := NewWrapStub(stub)
+	rb := NewBridgeStub(stub)
+	s := newBalanceSettlement(stub)
+	deltas := map[string]*BridgeDelta{}
+	completed := stringset.New()
+	res := &WrapBatchResult{BatchID: stub.GetTxID(), Items: []*WrapBatchItemResult{}}
+	for _, item := range items {
+		r := &WrapBatchItemResult{ID: item.WrapID}
+		res.Items = append(res.Items, r)
+
+		fee := item.Fee
+		if len(fee) == 0 {
+			fee = "0"
+		}
+		itemParams := []string{item.WrapID, fee}
+		if len(item.ExtTxID) > 0 {
+			itemParams = append(itemParams, item.ExtTxID)
+		}
+		cp, err := getValidatedWrapCompleteParameters(stub, kid, itemParams)
+		if err == nil && completed.Contains(cp.wrap.DOCTYPEID) {
+			err = DuplicateWrapCompleteError{}
+		}
+		if err != nil {
+			if GetErrorCode(err) == ErrorCodeInternal { // not the fault of the item
+				return responseError(err, "failed to validate the wrap completion")
+			}
+			r.Error = NewErrorResponse(GetErrorCode(err), err.Error())
+			continue
+		}
+		completed.Add(cp.wrap.DOCTYPEID)
+
+		if err = wb.completeWrap(cp.wrap, cp.extTxID, ts); err != nil {
+			return responseError(err, "failed to complete the wrap")
+		}
+		lk := rb.CreateKey(cp.wrap.Token, cp.wrap.ExtCode)
+		delta, ok := deltas[lk]
+		if !ok {
+			delta = NewBridgeDelta(cp.wrap.Token, cp.wrap.ExtCode, res.BatchID, ts)
+			deltas[lk] = delta
+		}
+		delta.ReleaseWrap(cp.wrap, cp.fee)
+		s.add(cp.wrapper.GetID(), &cp.wrap.Amount, cp.fee)
+		res.Applied++
+	}
+
+	if res.BalanceLogs, err = s.apply(BalanceLogTypeWrapComplete, res.BatchID, ts); err != nil {
+		return responseError(err, "failed to complete the wraps")
+	}
+	for _, delta := range deltas {
+		if err = rb.PutDelta(delta); err != nil {
+			return responseError(err, "failed to put the bridge delta")
+		}
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : wrap key (wrap tx id)
+func wrapRefund(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	wb := NewWrapStub(stub)
+	wrap, err := wb.GetWrap(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the wrap")
+	}
+	if wrap.Status != WrapStatusRequested {
+		return responseErrorCode(ErrorCodeInvalidState, "the wrap is not requested")
+	}
+	if wrap.Expiry == nil {
+		return responseErrorCode(ErrorCodeInvalidState, "the wrap has no expiry")
+	}
+	if wrap.Expiry.Cmp(ts) > 0 {
+		return responseErrorCode(ErrorCodeInvalidState, "too early to refund")
+	}
+
+	// sender
+	sAddr, _ := ParseAddress(wrap.Address) // err is nil
+	ab := NewAccountStub(stub, sAddr.Code)
+	sender, err := ab.GetAccount(sAddr)
+	if err != nil {
+		return responseError(err, "failed to get the sender account")
+	}
+	if !sender.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not holder")
+	}
+	if sender.IsSuspended() {
+		return responseErrorCode(ErrorCodeSuspendedAccount, "the sender account is suspended")
+	}
+
+	sBal, err := NewBalanceStub(stub).GetBalance(sender.GetID())
+	if err != nil {
+		return responseError(err, "failed to get the sender's balance")
+	}
+
+	log, err := wb.Refund(wrap, sBal)
+	if err != nil {
+		return responseError(err, "failed to refund the wrap")
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return responseError(err, "failed to marshal the log")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : receiver address | token code (bridge error handling)
+// params[1] : external token code(wpci, ...)
+// params[2] : external address
+// params[3] : external tx id
+// params[4] : amount (big int string | decimal-formatted string) must bigger than 0
+func unwrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	// param check
+	if len(params) < 5 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 5")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	up, err := getValidatedUnwrapParameters(stub, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if up.policy.Threshold > 0 {
+		return responseErrorCode(ErrorCodeInvalidAccess, "the wrap policy requires the attestations of the bridge validators. use unwrap/attest")
+	}
+	if !up.wrapper.HasHolder(kid) {
+		return responseErrorCode(ErrorCodeNotHolder, "invoker is not wrapper")
+	}
+
+	log, err := settleUnwrap(stub, up)
+	if err != nil {
+		return responseError(err, "failed to unwrap")
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return responseError(err, "failed to marshal the log")
+	}
+
+	return shim.Success(data)
+}
+
+// Unwrap the items of the external token code in a transaction. The wrap account has one balance update and one balance log of the net change.
+// Each receiver has the unwrap log of its external tx, so a receiver can have only one unwrap in a batch.
+// The impossible unwraps (empty receiver) are recorded without the balance log.
+// The failed items (including the duplicate unwraps) are reported in the results and don't abort the others.
+// The internal errors (e.g. state read failures) abort the transaction.
+// params[0] : token code
+// params[1] : external token code(wpci, ...)
+// params[2] : items (JSON array of UnwrapItem, max 50)
+func unwrapBatch(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 3 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 3")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+	extCode := strings.ToUpper(params[1])
+
+	items := []*UnwrapItem{}
+	if err = json.Unmarshal([]byte(params[2]), &items); err != nil {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid items: expecting JSON array of {receiver, ext_id, ext_tx_id, amount}")
+	}
+	if len(items) < 1 || len(items) > WrapBatchMaxItems {
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid number of items. expecting 1~50")
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if err != nil {
+		return responseError(err, "failed to get the token")
+	}
+	policy, err := token.getWrapPolicy(extCode)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if policy.Threshold > 0 {
+		return responseErrorCode(ErrorCodeInvalidAccess, "the wrap policy requires the attestations of the bridge validators. use unwrap/attest")
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	rb := NewBridgeStub(stub)
+	ledger, daily, err := rb.GetLimitLedger(code, extCode, policy.UnwrapLimitDaily)
+	if err != nil {
+		return responseError(err, "failed to get the bridge ledger")
+	}
+
+	wb := NewWrapStub(stub)
+	s := newBalanceSettlement(stub) // the wrap account only
+	s.extCode = extCode
+
+	unwrapped := stringset.New()
+	receivers := stringset.New()
+	debit := ZeroAmount() // total amount of the wrap account
+	res := &WrapBatchResult{BatchID: stub.GetTxID(), Items: []*WrapBatchItemResult{}, BalanceLogs: []*BalanceLog{}}
+	for _, item := range items {
+		r := &WrapBatchItemResult{ID: item.ExtTxID}
+		res.Items = append(res.Items, r)
+
+		receiver := item.Receiver
+		if len(receiver) == 0 {
+			receiver = code
+		}
+		up, err := getValidatedUnwrapParameters(stub, []string{receiver, extCode, item.ExtID, item.ExtTxID, item.Amount})
+		if err == nil {
+			r.ID = up.extTxID
+			err = validateUnwrapBatchItem(kid, up, unwrapped, receivers, debit, ledger, ts)
+		}
+		if err != nil {
+			if GetErrorCode(err) == ErrorCodeInternal { // not the fault of the item
+				return responseError(err, "failed to validate the unwrap")
+			}
+			r.Error = NewErrorResponse(GetErrorCode(err), err.Error())
+			continue
+		}
+		unwrapped.Add(up.extTxID)
+
+		impossible, err := putUnwrap(stub, up, ts)
+		if err != nil {
+			return responseError(err, "failed to put the unwrap")
+		}
+		if !impossible {
+			receivers.Add(up.rBal.GetID())
+			debit.Add(up.amount)
+			s.cache(up.wBal)
+			s.add(up.wBal.GetID(), up.amount.Copy().Neg(), nil)
+			rbl, err := wb.creditUnwrap(up.rBal, *up.amount.Copy(), extCode, up.extID, up.extTxID, ts)
+			if err != nil {
+				return responseError(err, "failed to unwrap")
+			}
+			res.BalanceLogs = append(res.BalanceLogs, rbl)
+		}
+		res.Applied++
+	}
+
+	logs, err := s.apply(BalanceLogTypeUnwrapComplete, res.BatchID, ts)
+	if err != nil {
+		return responseError(err, "failed to unwrap")
+	}
+	res.BalanceLogs = append(res.BalanceLogs, logs...)
+	if daily && debit.Sign() > 0 {
+		if err = rb.PutLedger(ledger); err != nil {
+			return responseError(err, "failed to put the bridge ledger")
+		}
+	}
+	delta := NewBridgeDelta(code, extCode, res.BatchID, ts)
+	delta.UnwrappedIn.Add(debit)
+	if err = rb.PutDelta(delta); err != nil {
+		return responseError(err, "failed to put the bridge delta")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+
+	return shim.Success(data)
+}
+
+// The attestation of a bridge validator. The unwrap is executed when the attestations of the same
+// (receiver, external address, amount) meet the threshold of the wrap policy.
+// params[0] : receiver address | token code (bridge error handling)
+// params[1] : external token code(wpci, ...)
+// params[2] : external address
+// params[3] : external tx id
+// params[4] : amount (big int string | decimal-formatted string) must bigger than 0
+func unwrapAttest(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	// param check
+	if len(params) < 5 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 5")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	up, err := getValidatedUnwrapParameters(stub, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+	if up.policy.Threshold < 1 {
+		return responseErrorCode(ErrorCodeInvalidAccess, "the wrap policy has no bridge validators")
+	}
+	if !up.policy.IsValidator(kid) {
+		return responseErrorCode(ErrorCodeNoAuthority, "invoker is not the bridge validator")
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	wb := NewWrapStub(stub)
+	ua, err := wb.GetUnwrapAttestation(up.extTxID)
+	if err != nil {
+		return responseError(err, "failed to get the unwrap attestation")
+	}
+	if ua == nil {
+		ua = &UnwrapAttestation{
+			DOCTYPEID:   up.extTxID,
+			Token:       up.token.DOCTYPEID,
+			ExtCode:     up.extCode,
+			Attests:     []*UnwrapAttest{},
+			CreatedTime: ts,
+		}
+	} else if ua.Token != up.token.DOCTYPEID || ua.ExtCode != up.extCode {
+		return responseErrorCode(ErrorCodeInvalidParameter, "the token or the external token code is not matched with the attestation")
+	}
+	if ua.GetAttest(kid) != nil {
+		return responseErrorCode(ErrorCodeInvalidState, "already attested")
+	}
+
+	attest := &UnwrapAttest{
+		Validator:   kid,
+		Receiver:    up.rBal.GetID(),
+		ExtID:       up.extID,
+		Amount:      *up.amount,
+		Digest:      UnwrapAttestDigest(up.extTxID, up.rBal.GetID(), up.extID, up.amount),
+		CreatedTime: ts,
+	}
+	for _, a := range ua.Attests {
+		if a.Digest != attest.Digest {
+			ua.Conflicted = true // recorded, the digests are counted separately
+			break
+		}
+	}
+	ua.Attests = append(ua.Attests, attest)
+	ua.Threshold = up.policy.Threshold
+	ua.UpdatedTime = ts
+
+	res := &UnwrapAttestResult{Attestation: ua}
+	if ua.Count(attest.Digest) >= ua.Threshold {
+		if res.BalanceLog, err = settleUnwrap(stub, up); err != nil {
+			return responseError(err, "failed to unwrap")
+		}
+		ua.Status = UnwrapAttestationStatusExecuted
+		ua.CompleteTxID = stub.GetTxID()
+	}
+	if err = wb.PutUnwrapAttestation(ua); err != nil {
+		return responseError(err, "")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the result")
+	}
+
+	return shim.Success(data)
+}
+
+// params[0] : wrap key (wrap tx id)
+func wrapGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) != 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	wrap, err := NewWrapStub(stub).GetWrap(params[0])
+	if err != nil {
+		return responseError(err, "failed to get the wrap")
+	}
+
+	data, err := json.Marshal(wrap)
+	if err != nil {
+		return responseError(err, "failed to marshal the wrap")
+	}
+	return shim.Success(data)
+}
+
+// params[0] : token code
+// params[1] : optional. external token code
+// params[2] : optional. sender address
+// params[3] : optional. status (requested | completed | impossible | refunded)
+// params[4] : optional. bookmark
+// params[5] : optional. fetch size (if less than 1, default size. max 200)
+// params[6] : optional. start time (timestamp represented by int64 seconds)
+// params[7] : optional. end time (timestamp represented by int64 seconds)
+func wrapList(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// authentication
+	_, err = kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	extCode, sender, status, bookmark := "", "", "", ""
+	fetchSize := 0
+	var stime, etime *txtime.Time
+	if len(params) > 1 && len(params[1]) > 0 {
+		token, err := NewTokenStub(stub).GetToken(code)
+		if err != nil {
+			return responseError(err, "failed to get the token")
+		}
+		// the ext code is put in the query, so it must be a code of the wrap bridge
+		if _, err = token.getWrapPolicy(params[1]); err != nil {
+			return responseError(err, "")
+		}
+		extCode = strings.ToUpper(params[1])
+	}
+	if len(params) > 2 && len(params[2]) > 0 {
+		addr, err := ParseAddress(params[2])
+		if err != nil {
+			return responseError(err, "failed to parse the sender's account address")
+		}
+		if addr.Code != code {
+			return responseErrorCode(ErrorCodeInvalidParameter, "the sender must be the token account")
+		}
+		sender = addr.String()
+	}
+	if len(params) > 3 && len(params[3]) > 0 {
+		s, err := ParseWrapStatus(params[3])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, err.Error())
+		}
+		status = strconv.Itoa(int(s))
+	}
+	if len(params) > 4 {
+		bookmark = params[4]
+	}
+	if len(params) > 5 && len(params[5]) > 0 {
+		fetchSize, err = strconv.Atoi(params[5])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+		}
+	}
+	if len(params) > 6 && len(params[6]) > 0 {
+		seconds, err := strconv.ParseInt(params[6], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid start time: need seconds since 1970")
+		}
+		stime = txtime.Unix(seconds, 0)
+	}
+	if len(params) > 7 && len(params[7]) > 0 {
+		seconds, err := strconv.ParseInt(params[7], 10, 64)
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid end time: need seconds since 1970")
+		}
+		etime = txtime.Unix(seconds, 0)
+		if stime != nil && stime.Cmp(etime) >= 0 {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid time parameters")
+		}
+	}
+
+	res, err := NewWrapStub(stub).GetQueryWraps(code, extCode, sender, status, bookmark, fetchSize, stime, etime)
+	if err != nil {
+		return responseError(err, "failed to get wraps")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal wraps")
+	}
+	return shim.Success(data)
+}
+
+// Only the holder of the genesis account of the token can migrate the legacy wraps.
+// params[0] : token code
+// params[1] : optional. start wrap id (the 'next' of the previous result)
+// params[2] : optional. fetch size (if less than 1, default size. max 200)
+func wrapMigrate(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if err != nil {
+		return responseError(err, "failed to get the token")
+	}
+	kids, err := NewAccountStub(stub, code).GetSignableIDs(token.GenesisAccount)
+	if err != nil {
+		return responseError(err, "failed to get the genesis account holders")
+	}
+	if !stringset.New(kids...).Contains(kid) {
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
+	}
+
+	start, fetchSize := "", 0
+	if len(params) > 1 {
+		start = params[1]
+	}
+	if len(params) > 2 && len(params[2]) > 0 {
+		fetchSize, err = strconv.Atoi(params[2])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+		}
+	}
+
+	res, err := NewWrapStub(stub).Migrate(code, start, fetchSize)
+	if err != nil {
+		return responseError(err, "failed to migrate the wraps")
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+	return shim.Success(data)
+}
+
+// params[0] : external tx id
+// params[1] : optional. token code - with the external token code, the tx id is normalized by the external chain
+// params[2] : optional. external token code
+func unwrapGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	extTxID, err := getNormalizedExtTxIDParam(stub, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	unwrap, err := NewWrapStub(stub).GetUnwrap(extTxID)
+	if err != nil {
+		return responseError(err, "failed to get the unwrap")
+	}
+
+	data, err := json.Marshal(unwrap)
+	if err != nil {
+		return responseError(err, "failed to marshal the unwrap")
+	}
+	return shim.Success(data)
+}
+
+// params[0] : external tx id
+// params[1] : optional. token code - with the external token code, the tx id is normalized by the external chain
+// params[2] : optional. external token code
+func unwrapAttestationGet(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	// authentication
+	_, err := kid.GetID(stub, false)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	extTxID, err := getNormalizedExtTxIDParam(stub, params)
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	ua, err := NewWrapStub(stub).GetUnwrapAttestation(extTxID)
+	if err != nil {
+		return responseError(err, "failed to get the unwrap attestation")
+	}
+	if ua == nil {
+		return responseErrorCode(ErrorCodeNotExistedUnwrap, "the unwrap attestation is not exists")
+	}
+
+	data, err := json.Marshal(ua)
+	if err != nil {
+		return responseError(err, "failed to marshal the unwrap attestation")
+	}
+	return shim.Success(data)
+}
+
+// params[0] : token code
+// params[1] : optional. external token code (if it is omitted, all external token codes of the wrap bridge)
+func bridgeStatus(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 1 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 1+")
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// authentication
+	if _, err = kid.GetID(stub, false); err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return responseError(err, "failed to get the timestamp")
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if err != nil {
+		return responseError(err, "failed to get the token")
+	}
+
+	var extCodes []string
+	if len(params) > 1 && len(params[1]) > 0 {
+		extCodes = []string{strings.ToUpper(params[1])}
+	} else {
+		for extCode := range token.WrapBridge {
+			extCodes = append(extCodes, extCode)
+		}
+		sort.Strings(extCodes)
+	}
+
+	bb := NewBalanceStub(stub)
+	rb := NewBridgeStub(stub)
+	res := []*BridgeStatus{}
+	for _, extCode := range extCodes {
+		policy, err := token.getWrapPolicy(extCode)
+		if err != nil {
+			return responseError(err, "")
+		}
+		ledger, err := rb.GetTotals(code, extCode)
+		if err != nil {
+			return responseError(err, "failed to get the bridge ledger")
+		}
+		wBal, err := bb.GetBalance(policy.WrapAddress)
+		if err != nil {
+			return responseError(err, "failed to get the balance of the wrap account")
+		}
+		res = append(res, &BridgeStatus{
+			BridgeLedger: ledger,
+			ExtChain:     policy.ExtChain,
+			WrapAddress:  policy.WrapAddress,
+			WrapBalance:  wBal.Amount,
+			Outstanding:  ledger.Outstanding(),
+			WrapLimit:    newBridgeLimit(policy.WrapLimitPerTx, policy.WrapLimitDaily, ledger.WrapDayUsed(ts)),
+			UnwrapLimit:  newBridgeLimit(policy.UnwrapLimitPerTx, policy.UnwrapLimitDaily, ledger.UnwrapDayUsed(ts)),
+		})
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the bridge status")
+	}
+	return shim.Success(data)
+}
+
+// Folds the bridge deltas before the current hour into the bridge ledger
+// params[0] : token code
+// params[1] : external token code
+// params[2] : optional. fetch size (max 900)
+func bridgeCompact(stub shim.ChaincodeStubInterface, params []string) peer.Response {
+	if len(params) < 2 {
+		return responseErrorCode(ErrorCodeInvalidParameter, "incorrect number of parameters. expecting 2+")
+	}
+
+	code, err := ValidateTokenCode(params[0])
+	if err != nil {
+		return responseError(err, "")
+	}
+
+	// authentication
+	kid, err := kid.GetID(stub, true)
+	if err != nil {
+		return responseErrorCode(ErrorCodeUnauthenticated, err.Error())
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if err != nil {
+		return responseError(err, "failed to get the token")
+	}
 	extCode := strings.ToUpper(params[1])
+	if _, err = token.getWrapPolicy(extCode); err != nil {
+		return responseError(err, "")
+	}
+	kids, err := NewAccountStub(stub, code).GetSignableIDs(token.GenesisAccount)
+	if err != nil {
+		return responseError(err, "failed to get the genesis account holders")
+	}
+	if !stringset.New(kids...).Contains(kid) {
+		return responseErrorCode(ErrorCodeNoAuthority, "no authority")
+	}
 
-	// external address
-	extID, err := NormalizeExtAddress(params[2])
+	fetchSize := 0
+	if len(params) > 2 && len(params[2]) > 0 {
+		fetchSize, err = strconv.Atoi(params[2])
+		if err != nil {
+			return responseErrorCode(ErrorCodeInvalidParameter, "invalid fetch size")
+		}
+	}
+
+	ts, err := txtime.GetTime(stub)
 	if err != nil {
-		return shim.Error("invalid ext address")
+		return responseError(err, "failed to get the timestamp")
 	}
 
-	// amount check
-	amount, err := NewAmount(params[3])
+	res, err := NewBridgeStub(stub).Compact(code, extCode, fetchSize, ts)
 	if err != nil {
-		return shim.Error(err.Error())
+		return responseError(err, "failed to compact the bridge ledger")
 	}
-	if amount.Sign() <= 0 {
-		return shim.Error("invalid amount. must be greater than 0")
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return responseError(err, "failed to marshal the payload")
+	}
+	return shim.Success(data)
+}
+
+// helpers
+
+// wrapParameters is the validated parameters of wrap. (see getValidatedWrapParameters)
+type wrapParameters struct {
+	sender  AccountInterface
+	wrapper AccountInterface
+	sBal    *Balance
+	amount  *Amount
+	extCode string
+	extID   string
+	memo    string
+	orderID string
+	expiry  int64
+	signers *stringset.Set
+}
+
+// getValidatedWrapParameters validates the wrap parameters without writing any state.
+// It is shared by wrap and quote.
+func getValidatedWrapParameters(stub shim.ChaincodeStubInterface, kid string, params []string) (*wrapParameters, error) {
+	if len(params) < 4 {
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 4+"}
 	}
 
+	// external code
+	extCode := strings.ToUpper(params[1])
+
 	// addresses
 	var sAddr *Address
 	code, err := ValidateTokenCode(params[0])
@@ -58,16 +965,38 @@ func wrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	} else { // by address
 		sAddr, err = ParseAddress(params[0])
 		if err != nil {
-			return responseError(err, "failed to parse the sender's account address")
+			return nil, errors.Wrap(err, "failed to parse the sender's account address")
 		}
 	}
 	token, err := NewTokenStub(stub).GetToken(sAddr.Code)
 	if err != nil {
-		return responseError(err, "failed to get the token")
+		return nil, errors.Wrap(err, "failed to get the token")
+	}
+
+	// amount check
+	amount, err := token.ParseAmount(params[3])
+	if err != nil {
+		return nil, err
 	}
+	if amount.Sign() <= 0 {
+		return nil, InvalidAmountError{reason: "invalid amount. must be greater than 0"}
+	}
+
 	wAddr, err := token.GetWrapAddress(extCode)
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, InvalidParameterError{reason: err.Error()}
+	}
+
+	// bridge limits (the wrap is recorded when it is requested)
+	if err = checkBridgeLedgerLimit(stub, token, extCode, amount, false); err != nil {
+		return nil, err
+	}
+
+	// external address
+	extChain, _ := token.GetExtChain(extCode) // err is nil
+	extID, err := extChain.NormalizeAddress(params[2])
+	if err != nil {
+		return nil, InvalidParameterError{reason: "invalid ext address"}
 	}
 
 	ab := NewAccountStub(stub, sAddr.Code)
@@ -75,66 +1004,70 @@ func wrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	// sender
 	sender, err := ab.GetAccount(sAddr)
 	if err != nil {
-		logger.Debug(err.Error())
-		return shim.Error("failed to get the sender account")
+		return nil, errors.Wrap(err, "failed to get the sender account")
 	}
 	if !sender.HasHolder(kid) {
-		return shim.Error("invoker is not holder")
+		return nil, NotHolderError{}
 	}
 	if sender.IsSuspended() {
-		return shim.Error("the sender account is suspended")
+		return nil, SuspendedAccountError{role: "sender"}
 	}
 
 	// IMPORTANT: assert(sender != wrapper)
 	if sAddr.Equal(wAddr) {
-		return shim.Error("wrap address cannot wrap self")
+		return nil, InvalidParameterError{reason: "wrap address cannot wrap self"}
 	}
 
 	// wrapper(wrap account)
 	wrapper, err := ab.GetAccount(wAddr)
 	if err != nil {
-		return responseError(err, "failed to get the wrap account")
+		return nil, errors.Wrap(err, "failed to get the wrap account")
 	}
 	// can wrapper be suspended?
 	if wrapper.IsSuspended() {
-		return shim.Error("the wrap account is suspended")
+		return nil, SuspendedAccountError{role: "wrap"}
 	}
 
 	// balance check
-	bb := NewBalanceStub(stub)
-	sBal, err := bb.GetBalance(sender.GetID())
+	sBal, err := NewBalanceStub(stub).GetBalance(sender.GetID())
 	if err != nil {
-		return shim.Error("failed to get the sender's balance")
+		return nil, errors.Wrap(err, "failed to get the sender's balance")
 	}
 
 	// balance must bigger than amount
 	if sBal.Amount.Cmp(amount) < 0 {
-		return shim.Error("not enough balance")
+		return nil, NotEnoughBalanceError{}
 	}
 
-	// options
-	memo := ""
-	orderID := ""
-	var expiry int64
-	signers := stringset.New(kid)
+	wp := &wrapParameters{
+		sender:  sender,
+		wrapper: wrapper,
+		sBal:    sBal,
+		amount:  amount,
+		extCode: extCode,
+		extID:   extID,
+		signers: stringset.New(kid),
+	}
 	if a, ok := sender.(*JointAccount); ok {
-		signers.AppendSet(a.Holders)
+		wp.signers.AppendSet(a.Holders)
 	}
+
+	// options
 	// memo
 	if len(params) > 4 {
 		if len(params[4]) > MemoMaxLength { // length limit
-			memo = params[4][:MemoMaxLength]
+			wp.memo = params[4][:MemoMaxLength]
 		} else {
-			memo = params[4]
+			wp.memo = params[4]
 		}
 		// order id
 		if len(params) > 5 {
-			orderID = params[5]
+			wp.orderID = params[5]
 			// expiry
 			if len(params) > 6 && len(params[6]) > 0 {
-				expiry, err = strconv.ParseInt(params[6], 10, 64)
+				wp.expiry, err = strconv.ParseInt(params[6], 10, 64)
 				if err != nil {
-					return shim.Error("invalid expiry: need seconds")
+					return nil, InvalidParameterError{reason: "invalid expiry: need seconds"}
 				}
 				// extra signers
 				if len(params) > 7 {
@@ -142,226 +1075,216 @@ func wrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 					for addr := range addrs.Map() {
 						kids, err := ab.GetSignableIDs(addr)
 						if err != nil {
-							return shim.Error(err.Error())
+							return nil, err
 						}
-						signers.AppendSlice(kids)
+						wp.signers.AppendSlice(kids)
 					}
 				}
 			}
 		}
 	}
 
-	var log *BalanceLog // log for response
-	if signers.Size() > 1 {
-		// multisig
-		if signers.Size() > 128 {
-			return shim.Error("too many signers")
-		}
-		// pending balance id
-		pbID := stub.GetTxID()
-		doc := []string{"wrap", pbID, sender.GetID(), amount.String(), extCode, extID, memo, orderID}
-		docb, err := json.Marshal(doc)
-		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to create a contract")
-		}
-		con, err := contract.CreateContract(stub, docb, expiry, signers)
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		// pending balance
-		log, err = bb.Deposit(pbID, sBal, con, *amount, nil, memo, orderID)
-		if err != nil {
-			logger.Debug(err.Error())
-			return shim.Error("failed to create the pending balance")
-		}
-	} else {
-		wb := NewWrapStub(stub)
-		log, err = wb.Wrap(sBal, *amount, extCode, extID, memo, orderID)
-		if err != nil {
-			return shim.Error("failed to wrap")
-		}
+	if wp.signers.Size() > 128 {
+		return nil, TooManySignersError{}
 	}
 
-	data, err := json.Marshal(log)
-	if err != nil {
-		return shim.Error("failed to marshal the log")
-	}
+	return wp, nil
+}
 
-	return shim.Success(data)
+// wrapCompleteParameters is the validated parameters of wrap/complete. (see getValidatedWrapCompleteParameters)
+type wrapCompleteParameters struct {
+	wrap    *Wrap
+	wrapper AccountInterface
+	fee     *Amount // not nil, zero if impossible wrap
+	extTxID string  // empty if impossible wrap
 }
 
-// params[0] : wrap key (wrap tx id)
-// params[1] : fee (big int string) must bigger than or equal to 0
-// params[2] : external tx id (if it is nil, it is 'impossible wrap')
-func wrapComplete(stub shim.ChaincodeStubInterface, params []string) peer.Response {
-	// param check
+// getValidatedWrapCompleteParameters validates the wrap/complete parameters without writing any state.
+// It is shared by wrap/complete and wrap/complete/batch.
+func getValidatedWrapCompleteParameters(stub shim.ChaincodeStubInterface, kid string, params []string) (*wrapCompleteParameters, error) {
 	if len(params) < 1 {
-		return shim.Error("incorrect number of parameters. expecting 1+")
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 1+"}
 	}
 
-	// authentication
-	kid, err := kid.GetID(stub, true)
+	// wrap key (wrap tx id)
+	wrap, err := NewWrapStub(stub).GetWrap(params[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, err
+	}
+	if wrap.CompleteTxID != "" {
+		return nil, DuplicateWrapCompleteError{}
+	}
+	if wrap.Status == WrapStatusRefunded {
+		return nil, InvalidStateError{reason: "the wrap is refunded"}
 	}
 
-	// wrap key (wrap tx id)
-	wrapKey := params[0]
-
-	var fee *Amount
-	if len(params) > 1 {
-		// check fee format even when it can be ignored (preventing abused arguments)
-		fee, err = NewAmount(params[1])
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		if fee.Sign() < 0 {
-			return shim.Error("invalid fee. must be greater than or equal to 0")
-		}
+	token, err := NewTokenStub(stub).GetToken(wrap.Token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the token")
 	}
 
 	// if 'external tx id' is not exist, it is 'impossible wrap' and fee will be ignored
 	extTxID := ""
 	if len(params) > 2 {
-		extTxID, err = NormalizeExtTxID(params[2])
+		extChain, err := token.GetExtChain(wrap.ExtCode)
 		if err != nil {
-			return shim.Error("invalid ext tx id")
+			return nil, err
+		}
+		extTxID, err = extChain.NormalizeTxID(params[2])
+		if err != nil {
+			return nil, InvalidParameterError{reason: "invalid ext tx id"}
 		}
-	} else {
-		fee = ZeroAmount()
 	}
 
-	wb := NewWrapStub(stub)
-	wrap, err := wb.GetWrap(wrapKey)
-	if err != nil {
-		return shim.Error(err.Error())
+	fee := ZeroAmount()
+	if len(params) > 1 {
+		// check fee format even when it can be ignored (preventing abused arguments)
+		_fee, err := token.ParseAmount(params[1])
+		if err != nil {
+			return nil, err
+		}
+		if _fee.Sign() < 0 {
+			return nil, InvalidAmountError{reason: "invalid fee. must be greater than or equal to 0"}
+		}
+		if len(params) > 2 { // 'impossible wrap' ignores the fee
+			fee = _fee
+		}
 	}
-	if wrap.CompleteTxID != "" {
-		return shim.Error(DuplicateWrapCompleteError{}.Error())
+	if wrap.Amount.Cmp(fee) <= 0 {
+		return nil, InvalidAmountError{reason: "wrap amount is less than or equal to fee"}
 	}
 
-	code, _ := ParseCode(wrap.Address)
-	token, err := NewTokenStub(stub).GetToken(code)
+	wAddr, err := token.GetWrapAddress(wrap.ExtCode)
 	if err != nil {
-		return responseError(err, "failed to get the token")
+		return nil, InvalidParameterError{reason: err.Error()}
 	}
-	wAddr, err := token.GetWrapAddress(wrap.ExtCode)
 
 	// wrapper(wrap account)
-	ab := NewAccountStub(stub, code)
-	wrapper, err := ab.GetAccount(wAddr)
+	wrapper, err := NewAccountStub(stub, wrap.Token).GetAccount(wAddr)
 	if err != nil {
-		return shim.Error("failed to get the wrap account")
+		return nil, errors.Wrap(err, "failed to get the wrap account")
 	}
 	if !wrapper.HasHolder(kid) {
-		return shim.Error("invoker is not wrapper")
+		return nil, NotHolderError{}
 	}
 	// can wrapper be suspended?
 	if wrapper.IsSuspended() {
-		return shim.Error("the wrap account is suspended")
-	}
-
-	bb := NewBalanceStub(stub)
-	wBal, err := bb.GetBalance(wrapper.GetID())
-	if err != nil {
-		return shim.Error("failed to get the balance of the wrap account")
+		return nil, SuspendedAccountError{role: "wrap"}
 	}
 
-	// amount := wrap.Amount.Copy().Neg()
-	log, err := wb.WrapComplete(wrap, wBal, *fee, extTxID)
-	if err != nil {
-		return shim.Error(err.Error())
-	}
+	return &wrapCompleteParameters{
+		wrap:    wrap,
+		wrapper: wrapper,
+		fee:     fee,
+		extTxID: extTxID,
+	}, nil
+}
 
-	data, err := json.Marshal(log)
-	if err != nil {
-		return shim.Error("failed to marshal the log")
+// getNormalizedExtTxIDParam returns the external tx id of the params. [ext_tx_id, token_code, ext_token_code]
+// If the token code and the external token code are given, the tx id is normalized by the external chain.
+func getNormalizedExtTxIDParam(stub shim.ChaincodeStubInterface, params []string) (string, error) {
+	extTxID := params[0]
+	if len(params) > 2 && len(params[1]) > 0 && len(params[2]) > 0 {
+		code, err := ValidateTokenCode(params[1])
+		if err != nil {
+			return "", err
+		}
+		token, err := NewTokenStub(stub).GetToken(code)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get the token")
+		}
+		extChain, err := token.GetExtChain(strings.ToUpper(params[2]))
+		if err != nil {
+			return "", err
+		}
+		if extTxID, err = extChain.NormalizeTxID(extTxID); err != nil {
+			return "", InvalidParameterError{reason: "invalid ext tx id"}
+		}
 	}
+	return extTxID, nil
+}
 
-	return shim.Success(data)
+// unwrapParameters is the validated parameters of unwrap. (see getValidatedUnwrapParameters)
+type unwrapParameters struct {
+	token   *Token
+	policy  *WrapPolicy
+	extCode string
+	extID   string
+	extTxID string
+	amount  *Amount
+	wrapper AccountInterface
+	wBal    *Balance
+	rBal    *Balance
 }
 
-// params[0] : receiver address | token code (bridge error handling)
-// params[1] : external token code(wpci, ...)
-// params[2] : external address(EOA)
-// params[3] : external tx id
-// params[4] : amount (big int string) must bigger than 0
-func unwrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
-	// param check
+// getValidatedUnwrapParameters validates the unwrap parameters without writing any state.
+// It doesn't authorize the invoker. It is shared by unwrap and unwrap/attest.
+func getValidatedUnwrapParameters(stub shim.ChaincodeStubInterface, params []string) (*unwrapParameters, error) {
 	if len(params) < 5 {
-		return shim.Error("incorrect number of parameters. expecting 5")
+		return nil, InvalidParameterError{reason: "incorrect number of parameters. expecting 5"}
 	}
 
-	// authentication
-	kid, err := kid.GetID(stub, true)
+	// external code
+	extCode := strings.ToUpper(params[1])
+
+	// addresses
+	var rAddr *Address
+	code, err := ValidateTokenCode(params[0])
+	if err != nil { // by address
+		rAddr, err = ParseAddress(params[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the receiver's account address")
+		}
+		code = rAddr.Code
+	}
+
+	token, err := NewTokenStub(stub).GetToken(code)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the token")
+	}
+	policy, err := token.getWrapPolicy(extCode)
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, err
 	}
+	extChain := GetExtChain(policy.ExtChain)
 
 	// external txid
-	extTxID, err := NormalizeExtTxID(params[3])
+	extTxID, err := extChain.NormalizeTxID(params[3])
 	if err != nil {
-		return shim.Error("invalid ext tx id")
+		return nil, InvalidParameterError{reason: "invalid ext tx id"}
 	}
 
+	// check unwrap duplication
 	wb := NewWrapStub(stub)
-
-	// check unwrap duplication (move to stub?)
-	data, err := wb.stub.GetState(wb.CreateUnwrapKey(extTxID))
-	if err != nil {
-		return responseError(err, "failed to get unwrap state")
-	}
-	if data != nil {
-		return shim.Error(DuplicateUnwrapCompleteError{}.Error())
+	if _, err = wb.GetUnwrap(extTxID); err == nil {
+		return nil, DuplicateUnwrapCompleteError{}
+	} else if _, ok := err.(NotExistedUnwrapError); !ok {
+		return nil, errors.Wrap(err, "failed to get unwrap state")
 	}
-	unwrap := &Unwrap{
-		DOCTYPEID:    extTxID,
-		CompleteTxID: wb.stub.GetTxID(),
-	}
-	if err = wb.PutUnwrap(unwrap); err != nil {
-		return shim.Error(err.Error())
-	}
-
-	// external code
-	extCode := strings.ToUpper(params[1])
 
 	// external address
-	extID, err := NormalizeExtAddress(params[2])
+	extID, err := extChain.NormalizeAddress(params[2])
 	if err != nil {
-		return shim.Error("invalid ext address")
+		return nil, InvalidParameterError{reason: "invalid ext address"}
 	}
 
 	// amount check
-	amount, err := NewAmount(params[4])
+	amount, err := token.ParseAmount(params[4])
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, err
 	}
 	if amount.Sign() <= 0 {
-		return shim.Error("invalid amount. must be greater than 0")
-	}
-
-	// addresses
-	var rAddr *Address
-	code, err := ValidateTokenCode(params[0])
-	if err != nil { // by address
-		rAddr, err = ParseAddress(params[0])
-		if err != nil {
-			return responseError(err, "failed to parse the receiver's account address")
-		}
-		code = rAddr.Code
+		return nil, InvalidAmountError{reason: "invalid amount. must be greater than 0"}
 	}
 
-	token, err := NewTokenStub(stub).GetToken(code)
-	if err != nil {
-		return responseError(err, "failed to get the token")
-	}
 	wAddr, err := token.GetWrapAddress(extCode)
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, InvalidParameterError{reason: err.Error()}
 	}
 	if rAddr == nil { // param[0] was token code (impossible unwrap)
 		rAddr = wAddr
+	} else if err = checkBridgeLedgerLimit(stub, token, extCode, amount, true); err != nil { // bridge limits
+		return nil, err
 	}
 
 	ab := NewAccountStub(stub, code)
@@ -369,94 +1292,169 @@ func unwrap(stub shim.ChaincodeStubInterface, params []string) peer.Response {
 	// wrapper(wrap account)
 	wrapper, err := ab.GetAccount(wAddr)
 	if err != nil {
-		return shim.Error("failed to get the wrap account")
-	}
-	if !wrapper.HasHolder(kid) {
-		return shim.Error("invoker is not wrapper")
+		return nil, errors.Wrap(err, "failed to get the wrap account")
 	}
 	// can wrapper be suspended?
 	if wrapper.IsSuspended() {
-		return shim.Error("the wrap account is suspended")
+		return nil, SuspendedAccountError{role: "wrap"}
 	}
 
 	// receiver
-	if rAddr == nil { // param[0] was token code (impossible unwrap)
-		rAddr = wAddr
-	}
 	receiver, err := ab.GetAccount(rAddr)
 	if err != nil {
-		return responseError(err, "failed to get the receiver account")
+		return nil, errors.Wrap(err, "failed to get the receiver account")
 	}
 	if receiver.IsSuspended() {
-		return shim.Error("the receiver account is suspended")
+		return nil, SuspendedAccountError{role: "receiver"}
 	}
 
 	// balance
 	bb := NewBalanceStub(stub)
 	rBal, err := bb.GetBalance(receiver.GetID())
 	if err != nil {
-		return shim.Error("failed to get the receiver's balance")
+		return nil, errors.Wrap(err, "failed to get the receiver's balance")
 	}
 
 	// wrapper balance
 	wBal, err := bb.GetBalance(wrapper.GetID())
 	if err != nil {
-		return shim.Error("failed to get the balance of the wrap account")
+		return nil, errors.Wrap(err, "failed to get the balance of the wrap account")
 	}
 
-	var log *BalanceLog
-	if !wAddr.Equal(rAddr) {
-		// normal unwrap
-		// wrap acocunt balance check
-		if wBal.Amount.Cmp(amount) < 0 {
-			return shim.Error("not enough balance")
-		}
-		log, err = wb.Unwrap(wBal, rBal, *amount, extCode, extID, extTxID)
-		if err != nil {
-			return responseError(err, "failed to unwrap")
-		}
-	} else {
-		// unwrap error handle
-		log, err = wb.UnwrapImpossible(wBal, extCode, extID, extTxID)
-		if err != nil {
-			return responseError(err, "failed to unwrap")
-		}
-	}
+	return &unwrapParameters{
+		token:   token,
+		policy:  policy,
+		extCode: extCode,
+		extID:   extID,
+		extTxID: extTxID,
+		amount:  amount,
+		wrapper: wrapper,
+		wBal:    wBal,
+		rBal:    rBal,
+	}, nil
+}
 
-	data, err = json.Marshal(log)
+// settleUnwrap puts the unwrap and moves the amount from the wrap account to the receiver.
+// If the receiver is the wrap account, it is the impossible unwrap.
+func settleUnwrap(stub shim.ChaincodeStubInterface, up *unwrapParameters) (*BalanceLog, error) {
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the timestamp")
+	}
+	impossible, err := putUnwrap(stub, up, ts)
 	if err != nil {
-		return shim.Error("failed to marshal the log")
+		return nil, err
 	}
 
-	return shim.Success(data)
+	wb := NewWrapStub(stub)
+	if impossible { // unwrap error handle
+		return wb.UnwrapImpossible(up.wBal, up.extCode, up.extID, up.extTxID)
+	}
+	// normal unwrap
+	// wrap acocunt balance check
+	if up.wBal.Amount.Cmp(up.amount) < 0 {
+		return nil, NotEnoughBalanceError{}
+	}
+	if err = NewBridgeStub(stub).RecordUnwrap(up.policy, up.token.DOCTYPEID, up.extCode, up.amount, ts); err != nil {
+		return nil, err
+	}
+	return wb.Unwrap(up.wBal, up.rBal, *up.amount.Copy(), up.extCode, up.extID, up.extTxID)
+}
+
+// putUnwrap puts the unwrap record. If the receiver is the wrap account, it is the impossible unwrap.
+func putUnwrap(stub shim.ChaincodeStubInterface, up *unwrapParameters, ts *txtime.Time) (bool, error) {
+	impossible := up.wBal.GetID() == up.rBal.GetID()
+	unwrap := &Unwrap{
+		DOCTYPEID:    up.extTxID,
+		CompleteTxID: stub.GetTxID(),
+		Token:        up.token.DOCTYPEID,
+		Address:      up.rBal.GetID(),
+		Amount:       up.amount,
+		ExtCode:      up.extCode,
+		ExtID:        up.extID,
+		Impossible:   impossible,
+		CreatedTime:  ts,
+	}
+	return impossible, NewWrapStub(stub).PutUnwrap(unwrap)
+}
+
+// validateUnwrapBatchItem validates the item of unwrap/batch with the previous items. (unwrapped tx ids, receivers, debit of the wrap account and the bridge ledger)
+// If it is valid and not impossible, the unwrap is added to the day total of the ledger.
+func validateUnwrapBatchItem(kid string, up *unwrapParameters, unwrapped, receivers *stringset.Set, debit *Amount, ledger *BridgeLedger, ts *txtime.Time) error {
+	if up.token.DOCTYPEID != ledger.Token {
+		return InvalidParameterError{reason: "different token accounts"}
+	}
+	if unwrapped.Contains(up.extTxID) {
+		return DuplicateUnwrapCompleteError{}
+	}
+	if !up.wrapper.HasHolder(kid) {
+		return NotHolderError{}
+	}
+	if up.wBal.GetID() == up.rBal.GetID() { // impossible unwrap
+		return nil
+	}
+	if receivers.Contains(up.rBal.GetID()) { // one balance log per balance in a transaction
+		return InvalidParameterError{reason: "the receiver has another unwrap in the batch"}
+	}
+	// wrap acocunt balance check
+	if up.wBal.Amount.Cmp(debit.Copy().Add(up.amount)) < 0 {
+		return NotEnoughBalanceError{}
+	}
+	return ledger.AddUnwrap(up.policy, up.amount, ts)
+}
+
+// checkBridgeLedgerLimit checks the wrap (or unwrap) limits of the wrap policy with the day total of the bridge ledger.
+func checkBridgeLedgerLimit(stub shim.ChaincodeStubInterface, token *Token, extCode string, amount *Amount, unwrap bool) error {
+	policy, err := token.getWrapPolicy(extCode)
+	if err != nil {
+		return err
+	}
+	ts, err := txtime.GetTime(stub)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the timestamp")
+	}
+	daily := policy.WrapLimitDaily
+	if unwrap {
+		daily = policy.UnwrapLimitDaily
+	}
+	ledger, _, err := NewBridgeStub(stub).GetLimitLedger(token.DOCTYPEID, extCode, daily)
+	if err != nil {
+		return err
+	}
+	if unwrap {
+		return policy.CheckUnwrapLimit(amount, ledger.UnwrapDayUsed(ts))
+	}
+	return policy.CheckWrapLimit(amount, ledger.WrapDayUsed(ts))
 }
 
+// contract callbacks
+
 // doc: ["wrap", pending-balance-ID, sender-ID, amount, external-code, external-address, memo, order-ID]
 func executeWrap(stub shim.ChaincodeStubInterface, cid string, doc []interface{}) peer.Response {
 	if len(doc) < 6 {
-		return shim.Error("invalid contract document")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid contract document")
 	}
 
 	// pending balance
 	bb := NewBalanceStub(stub)
 	pb, err := bb.GetPendingBalance(doc[1].(string))
 	if err != nil {
-		return shim.Error("failed to get the pending balance")
+		return responseError(err, "failed to get the pending balance")
 	}
 	// validate
 	if pb.Type != PendingBalanceTypeContract || pb.RID != cid {
-		return shim.Error("invalid pending balance")
+		return responseErrorCode(ErrorCodeInvalidParameter, "invalid pending balance")
 	}
 
 	// sender balance
 	sBal, err := bb.GetBalance(doc[2].(string))
 	if err != nil {
-		return shim.Error("failed to get the sender's balance")
+		return responseError(err, "failed to get the sender's balance")
 	}
 
 	wrap, err := NewWrapStub(stub).WrapPendingBalance(pb, sBal, doc[4].(string), doc[5].(string), doc[6].(string), doc[7].(string))
 	if err != nil {
-		return shim.Error("failed to wrap")
+		return responseError(err, "failed to wrap")
 	}
 
 	memo := ""
@@ -487,7 +1485,7 @@ func executeWrap(stub shim.ChaincodeStubInterface, cid string, doc []interface{}
 
 	data, err := json.Marshal(&log) // pass log by reference (diff marshal issue)
 	if err != nil {
-		return shim.Error("failed to marshal the log")
+		return responseError(err, "failed to marshal the log")
 	}
 
 	return shim.Success(data)